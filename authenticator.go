@@ -0,0 +1,133 @@
+package otp
+
+import "sync"
+
+// LastStepStore records the last time step accepted by an Authenticator, so that a code cannot be replayed, nor can a
+// code of an earlier time step be accepted once a later one has been.
+//
+// The default store keeps the record in memory, which is lost when the process exits. Web applications usually
+// implement the interface on top of the user record in their database, such as an UPDATE with a condition on the
+// current value. Implementations must be safe for concurrent use.
+type LastStepStore interface {
+	// AdvanceLastStep atomically records the time step as the last accepted one if it is later than the recorded one or
+	// there is no record, and reports whether it was recorded.
+	AdvanceLastStep(movingFactor int64) (bool, error)
+
+	// ResetLastStep removes the record, such as when a new secret key is enrolled.
+	ResetLastStep() error
+}
+
+// memoryLastStepStore represents a last step store keeping the record in memory. It is safe for concurrent use.
+type memoryLastStepStore struct {
+	mutex    sync.Mutex
+	last     int64
+	recorded bool
+}
+
+func (store *memoryLastStepStore) AdvanceLastStep(movingFactor int64) (bool, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if store.recorded && movingFactor <= store.last {
+		return false, nil
+	}
+	store.last, store.recorded = movingFactor, true
+	return true, nil
+}
+
+func (store *memoryLastStepStore) ResetLastStep() error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.last, store.recorded = 0, false
+	return nil
+}
+
+// Authenticator bundles a TOTP manager of a single account with the workflow of a web login: Enroll creates a secret
+// key and the key URI to show as a QR code, and Verify checks codes entered by the user against the clock of the
+// manager, accepting each time step at most once.
+//
+// Authenticator is safe for concurrent use by multiple goroutines.
+type Authenticator struct {
+	issuer    string
+	algorithm HashAlgorithm
+	opts      []TOTPOption
+	store     LastStepStore
+	mutex     sync.RWMutex
+	manager   *totpManager
+}
+
+// NewAuthenticator creates an authenticator for accounts of the issuer, with a TOTP manager created by
+// NewTOTPWithOptions with the specified hash algorithm and options. The options are kept, and applied again whenever a
+// new secret key is enrolled. To verify codes of an account that has already been enrolled, pass its secret key with
+// WithSecret; otherwise call Enroll. The last accepted time step is recorded in memory if store is nil.
+func NewAuthenticator(issuer string, algorithm HashAlgorithm, store LastStepStore,
+	opts ...TOTPOption) (*Authenticator, error) {
+	opts = append([]TOTPOption(nil), opts...)
+	manager, err := NewTOTPWithOptions(algorithm, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if store == nil {
+		store = &memoryLastStepStore{}
+	}
+	return &Authenticator{
+		issuer:    issuer,
+		algorithm: algorithm,
+		opts:      opts,
+		store:     store,
+		manager:   manager.(*totpManager),
+	}, nil
+}
+
+// Enroll generates a new secret key for the account, and gets the secret key to persist along with the key URI to
+// provision authenticator apps with. The new secret key replaces the current one, and the record of the last accepted
+// time step is reset, once the verifications in progress have finished. Nothing is replaced when an error is returned.
+func (authenticator *Authenticator) Enroll(account string) (secret []byte, uri string, err error) {
+	opts := append(append([]TOTPOption(nil), authenticator.opts...), WithSecret(nil))
+	manager, err := NewTOTPWithOptions(authenticator.algorithm, opts...)
+	if err != nil {
+		return nil, "", err
+	}
+	if uri, err = manager.ProvisioningURI(authenticator.issuer, account); err != nil {
+		return nil, "", err
+	}
+	// The record is reset while no Verify is in progress, so that a code of the old secret key accepted concurrently
+	// cannot record a time step that would reject the first code of the new one.
+	authenticator.mutex.Lock()
+	defer authenticator.mutex.Unlock()
+	if err = authenticator.store.ResetLastStep(); err != nil {
+		return nil, "", err
+	}
+	authenticator.manager = manager.(*totpManager)
+	return manager.Secret(), uri, nil
+}
+
+// Verify validates whether the one-time password matches the current time read from the clock of the manager within
+// the tolerant time steps, and rejects codes of time steps that are not later than the last accepted one, which
+// prevents an observed code from being replayed.
+//
+// The code is rejected when the last step store fails, since accepting a possibly replayed code is worse than asking
+// the user to try again.
+func (authenticator *Authenticator) Verify(code string) bool {
+	authenticator.mutex.RLock()
+	defer authenticator.mutex.RUnlock()
+	generator := authenticator.manager
+	code, ok := generator.hotp.prepareInput(code)
+	if !ok {
+		return false
+	}
+	movingFactor := generator.movingFactorTime(generator.clock())
+	offset, ok := generator.matchOffset(movingFactor, code)
+	if !ok {
+		return false
+	}
+	advanced, err := authenticator.store.AdvanceLastStep(movingFactor + int64(offset))
+	return err == nil && advanced
+}
+
+// Manager gets the underlying manager, for features not covered by the authenticator. Codes validated directly with
+// the manager are not recorded as accepted.
+func (authenticator *Authenticator) Manager() TOTPManager {
+	authenticator.mutex.RLock()
+	defer authenticator.mutex.RUnlock()
+	return authenticator.manager
+}
@@ -0,0 +1,146 @@
+package otp
+
+import (
+	"encoding/hex"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthenticator(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	authenticator, err := NewAuthenticator("Example", HashAlgorithmSHA1, nil,
+		WithClock(func() time.Time { return now }))
+	assert.NoError(t, err)
+
+	secret, uri, err := authenticator.Enroll("alice@example.com")
+	assert.NoError(t, err)
+	assert.Len(t, secret, 20)
+	assert.Equal(t, secret, authenticator.Manager().Secret())
+
+	// The app is provisioned from the key URI, and the secret key is persisted by the server.
+	app, err := ParseURI(uri)
+	assert.NoError(t, err)
+	assert.Equal(t, EncodeSecret(secret), app.QueryParams().Get("secret"))
+	assert.False(t, authenticator.Verify("abcdef"))
+	assert.True(t, authenticator.Verify(app.Generate(now.Unix())))
+	assert.False(t, authenticator.Verify(app.Generate(now.Unix())))
+
+	// A code of the previous time step is tolerated, but not once a later one has been accepted.
+	now = now.Add(30 * time.Second)
+	previous := app.Generate(now.Unix() - 30)
+	assert.True(t, authenticator.Verify(app.Generate(now.Unix())))
+	assert.False(t, authenticator.Verify(previous))
+
+	// An enrolled account is verified again with its persisted secret key.
+	restored, err := NewAuthenticator("Example", HashAlgorithmSHA1, nil, WithSecret(secret),
+		WithClock(func() time.Time { return now }))
+	assert.NoError(t, err)
+	assert.True(t, restored.Verify(previous))
+	assert.False(t, restored.Verify(previous))
+
+	// Enrolling again replaces the secret key and resets the last accepted time step.
+	again, uri, err := authenticator.Enroll("alice@example.com")
+	assert.NoError(t, err)
+	assert.NotEqual(t, secret, again)
+	app, err = ParseURI(uri)
+	assert.NoError(t, err)
+	assert.True(t, authenticator.Verify(app.Generate(now.Unix())))
+	assert.False(t, authenticator.Verify(app.Generate(now.Unix())))
+}
+
+func TestAuthenticatorOptions(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	now := time.Unix(59, 0)
+	authenticator, err := NewAuthenticator("Example", HashAlgorithmSHA1, nil, WithSecret(secret), WithDigits(8),
+		WithSkewWindow(0, 0), WithClock(func() time.Time { return now }))
+	assert.NoError(t, err)
+	assert.Equal(t, 8, authenticator.Manager().CodeDigits())
+	assert.True(t, authenticator.Verify("94287082"))
+
+	_, uri, err := authenticator.Enroll("alice")
+	assert.NoError(t, err)
+	app, err := ParseURI(uri)
+	assert.NoError(t, err)
+	assert.Equal(t, "8", app.QueryParams().Get("digits"))
+	assert.NotEqual(t, secret, app.Secret())
+	assert.False(t, authenticator.Verify("94287082"))
+
+	_, _, err = authenticator.Enroll("")
+	assert.EqualError(t, err, "invalid account name")
+	assert.Equal(t, app.Secret(), authenticator.Manager().Secret())
+
+	_, err = NewAuthenticator("Example", HashAlgorithmSHA1, nil, WithDigits(11))
+	assert.Error(t, err)
+}
+
+// failingLastStepStore represents a last step store that always fails.
+type failingLastStepStore struct{}
+
+func (failingLastStepStore) AdvanceLastStep(int64) (bool, error) {
+	return false, errors.New("store unavailable")
+}
+
+func (failingLastStepStore) ResetLastStep() error {
+	return errors.New("store unavailable")
+}
+
+// blockingLastStepStore represents a last step store whose AdvanceLastStep blocks until released.
+type blockingLastStepStore struct {
+	memoryLastStepStore
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (store *blockingLastStepStore) AdvanceLastStep(movingFactor int64) (bool, error) {
+	close(store.entered)
+	<-store.release
+	return store.memoryLastStepStore.AdvanceLastStep(movingFactor)
+}
+
+func TestAuthenticatorConcurrentEnroll(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	store := &blockingLastStepStore{entered: make(chan struct{}), release: make(chan struct{})}
+	authenticator, err := NewAuthenticator("Example", HashAlgorithmSHA1, store, WithSecret(secret),
+		WithClock(func() time.Time { return time.Unix(59, 0) }))
+	assert.NoError(t, err)
+
+	// A code of the old secret key is being accepted while a new secret key is enrolled.
+	var wait sync.WaitGroup
+	wait.Add(2)
+	go func() {
+		defer wait.Done()
+		assert.True(t, authenticator.Verify("287082"))
+	}()
+	<-store.entered
+	var uri string
+	go func() {
+		defer wait.Done()
+		_, uri, err = authenticator.Enroll("alice")
+		assert.NoError(t, err)
+	}()
+	// Give Enroll the time to reset the record too early, if it does not wait for the Verify in progress.
+	time.Sleep(10 * time.Millisecond)
+	close(store.release)
+	wait.Wait()
+
+	// The time step accepted with the old secret key does not reject the first code of the new one.
+	app, err := ParseURI(uri)
+	assert.NoError(t, err)
+	store.entered = make(chan struct{})
+	assert.True(t, authenticator.Verify(app.Generate(59)))
+}
+
+func TestAuthenticatorStoreFailure(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	authenticator, err := NewAuthenticator("Example", HashAlgorithmSHA1, failingLastStepStore{}, WithSecret(secret),
+		WithClock(func() time.Time { return time.Unix(59, 0) }))
+	assert.NoError(t, err)
+	assert.False(t, authenticator.Verify("287082"))
+	_, _, err = authenticator.Enroll("alice")
+	assert.EqualError(t, err, "store unavailable")
+	assert.Equal(t, secret, authenticator.Manager().Secret())
+}
@@ -0,0 +1,167 @@
+package otp
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+)
+
+const (
+	// backupCodeSymbols represents the symbols of backup codes, which are lowercase letters and digits except those
+	// easily confused with each other, such as 0, 1, i, l and o.
+	backupCodeSymbols = "23456789abcdefghjkmnpqrstuvwxyz"
+
+	// backupCodeGroup represents the number of symbols in each of the two groups of a backup code.
+	backupCodeGroup = 4
+
+	// maxBackupCodes represents the maximum number of backup codes generated at once.
+	maxBackupCodes = 100
+
+	// backupCodeSaltSize represents the size of the random salt stored in front of the hash of each backup code.
+	backupCodeSaltSize = 16
+
+	// backupCodeKeySize represents the size of the PBKDF2 key stored as the hash of each backup code.
+	backupCodeKeySize = 32
+
+	// backupCodeIterations represents the number of PBKDF2-HMAC-SHA256 iterations of the hash of each backup code.
+	backupCodeIterations = 20000
+)
+
+// BackupCodes represents a set of single-use backup codes, which users enter instead of one-time passwords when they
+// have lost their authenticator. Codes look like "k7mq-x2fd", and only their hashes are kept, so the codes themselves
+// are shown once when generated and are not stored. Each hash is a random salt followed by the PBKDF2-HMAC-SHA256 key
+// of the code, so that the hashes of different codes cannot be attacked together. However, a code only has about 40
+// bits of entropy, so a leaked hash can still be brute-forced with enough effort, and the hashes should be protected
+// like passwords.
+//
+// BackupCodes is safe for concurrent use by multiple goroutines.
+type BackupCodes struct {
+	mutex  sync.Mutex
+	hashes [][]byte
+}
+
+// NewBackupCodes generates count backup codes with cryptographically secure pseudo-random numbers, and gets the codes
+// to show to the user along with the backup codes holding their hashes. Count must be between 1 and 100.
+func NewBackupCodes(count int) (*BackupCodes, []string, error) {
+	return newBackupCodes(rand.Reader, count)
+}
+
+// newBackupCodes generates backup codes like NewBackupCodes with symbols read from the random source.
+func newBackupCodes(random io.Reader, count int) (*BackupCodes, []string, error) {
+	if count < 1 || count > maxBackupCodes {
+		return nil, nil, errors.New("invalid count")
+	}
+	codes := make([]string, count)
+	hashes := make([][]byte, count)
+	for i := range codes {
+		symbols, err := randomSymbols(random, backupCodeSymbols, 2*backupCodeGroup)
+		if err != nil {
+			return nil, nil, err
+		}
+		salt := make([]byte, backupCodeSaltSize)
+		if _, err := io.ReadFull(random, salt); err != nil {
+			return nil, nil, err
+		}
+		codes[i] = symbols[:backupCodeGroup] + "-" + symbols[backupCodeGroup:]
+		hashes[i] = hashBackupCode(symbols, salt)
+	}
+	return &BackupCodes{hashes: hashes}, codes, nil
+}
+
+// LoadBackupCodes creates backup codes from the hashes got from Hashes, such as when they are loaded from storage.
+func LoadBackupCodes(hashes [][]byte) *BackupCodes {
+	codes := &BackupCodes{hashes: make([][]byte, len(hashes))}
+	for i, hash := range hashes {
+		codes.hashes[i] = append([]byte(nil), hash...)
+	}
+	return codes
+}
+
+// Hashes gets the hashes of the backup codes that have not been used, for storage. They should be stored again after
+// every successful Verify, so that used codes are not accepted after the backup codes are loaded again.
+func (codes *BackupCodes) Hashes() [][]byte {
+	codes.mutex.Lock()
+	defer codes.mutex.Unlock()
+	hashes := make([][]byte, len(codes.hashes))
+	for i, hash := range codes.hashes {
+		hashes[i] = append([]byte(nil), hash...)
+	}
+	return hashes
+}
+
+// Remaining gets the number of backup codes that have not been used.
+func (codes *BackupCodes) Remaining() int {
+	codes.mutex.Lock()
+	defer codes.mutex.Unlock()
+	return len(codes.hashes)
+}
+
+// Verify validates whether the code matches one of the backup codes that have not been used, and consumes it if so,
+// so that every backup code is accepted at most once. Letter case, the hyphen and whitespace are ignored. The code is
+// hashed with the salt of every backup code and compared in constant time, and all of them are always compared, so the
+// response time does not reveal which code matched. Since every hash is deliberately slow, the time taken grows with
+// the number of remaining backup codes.
+func (codes *BackupCodes) Verify(code string) bool {
+	code = normalizeBackupCode(code)
+	codes.mutex.Lock()
+	defer codes.mutex.Unlock()
+	index := -1
+	for i, stored := range codes.hashes {
+		if len(stored) != backupCodeSaltSize+backupCodeKeySize {
+			continue
+		}
+		hash := hashBackupCode(code, stored[:backupCodeSaltSize])
+		if subtle.ConstantTimeCompare(stored, hash) == 1 && index < 0 {
+			index = i
+		}
+	}
+	if index < 0 {
+		return false
+	}
+	codes.hashes = append(codes.hashes[:index], codes.hashes[index+1:]...)
+	return true
+}
+
+// randomSymbols gets a string of the specified length with symbols chosen uniformly with bytes read from the random
+// source. Bytes that would make some symbols more likely than others are discarded.
+func randomSymbols(random io.Reader, symbols string, length int) (string, error) {
+	limit := 256 - 256%len(symbols)
+	result := make([]byte, 0, length)
+	buffer := make([]byte, length)
+	for len(result) < length {
+		chunk := buffer[:length-len(result)]
+		if _, err := io.ReadFull(random, chunk); err != nil {
+			return "", err
+		}
+		for _, b := range chunk {
+			if int(b) < limit {
+				result = append(result, symbols[int(b)%len(symbols)])
+			}
+		}
+	}
+	return string(result), nil
+}
+
+// normalizeBackupCode removes the hyphen and whitespace from the backup code, and converts it to lowercase.
+func normalizeBackupCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '-', ' ', '\t', '\n', '\r':
+			return -1
+		}
+		return r
+	}, strings.ToLower(code))
+}
+
+// hashBackupCode gets the hash of the normalized backup code with the salt, which is the salt followed by the PBKDF2
+// key of the code.
+func hashBackupCode(code string, salt []byte) []byte {
+	// PBKDF2 only fails with key sizes too large for the hash, which the constant key size is not.
+	key, _ := pbkdf2.Key(sha256.New, code, salt, backupCodeIterations, backupCodeKeySize)
+	return append(append(make([]byte, 0, len(salt)+len(key)), salt...), key...)
+}
@@ -0,0 +1,79 @@
+package otp
+
+import (
+	"bytes"
+	"encoding/hex"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackupCodes(t *testing.T) {
+	backup, codes, err := NewBackupCodes(10)
+	assert.NoError(t, err)
+	assert.Len(t, codes, 10)
+	assert.Equal(t, 10, backup.Remaining())
+	assert.Len(t, backup.Hashes(), 10)
+	format := regexp.MustCompile(`^[23456789abcdefghjkmnpqrstuvwxyz]{4}-[23456789abcdefghjkmnpqrstuvwxyz]{4}$`)
+	seen := map[string]bool{}
+	for _, code := range codes {
+		assert.True(t, format.MatchString(code), code)
+		assert.False(t, seen[code], code)
+		seen[code] = true
+	}
+
+	// Every code is accepted once, in any order and formatting.
+	assert.True(t, backup.Verify(codes[3]))
+	assert.False(t, backup.Verify(codes[3]))
+	assert.Equal(t, 9, backup.Remaining())
+	assert.True(t, backup.Verify(" "+codes[0][:4]+" "+codes[0][5:]+"\n"))
+	assert.False(t, backup.Verify(codes[0]))
+	assert.Equal(t, 8, backup.Remaining())
+	assert.False(t, backup.Verify(""))
+	assert.False(t, backup.Verify("aaaa-aaaa"))
+	assert.Equal(t, 8, backup.Remaining())
+
+	// Used codes stay consumed after a round trip through storage.
+	loaded := LoadBackupCodes(backup.Hashes())
+	assert.Equal(t, 8, loaded.Remaining())
+	assert.False(t, loaded.Verify(codes[3]))
+	assert.True(t, loaded.Verify(codes[9]))
+	assert.True(t, backup.Verify(codes[9]))
+	for _, code := range codes[1:3] {
+		assert.True(t, loaded.Verify(code))
+	}
+	assert.Equal(t, 5, loaded.Remaining())
+
+	for _, count := range []int{-1, 0, 101} {
+		_, _, err := NewBackupCodes(count)
+		assert.EqualError(t, err, "invalid count")
+	}
+	_, codes, err = NewBackupCodes(maxBackupCodes)
+	assert.NoError(t, err)
+	assert.Len(t, codes, maxBackupCodes)
+}
+
+func TestBackupCodesRandomSource(t *testing.T) {
+	// Bytes from 248 are discarded, and the others select the symbols modulo 31. The salt is read after the symbols,
+	// and the expected key is computed with Python's hashlib.pbkdf2_hmac.
+	salt := []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+	random := bytes.NewReader(append([]byte{0, 1, 248, 255, 30, 31, 62, 247, 100, 200}, salt...))
+	backup, codes, err := newBackupCodes(random, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"23z2-2z9g"}, codes)
+	key, _ := hex.DecodeString("4ad2f13262271b8479c3e6896bd528ca4e63681b9ddca6f28ab3c636d333e1e6")
+	assert.Equal(t, [][]byte{append(salt, key...)}, backup.Hashes())
+	assert.True(t, backup.Verify("23Z2-2Z9G"))
+
+	// The same code gets a different hash with a different salt.
+	assert.NotEqual(t, hashBackupCode("23z22z9g", salt), hashBackupCode("23z22z9g", make([]byte, backupCodeSaltSize)))
+
+	// Hashes of unexpected sizes never match.
+	assert.False(t, LoadBackupCodes([][]byte{key}).Verify("23z2-2z9g"))
+
+	_, _, err = newBackupCodes(bytes.NewReader([]byte{1, 2, 3}), 1)
+	assert.Error(t, err)
+	_, _, err = newBackupCodes(bytes.NewReader([]byte{1, 2, 3, 4, 5, 6, 7, 8, 9}), 1)
+	assert.Error(t, err)
+}
@@ -0,0 +1,45 @@
+package otp
+
+// ChallengeResponse computes the response to a challenge for the specified counter, in the spirit of the OCRA
+// challenge-response algorithm described in RFC 6287.
+//
+// The HMAC message is the big-endian counter, 8 bytes long unless set otherwise with WithCounterBytes, followed by the
+// device identifier if the manager is bound to one, followed by the challenge as raw bytes, without any separator or
+// length prefix. The HMAC result is then truncated and rendered exactly like an HOTP code. An empty challenge gives the
+// plain HOTP code. This is a building block, not an implementation of OCRA suites: the challenge is not padded or
+// converted as OCRA does.
+func (generator *hotpManager) ChallengeResponse(movingFactor int64, challenge string) string {
+	response, _ := generator.generateMessage(movingFactor, []byte(challenge), generator.codeDigits)
+	return response
+}
+
+// ValidateChallengeResponse validates whether the response to a challenge matches for the specified counter.
+func (generator *hotpManager) ValidateChallengeResponse(movingFactor int64, challenge, response string) bool {
+	response, ok := generator.prepareInput(response)
+	return ok && generator.validateMessageMAC(generator.newMAC(), movingFactor, []byte(challenge), response)
+}
+
+// ChallengeResponse computes the response to a challenge for the time step of the specified epoch. Refers to the HOTP
+// counterpart for the message format.
+func (generator *totpManager) ChallengeResponse(epoch int64, challenge string) string {
+	return generator.hotp.ChallengeResponse(generator.MovingFactor(epoch), challenge)
+}
+
+// ValidateChallengeResponse validates whether the response to a challenge matches the specified epoch within the
+// tolerant time steps. Like Validate, every time step is always compared and the results are combined in constant
+// time, so the response time does not reveal which time step matched.
+func (generator *totpManager) ValidateChallengeResponse(epoch int64, challenge, response string) bool {
+	response, ok := generator.hotp.prepareInput(response)
+	if !ok {
+		return false
+	}
+	movingFactor := generator.MovingFactor(epoch)
+	state := generator.hotp.newMAC()
+	matched := 0
+	for i := -generator.lookBackward; i <= generator.lookForward; i += 1 {
+		if generator.hotp.validateMessageMAC(state, movingFactor+int64(i), []byte(challenge), response) {
+			matched |= 1
+		}
+	}
+	return matched == 1
+}
@@ -0,0 +1,46 @@
+package otp
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHOTPChallengeResponse(t *testing.T) {
+	// Expected values are HMAC-SHA1 of the counter followed by the challenge, computed with a reference implementation
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewHOTP(HashAlgorithmSHA1, secret, 6)
+	assert.NoError(t, err)
+	assert.Equal(t, "792092", generator.ChallengeResponse(0, "12345678"))
+	assert.Equal(t, "203658", generator.ChallengeResponse(1, "12345678"))
+	assert.Equal(t, "119467", generator.ChallengeResponse(0, "87654321"))
+	assert.Equal(t, generator.Generate(0), generator.ChallengeResponse(0, ""))
+
+	assert.True(t, generator.ValidateChallengeResponse(0, "12345678", "792092"))
+	assert.False(t, generator.ValidateChallengeResponse(1, "12345678", "792092"))
+	assert.False(t, generator.ValidateChallengeResponse(0, "87654321", "792092"))
+}
+
+func TestTOTPChallengeResponse(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 1, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "08122826", generator.ChallengeResponse(1234567890, "CHALLENGE"))
+	assert.True(t, generator.ValidateChallengeResponse(1234567890, "CHALLENGE", "08122826"))
+	assert.True(t, generator.ValidateChallengeResponse(1234567920, "CHALLENGE", "08122826"))
+	assert.False(t, generator.ValidateChallengeResponse(1234567950, "CHALLENGE", "08122826"))
+	assert.False(t, generator.ValidateChallengeResponse(1234567890, "challenge", "08122826"))
+	assert.False(t, generator.ValidateChallengeResponse(1234567890, "CHALLENGE", ""))
+
+	// Every time step of the window is computed, even after a match.
+	reads := 0
+	generator, err = NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 2, 2, WithEncoder(countingEncoder{&reads}))
+	assert.NoError(t, err)
+	assert.True(t, generator.ValidateChallengeResponse(1234567830, "CHALLENGE", "08122826"))
+	assert.Equal(t, 5, reads)
+
+	bound, err := NewTOTPBound(HashAlgorithmSHA1, secret, 8, 30, 0, 0, []byte("device-1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "54381480", bound.ChallengeResponse(1234567890, "CHALLENGE"))
+}
@@ -0,0 +1,46 @@
+package otp
+
+import "errors"
+
+// Clone creates a copy of the manager with the specified options applied on top of its settings, such as a manager
+// with fewer tolerant time steps for sensitive operations, or with another digit count. The copy holds its own copy of
+// the secret key and of all other mutable state, so it must be closed separately, and validating with one never
+// affects the other: the copy starts with an empty skew histogram if the manager has one, and with its own in-memory
+// record of used codes unless a store was set with WithUsedCodeStore, which is kept. Options are validated exactly
+// like the constructors do, and the manager is left untouched when an error is returned.
+func (generator *totpManager) Clone(opts ...TOTPOption) (TOTPManager, error) {
+	if generator.hotp.closed() {
+		return nil, errors.New("manager closed")
+	}
+	clone := generator.copy()
+	for _, opt := range opts {
+		if err := opt.applyTOTP(clone); err != nil {
+			return nil, err
+		}
+	}
+	if err := clone.hotp.complete(); err != nil {
+		return nil, err
+	}
+	if clone.derivedT0 {
+		clone.t0 = clone.hotp.deriveT0(clone.timeStep)
+	}
+	return clone, nil
+}
+
+// copy creates a copy of the manager with its own copy of the secret key and of all other mutable state, as described
+// by Clone.
+func (generator *totpManager) copy() *totpManager {
+	hotp := *generator.hotp
+	hotp.secret = append([]byte(nil), generator.hotp.secret...)
+	hotp.binding = append([]byte(nil), generator.hotp.binding...)
+	hotp.counter = &hotpCounter{value: generator.hotp.Counter()}
+	copied := *generator
+	copied.hotp = &hotp
+	if generator.skew != nil {
+		copied.skew = &skewHistogram{}
+	}
+	if _, ok := generator.used.(*memoryUsedCodeStore); ok {
+		copied.used = &memoryUsedCodeStore{}
+	}
+	return &copied
+}
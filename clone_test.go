@@ -0,0 +1,65 @@
+package otp
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTOTPClone(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	original, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 1, 1, WithSkewHistogram())
+	assert.NoError(t, err)
+
+	clone, err := original.Clone(WithDigits(6), WithSkewWindow(0, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, original.Secret(), clone.Secret())
+	assert.Equal(t, "005924", clone.Generate(1234567890))
+	assert.Equal(t, 0, clone.LookBackward())
+	assert.Equal(t, 0, clone.LookForward())
+	assert.False(t, clone.Validate(1234567920, "005924"))
+	assert.Equal(t, "89005924", original.Generate(1234567890))
+	assert.Equal(t, 1, original.LookBackward())
+	assert.True(t, original.Validate(1234567920, "89005924"))
+
+	// The clone shares no mutable state with the original.
+	assert.True(t, clone.Validate(1234567890, "005924"))
+	assert.Equal(t, map[int]int64{-1: 1}, original.SkewHistogram())
+	assert.Equal(t, map[int]int64{0: 1}, clone.SkewHistogram())
+	assert.True(t, original.ValidateOnce(1234567890, "89005924"))
+	same, err := original.Clone()
+	assert.NoError(t, err)
+	assert.True(t, same.ValidateOnce(1234567890, "89005924"))
+	assert.False(t, original.ValidateOnce(1234567890, "89005924"))
+	clone.Close()
+	assert.Equal(t, "89005924", original.Generate(1234567890))
+	assert.Equal(t, secret, original.Secret())
+	original.Close()
+	assert.Equal(t, "89005924", same.Generate(1234567890))
+
+	// Settings of the original are kept unless overridden, and overrides are validated.
+	prefixed, err := NewTOTPDuration(HashAlgorithmSHA256, nil, 6, 1500*time.Millisecond, 2, 0, WithExpectedPrefix("G-"))
+	assert.NoError(t, err)
+	clone, err = prefixed.Clone(WithSkewWindow(0, 1))
+	assert.NoError(t, err)
+	now := time.Unix(1234567890, 0)
+	assert.Equal(t, prefixed.GenerateTime(now), clone.GenerateTime(now))
+	assert.Equal(t, 1500*time.Millisecond, clone.TimeStepDuration())
+	assert.True(t, clone.ValidateTime(now, "G-"+prefixed.GenerateTime(now)))
+	_, err = prefixed.Clone(WithDigits(11))
+	assert.True(t, errors.Is(err, ErrInvalidCodeDigit))
+	_, err = prefixed.Clone(WithSkewWindow(-1, 0))
+	assert.True(t, errors.Is(err, ErrInvalidLookBackward))
+	assert.Equal(t, 2, prefixed.LookBackward())
+
+	fresh, err := prefixed.Clone(WithSecret(nil))
+	assert.NoError(t, err)
+	assert.NotEqual(t, prefixed.Secret(), fresh.Secret())
+
+	prefixed.Close()
+	_, err = prefixed.Clone()
+	assert.EqualError(t, err, "manager closed")
+}
@@ -0,0 +1,30 @@
+package otp
+
+import "crypto/subtle"
+
+// Code represents a generated one-time password code.
+//
+// Codes should be compared with Equal rather than the == operator, which is not constant-time and may leak how many
+// leading characters of a guess are correct. Existing callers of Generate can switch to GenerateCode and convert the
+// user input with Code(input), or keep using Generate and Validate, which remain available and behave as before.
+type Code string
+
+// String gets the code as a plain string.
+func (code Code) String() string {
+	return string(code)
+}
+
+// Formatted gets the code split into two groups separated by a space for display, such as "123 456" for 6-digit codes
+// and "1234 5678" for 8-digit codes. When the length is odd the first group is the shorter one.
+func (code Code) Formatted() string {
+	if len(code) < 2 {
+		return string(code)
+	}
+	half := len(code) / 2
+	return string(code[:half]) + " " + string(code[half:])
+}
+
+// Equal checks whether two codes are identical in constant time.
+func (code Code) Equal(other Code) bool {
+	return subtle.ConstantTimeCompare([]byte(code), []byte(other)) == 1
+}
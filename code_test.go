@@ -0,0 +1,36 @@
+package otp
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodeFormatted(t *testing.T) {
+	assert.Equal(t, "", Code("").Formatted())
+	assert.Equal(t, "1", Code("1").Formatted())
+	assert.Equal(t, "123 456", Code("123456").Formatted())
+	assert.Equal(t, "123 4567", Code("1234567").Formatted())
+	assert.Equal(t, "0708 1804", Code("07081804").Formatted())
+}
+
+func TestCodeEqual(t *testing.T) {
+	assert.True(t, Code("123456").Equal(Code("123456")))
+	assert.False(t, Code("123456").Equal(Code("123457")))
+	assert.False(t, Code("123456").Equal(Code("12345")))
+	assert.False(t, Code("123456").Equal(Code("")))
+}
+
+func TestGenerateCode(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	hotp, err := NewHOTP(HashAlgorithmSHA1, secret, 6)
+	assert.NoError(t, err)
+	assert.Equal(t, Code("755224"), hotp.GenerateCode(0))
+	assert.Equal(t, hotp.Generate(1), hotp.GenerateCode(1).String())
+
+	totp, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 0, 0)
+	assert.NoError(t, err)
+	assert.True(t, totp.GenerateCode(1111111109).Equal("07081804"))
+	assert.Equal(t, totp.Generate(59), totp.GenerateCode(59).String())
+}
@@ -0,0 +1,143 @@
+package otp
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+const (
+	// compactVersion represents the version of the compact provisioning payload format.
+	compactVersion = '1'
+
+	// compactHeaderLength represents the length of the fixed-width fields following the version.
+	compactHeaderLength = 2 + 2 + 5
+
+	// maxCompactPeriod represents the maximum time step that fits into the compact provisioning payload.
+	maxCompactPeriod = 99999
+)
+
+// CompactProvisioning represents the essential settings of a TOTP manager carried by a compact provisioning payload.
+type CompactProvisioning struct {
+	// Algorithm is the hash algorithm used for HMAC.
+	Algorithm HashAlgorithm
+
+	// Digits is the digit count of password codes.
+	Digits int
+
+	// Period is the time step in seconds.
+	Period int
+
+	// Secret is the secret key.
+	Secret []byte
+}
+
+// EncodeCompact encodes the settings as a payload consisting of decimal digits only, for provisioning hardware tokens
+// that can only be configured through a numeric keypad, where URIs and QR codes are impractical.
+//
+// The payload of version 1 consists of the following fields, all zero-padded decimal numbers:
+//
+//	1 digit    version, which is 1
+//	2 digits   algorithm: 00 for SHA1, 01 for SHA256, 02 for SHA512
+//	2 digits   digit count of password codes
+//	5 digits   time step in seconds
+//	3n digits  each byte of the secret key as a number from 000 to 255
+//	1 digit    Luhn check digit over all preceding digits
+//
+// Later versions may add fields, and are told apart by the leading version digit.
+func (config CompactProvisioning) EncodeCompact() (string, error) {
+	if _, err := config.Algorithm.hash(); err != nil {
+		return "", err
+	}
+	if !config.Algorithm.standard() {
+		return "", errors.New("unsupported hash algorithm")
+	}
+	if config.Digits <= 0 || config.Digits > MaxCodeDigits {
+		return "", &ParamError{Param: "codeDigit", Value: config.Digits, Min: 1, Max: MaxCodeDigits}
+	}
+	if config.Period <= 0 || config.Period > maxCompactPeriod {
+		return "", &ParamError{Param: "timeStep", Value: config.Period, Min: 1, Max: maxCompactPeriod}
+	}
+	if len(config.Secret) == 0 {
+		return "", errors.New("invalid secret")
+	}
+
+	var builder strings.Builder
+	builder.WriteByte(compactVersion)
+	writePadded(&builder, int(config.Algorithm), 2)
+	writePadded(&builder, config.Digits, 2)
+	writePadded(&builder, config.Period, 5)
+	for _, b := range config.Secret {
+		writePadded(&builder, int(b), 3)
+	}
+	builder.WriteByte('0' + luhnCheckDigit(builder.String()))
+	return builder.String(), nil
+}
+
+// ParseCompact parses a compact provisioning payload created by EncodeCompact. Payloads with a wrong check digit, which
+// usually indicates a typo on the keypad, are rejected.
+func ParseCompact(payload string) (CompactProvisioning, error) {
+	var config CompactProvisioning
+	for _, c := range payload {
+		if c < '0' || c > '9' {
+			return config, errors.New("invalid payload")
+		}
+	}
+	if len(payload) == 0 || payload[0] != compactVersion {
+		return config, errors.New("unknown payload version")
+	}
+	body, check := payload[:len(payload)-1], payload[len(payload)-1]
+	if len(body) < 1+compactHeaderLength+3 || (len(body)-1-compactHeaderLength)%3 != 0 {
+		return config, errors.New("invalid payload")
+	}
+	if check != '0'+luhnCheckDigit(body) {
+		return config, errors.New("invalid check digit")
+	}
+
+	algorithm, _ := strconv.Atoi(body[1:3])
+	config.Algorithm = HashAlgorithm(algorithm)
+	if !config.Algorithm.standard() {
+		return config, ErrUnknownAlgorithm
+	}
+	config.Digits, _ = strconv.Atoi(body[3:5])
+	if config.Digits <= 0 || config.Digits > MaxCodeDigits {
+		return config, &ParamError{Param: "codeDigit", Value: config.Digits, Min: 1, Max: MaxCodeDigits}
+	}
+	config.Period, _ = strconv.Atoi(body[5:10])
+	if config.Period <= 0 {
+		return config, &ParamError{Param: "timeStep", Value: config.Period, Min: 1, Max: maxCompactPeriod}
+	}
+	secret := body[1+compactHeaderLength:]
+	config.Secret = make([]byte, len(secret)/3)
+	for i := range config.Secret {
+		b, _ := strconv.Atoi(secret[i*3 : i*3+3])
+		if b > 255 {
+			return CompactProvisioning{}, errors.New("invalid payload")
+		}
+		config.Secret[i] = byte(b)
+	}
+	return config, nil
+}
+
+// writePadded writes the value as a decimal number zero-padded to the specified width.
+func writePadded(builder *strings.Builder, value, width int) {
+	digits := strconv.Itoa(value)
+	builder.WriteString(strings.Repeat("0", width-len(digits)))
+	builder.WriteString(digits)
+}
+
+// luhnCheckDigit computes the Luhn check digit of a string of decimal digits.
+func luhnCheckDigit(digits string) byte {
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i -= 1 {
+		d := int(digits[i] - '0')
+		if (len(digits)-1-i)%2 == 0 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return byte((10 - sum%10) % 10)
+}
@@ -0,0 +1,61 @@
+package otp
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeCompact(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	config := CompactProvisioning{Algorithm: HashAlgorithmSHA1, Digits: 6, Period: 30, Secret: secret}
+	payload, err := config.EncodeCompact()
+	assert.NoError(t, err)
+	assert.Equal(t, "10006000300490500510520530540550560570480490500510520530540550560570484", payload)
+
+	parsed, err := ParseCompact(payload)
+	assert.NoError(t, err)
+	assert.Equal(t, config, parsed)
+
+	config = CompactProvisioning{Algorithm: HashAlgorithmSHA512, Digits: 8, Period: 60, Secret: []byte{0, 255, 7}}
+	payload, err = config.EncodeCompact()
+	assert.NoError(t, err)
+	parsed, err = ParseCompact(payload)
+	assert.NoError(t, err)
+	assert.Equal(t, config, parsed)
+
+	_, err = CompactProvisioning{Algorithm: HashAlgorithm(-1), Digits: 6, Period: 30, Secret: secret}.EncodeCompact()
+	assert.EqualError(t, err, "unknown hash algorithm")
+	_, err = CompactProvisioning{Algorithm: HashAlgorithmSHA3_256, Digits: 6, Period: 30, Secret: secret}.EncodeCompact()
+	assert.EqualError(t, err, "unsupported hash algorithm")
+	_, err = CompactProvisioning{Algorithm: HashAlgorithmSHA1, Digits: 11, Period: 30, Secret: secret}.EncodeCompact()
+	assert.EqualError(t, err, "invalid code digit 11: must be between 1 and 10")
+	_, err = CompactProvisioning{Algorithm: HashAlgorithmSHA1, Digits: 6, Period: 100000, Secret: secret}.EncodeCompact()
+	assert.EqualError(t, err, "invalid time step")
+	_, err = CompactProvisioning{Algorithm: HashAlgorithmSHA1, Digits: 6, Period: 30}.EncodeCompact()
+	assert.EqualError(t, err, "invalid secret")
+}
+
+func TestParseCompact(t *testing.T) {
+	for _, test := range []struct {
+		payload string
+		err     string
+	}{
+		{"", "unknown payload version"},
+		{"2000600030049x", "invalid payload"},
+		{"20006000300490", "unknown payload version"},
+		{"1000600030", "invalid payload"},
+		{"100060003004905", "invalid payload"},
+		{"10006000300490500510520530540550560570480490500510520530540550560570485", "invalid check digit"},
+		{"10006000301490500510520530540550560570480490500510520530540550560570484", "invalid check digit"},
+		{"1030600030049" + string('0'+luhnCheckDigit("1030600030049")), "unknown hash algorithm"},
+		{"1001100030049" + string('0'+luhnCheckDigit("1001100030049")),
+			"invalid code digit 11: must be between 1 and 10"},
+		{"1000600000049" + string('0'+luhnCheckDigit("1000600000049")), "invalid time step"},
+		{"1000600030256" + string('0'+luhnCheckDigit("1000600030256")), "invalid payload"},
+	} {
+		_, err := ParseCompact(test.payload)
+		assert.EqualError(t, err, test.err, test.payload)
+	}
+}
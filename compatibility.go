@@ -0,0 +1,103 @@
+package otp
+
+import "fmt"
+
+// CompatibilityReport gets human-readable warnings about settings of the manager that mainstream authenticator apps
+// are unlikely to support. It is advisory only: the manager works regardless. Nil is returned for the standard HOTP
+// configuration, which is SHA1 with 6 digits.
+func (generator *hotpManager) CompatibilityReport() []string {
+	var warnings []string
+	if generator.algorithm != HashAlgorithmSHA1 {
+		name, _ := generator.algorithm.name()
+		warnings = append(warnings, fmt.Sprintf("algorithm %s is ignored by some apps, which use SHA1 instead", name))
+	}
+	if generator.codeDigits != 6 {
+		warnings = append(warnings, fmt.Sprintf("%d digits are not supported by some apps, which show 6 digits instead",
+			generator.codeDigits))
+	}
+	if generator.encoder != DecimalEncoder {
+		warnings = append(warnings, "codes other than decimal digits are not supported by authenticator apps")
+	}
+	if generator.offset != dynamicTruncation {
+		warnings = append(warnings, "fixed truncation offsets are not supported by authenticator apps")
+	}
+	if generator.checksum {
+		warnings = append(warnings, "checksum digits are not supported by authenticator apps")
+	}
+	if generator.padding != PaddingZero {
+		warnings = append(warnings, "codes without leading zeros are not supported by authenticator apps")
+	}
+	if generator.counterBytes != 8 {
+		warnings = append(warnings, fmt.Sprintf("%d-byte counters are not supported by authenticator apps",
+			generator.counterBytes))
+	}
+	if len(generator.binding) > 0 {
+		warnings = append(warnings, "codes bound to a device identifier are not supported by authenticator apps")
+	}
+	return warnings
+}
+
+// CompatibilityReport gets human-readable warnings about settings of the manager that mainstream authenticator apps
+// are unlikely to support. Nil is returned for the standard TOTP configuration, which is SHA1 with 6 digits and a time
+// step of 30 seconds.
+func (generator *totpManager) CompatibilityReport() []string {
+	warnings := generator.hotp.CompatibilityReport()
+	if generator.stepDuration != 0 {
+		warnings = append(warnings, "time steps that are not a whole number of seconds are not supported by authenticator apps")
+	} else if generator.timeStep != 30 {
+		warnings = append(warnings, fmt.Sprintf("time step of %d seconds is ignored by some apps, which use 30 seconds "+
+			"instead", generator.timeStep))
+	}
+	if generator.derivedT0 && generator.t0 != 0 {
+		warnings = append(warnings, "secret-derived T0 is not supported by authenticator apps")
+	} else if generator.t0 != 0 {
+		warnings = append(warnings, "T0 other than 0 is not supported by authenticator apps")
+	}
+	return warnings
+}
+
+// SecretWarningKind identifies what a SecretWarning is about.
+type SecretWarningKind int
+
+const (
+	// SecretTooShort warns that the secret key is shorter than the default key size of the hash algorithm.
+	SecretTooShort SecretWarningKind = iota
+
+	// SecretExceedsBlockSize warns that the secret key is longer than the block size of the hash function.
+	SecretExceedsBlockSize
+)
+
+// SecretWarning represents a warning about the length of the secret key got from CheckSecret.
+type SecretWarning struct {
+	// Kind is what the warning is about, for tooling to handle warnings without parsing the message.
+	Kind SecretWarningKind
+
+	// Message is a human-readable description of the warning.
+	Message string
+}
+
+// CheckSecret gets warnings about the length of the secret key, such as for enrollment tooling to report keys that are
+// shorter than the default key size of the hash algorithm recommended by RFC 4226, or longer than the block size of the
+// hash function, which HMAC hashes before use. Such keys work, but suggest a misconfiguration. It is advisory only, and
+// nil is returned for keys between the two sizes, or if the manager has been closed.
+func (generator *hotpManager) CheckSecret() []SecretWarning {
+	if generator.closed() {
+		return nil
+	}
+	var warnings []SecretWarning
+	name, _ := generator.algorithm.name()
+	if recommended, _ := generator.algorithm.DefaultKeyByteSize(); len(generator.secret) < recommended {
+		warnings = append(warnings, SecretWarning{SecretTooShort, fmt.Sprintf("secret key of %d bytes is shorter than "+
+			"the %d bytes recommended for %s", len(generator.secret), recommended, name)})
+	}
+	if blockSize := generator.hashAlgorithm().BlockSize(); len(generator.secret) > blockSize {
+		warnings = append(warnings, SecretWarning{SecretExceedsBlockSize, fmt.Sprintf("secret key of %d bytes exceeds "+
+			"the block size of %s of %d bytes, and is hashed by HMAC before use", len(generator.secret), name, blockSize)})
+	}
+	return warnings
+}
+
+// CheckSecret gets warnings about the length of the secret key. Refers to the HOTP counterpart for details.
+func (generator *totpManager) CheckSecret() []SecretWarning {
+	return generator.hotp.CheckSecret()
+}
@@ -0,0 +1,91 @@
+package otp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHOTPCompatibilityReport(t *testing.T) {
+	generator, err := NewHOTP(HashAlgorithmSHA1, nil, 6)
+	assert.NoError(t, err)
+	assert.Empty(t, generator.CompatibilityReport())
+
+	generator, err = NewHOTP(HashAlgorithmSHA256, nil, 8)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"algorithm SHA256 is ignored by some apps, which use SHA1 instead",
+		"8 digits are not supported by some apps, which show 6 digits instead",
+	}, generator.CompatibilityReport())
+
+	generator, err = NewHOTP(HashAlgorithmSHA1, nil, 6, WithEncoder(HexEncoder))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"codes other than decimal digits are not supported by authenticator apps",
+	}, generator.CompatibilityReport())
+
+	generator, err = NewHOTP(HashAlgorithmSHA1, nil, 6, WithChecksum(true))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"checksum digits are not supported by authenticator apps",
+	}, generator.CompatibilityReport())
+}
+
+func TestTOTPCompatibilityReport(t *testing.T) {
+	generator, err := NewTOTP(HashAlgorithmSHA1, nil, 6, 30, 1, 1)
+	assert.NoError(t, err)
+	assert.Empty(t, generator.CompatibilityReport())
+
+	generator, err = NewTOTP(HashAlgorithmSHA512, nil, 6, 60, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"algorithm SHA512 is ignored by some apps, which use SHA1 instead",
+		"time step of 60 seconds is ignored by some apps, which use 30 seconds instead",
+	}, generator.CompatibilityReport())
+
+	generator, err = NewTOTPDuration(HashAlgorithmSHA1, nil, 6, time.Second/2, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"time steps that are not a whole number of seconds are not supported by authenticator apps",
+	}, generator.CompatibilityReport())
+
+	generator, err = NewTOTPBound(HashAlgorithmSHA1, nil, 6, 30, 0, 0, []byte("device-1"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"codes bound to a device identifier are not supported by authenticator apps",
+	}, generator.CompatibilityReport())
+}
+
+func TestCheckSecret(t *testing.T) {
+	for _, algorithm := range []HashAlgorithm{HashAlgorithmSHA1, HashAlgorithmSHA256, HashAlgorithmSHA512,
+		HashAlgorithmSHA3_256, HashAlgorithmSHA3_512, HashAlgorithmSHA224, HashAlgorithmSHA384} {
+		generator, err := NewTOTP(algorithm, nil, 6, 30, 1, 0)
+		assert.NoError(t, err)
+		assert.Nil(t, generator.CheckSecret())
+	}
+	generator, err := NewHOTP(HashAlgorithmSHA1, make([]byte, 64), 6)
+	assert.NoError(t, err)
+	assert.Nil(t, generator.CheckSecret())
+
+	generator, err = NewHOTP(HashAlgorithmSHA1, []byte("1234567890123456"), 6)
+	assert.NoError(t, err)
+	assert.Equal(t, []SecretWarning{{SecretTooShort, "secret key of 16 bytes is shorter than the 20 bytes recommended " +
+		"for SHA1"}}, generator.CheckSecret())
+	totp, err := NewTOTP(HashAlgorithmSHA512, []byte("12345678901234567890"), 6, 30, 1, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []SecretWarning{{SecretTooShort, "secret key of 20 bytes is shorter than the 64 bytes recommended " +
+		"for SHA512"}}, totp.CheckSecret())
+
+	generator, err = NewHOTP(HashAlgorithmSHA1, make([]byte, 100), 6)
+	assert.NoError(t, err)
+	assert.Equal(t, []SecretWarning{{SecretExceedsBlockSize, "secret key of 100 bytes exceeds the block size of SHA1 " +
+		"of 64 bytes, and is hashed by HMAC before use"}}, generator.CheckSecret())
+	totp, err = NewTOTP(HashAlgorithmSHA3_512, make([]byte, 73), 6, 30, 1, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []SecretWarning{{SecretExceedsBlockSize, "secret key of 73 bytes exceeds the block size of " +
+		"SHA3-512 of 72 bytes, and is hashed by HMAC before use"}}, totp.CheckSecret())
+
+	generator.Close()
+	assert.Nil(t, generator.CheckSecret())
+}
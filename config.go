@@ -0,0 +1,86 @@
+package otp
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// Config represents the settings of a manager as declared in configuration files, such as YAML documents decoded with
+// gopkg.in/yaml.v3, or JSON documents created by MarshalJSON. A TOTP manager declared in YAML looks like:
+//
+//	type: totp
+//	algorithm: SHA1
+//	digits: 6
+//	period: 30
+//	skew: 1
+//	secret: GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ
+//
+// The secret key is included in base32, so configuration files declaring managers are as sensitive as the secret keys
+// themselves.
+type Config struct {
+	// Type is either "hotp" or "totp".
+	Type string `json:"type" yaml:"type"`
+
+	// Algorithm is the name of the hash algorithm, such as "SHA1", as used in key URIs.
+	Algorithm string `json:"algorithm" yaml:"algorithm"`
+
+	// Digits is the digit count of password codes.
+	Digits int `json:"digits" yaml:"digits"`
+
+	// Period is the time step of TOTP managers in seconds. Fractions of a second are allowed.
+	Period float64 `json:"period,omitempty" yaml:"period,omitempty"`
+
+	// Skew is the number of tolerant time steps of TOTP managers in both directions. It is a shorthand for setting
+	// LookBackward and LookForward to the same value, and cannot be combined with them.
+	Skew int `json:"skew,omitempty" yaml:"skew,omitempty"`
+
+	// LookBackward is the number of tolerant time steps of TOTP managers backward.
+	LookBackward int `json:"lookBackward,omitempty" yaml:"lookBackward,omitempty"`
+
+	// LookForward is the number of tolerant time steps of TOTP managers forward.
+	LookForward int `json:"lookForward,omitempty" yaml:"lookForward,omitempty"`
+
+	// Secret is the secret key encoded in base32.
+	Secret string `json:"secret" yaml:"secret"`
+}
+
+// Build creates the manager declared by the settings, which is an HOTPManager or a TOTPManager depending on Type. The
+// settings are validated exactly like the constructors do, and the same errors are returned. Settings of TOTP managers
+// are ignored for HOTP managers. Unlike the constructors, the secret key is required, since a declared manager is
+// useless with a new secret key, and it may be shorter than 16 bytes, since it may have been created with
+// AllowShortSecret.
+func (config Config) Build() (Manager, error) {
+	algorithm, err := parseAlgorithmName(config.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := DecodeSecret(config.Secret)
+	if err != nil {
+		return nil, err
+	}
+
+	switch config.Type {
+	case "hotp":
+		return NewHOTP(algorithm, secret, config.Digits, AllowShortSecret())
+	case "totp":
+		lookBackward, lookForward := config.LookBackward, config.LookForward
+		if config.Skew != 0 {
+			if lookBackward != 0 || lookForward != 0 {
+				return nil, errors.New("skew combined with look-backward or look-forward value")
+			}
+			lookBackward, lookForward = config.Skew, config.Skew
+		}
+		if config.Period > float64(math.MaxInt32) {
+			return nil, ErrInvalidTimeStep
+		}
+		if config.Period == math.Trunc(config.Period) {
+			return NewTOTP(algorithm, secret, config.Digits, int(config.Period), lookBackward, lookForward,
+				AllowShortSecret())
+		}
+		timeStep := time.Duration(config.Period * float64(time.Second))
+		return NewTOTPDuration(algorithm, secret, config.Digits, timeStep, lookBackward, lookForward, AllowShortSecret())
+	default:
+		return nil, errors.New("invalid type")
+	}
+}
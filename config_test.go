@@ -0,0 +1,91 @@
+package otp
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestConfigBuildYAML(t *testing.T) {
+	var config Config
+	err := yaml.Unmarshal([]byte(`
+type: totp
+algorithm: SHA1
+digits: 8
+period: 30
+skew: 1
+secret: GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ
+`), &config)
+	assert.NoError(t, err)
+	assert.Equal(t, Config{Type: "totp", Algorithm: "SHA1", Digits: 8, Period: 30, Skew: 1,
+		Secret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"}, config)
+	manager, err := config.Build()
+	assert.NoError(t, err)
+	totp := manager.(*totpManager)
+	assert.Equal(t, 1, totp.lookBackward)
+	assert.Equal(t, 1, totp.lookForward)
+	assert.Equal(t, "89005924", totp.Generate(1234567890))
+	assert.True(t, totp.Validate(1234567890+30, "89005924"))
+	assert.False(t, totp.Validate(1234567890+60, "89005924"))
+
+	config = Config{}
+	err = yaml.Unmarshal([]byte("type: hotp\nalgorithm: SHA1\ndigits: 6\n"+
+		"secret: gezd gnbv gy3t qojq gezd gnbv gy3t qojq\n"), &config)
+	assert.NoError(t, err)
+	manager, err = config.Build()
+	assert.NoError(t, err)
+	assert.IsType(t, &hotpManager{}, manager)
+	assert.Equal(t, "287082", manager.Generate(1))
+
+	// Settings encoded as YAML are built into a manager generating the same codes.
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	original, err := NewTOTP(HashAlgorithmSHA256, secret, 6, 60, 2, 2)
+	assert.NoError(t, err)
+	data, err := yaml.Marshal(Config{Type: "totp", Algorithm: "SHA256", Digits: 6, Period: 60, Skew: 2,
+		Secret: original.SecretBase32()})
+	assert.NoError(t, err)
+	assert.Equal(t, "type: totp\nalgorithm: SHA256\ndigits: 6\nperiod: 60\nskew: 2\n"+
+		"secret: GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ\n", string(data))
+	config = Config{}
+	assert.NoError(t, yaml.Unmarshal(data, &config))
+	manager, err = config.Build()
+	assert.NoError(t, err)
+	rebuilt := manager.(*totpManager)
+	assert.Equal(t, 60, rebuilt.timeStep)
+	assert.Equal(t, 2, rebuilt.lookBackward)
+	assert.Equal(t, 2, rebuilt.lookForward)
+	for epoch := int64(1234567890); epoch < 1234568890; epoch += 45 {
+		assert.Equal(t, original.Generate(epoch), rebuilt.Generate(epoch))
+		assert.True(t, rebuilt.Validate(epoch+120, original.Generate(epoch)))
+	}
+}
+
+func TestConfigBuildFailure(t *testing.T) {
+	for _, testCase := range []struct {
+		Config Config
+		Error  error
+	}{
+		{Config{Type: "totp", Algorithm: "MD5", Digits: 6, Period: 30, Secret: "GEZDGNBV"}, ErrUnknownAlgorithm},
+		{Config{Type: "totp", Algorithm: "SHA1", Digits: 11, Period: 30, Secret: "GEZDGNBV"}, ErrInvalidCodeDigit},
+		{Config{Type: "hotp", Algorithm: "SHA1", Digits: 0, Secret: "GEZDGNBV"}, ErrInvalidCodeDigit},
+		{Config{Type: "totp", Algorithm: "SHA1", Digits: 6, Secret: "GEZDGNBV"}, ErrInvalidTimeStep},
+		{Config{Type: "totp", Algorithm: "SHA1", Digits: 6, Period: 30, Skew: -1, Secret: "GEZDGNBV"},
+			ErrInvalidLookBackward},
+		{Config{Type: "totp", Algorithm: "SHA1", Digits: 6, Period: 30, LookForward: -1, Secret: "GEZDGNBV"},
+			ErrInvalidLookForward},
+	} {
+		_, err := testCase.Config.Build()
+		assert.True(t, errors.Is(err, testCase.Error), "%+v: %v", testCase.Config, err)
+	}
+
+	_, err := Config{Type: "totp", Algorithm: "SHA1", Digits: 6, Period: 30, Skew: 1, LookForward: 2,
+		Secret: "GEZDGNBV"}.Build()
+	assert.EqualError(t, err, "skew combined with look-backward or look-forward value")
+	_, err = Config{Type: "totp", Algorithm: "SHA1", Digits: 6, Period: 30}.Build()
+	assert.EqualError(t, err, "invalid secret")
+	_, err = Config{Type: "motp", Algorithm: "SHA1", Digits: 6, Secret: "GEZDGNBV"}.Build()
+	assert.EqualError(t, err, "invalid type")
+}
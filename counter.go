@@ -0,0 +1,57 @@
+package otp
+
+import (
+	"errors"
+	"math"
+	"sync"
+)
+
+// hotpCounter represents the counter held by an HOTP manager for Next. It is safe for concurrent use.
+type hotpCounter struct {
+	mutex sync.Mutex
+	value int64
+}
+
+// hotpOnlyOption represents an option only applicable to HOTP managers.
+type hotpOnlyOption func(*hotpManager) error
+
+func (option hotpOnlyOption) applyHOTP(generator *hotpManager) error {
+	return option(generator)
+}
+
+// WithInitialCounter sets the counter held by the manager, which Next generates the code of and then advances, for
+// using the manager as a stateful HOTP token. The counter starts at 0 by default, and cannot be negative. Methods
+// taking an explicit counter neither read nor advance it.
+func WithInitialCounter(counter int64) HOTPOption {
+	return hotpOnlyOption(func(generator *hotpManager) error {
+		if counter < 0 {
+			return errors.New("invalid counter")
+		}
+		generator.counter.value = counter
+		return nil
+	})
+}
+
+// Next generates the one-time password of the counter held by the manager, and advances the counter to the following
+// one, like a hardware HOTP token does when its button is pressed. Concurrent calls never get the same code. An empty
+// string is returned and the counter is not advanced if the code cannot be generated, such as after Close, or once the
+// counter has reached the largest one.
+func (generator *hotpManager) Next() string {
+	generator.counter.mutex.Lock()
+	defer generator.counter.mutex.Unlock()
+	if generator.counter.value == math.MaxInt64 {
+		return ""
+	}
+	code := generator.Generate(generator.counter.value)
+	if code != "" {
+		generator.counter.value += 1
+	}
+	return code
+}
+
+// Counter gets the counter held by the manager, which is the counter of the code the next call to Next generates.
+func (generator *hotpManager) Counter() int64 {
+	generator.counter.mutex.Lock()
+	defer generator.counter.mutex.Unlock()
+	return generator.counter.value
+}
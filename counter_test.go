@@ -0,0 +1,64 @@
+package otp
+
+import (
+	"encoding/hex"
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHOTPNext(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	hotp, err := NewHOTP(HashAlgorithmSHA1, secret, 6)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), hotp.Counter())
+	for counter, expected := range []string{"755224", "287082", "359152", "969429"} {
+		assert.Equal(t, expected, hotp.Next())
+		assert.Equal(t, int64(counter+1), hotp.Counter())
+	}
+	// Methods taking an explicit counter do not touch the counter held by the manager.
+	assert.Equal(t, "755224", hotp.Generate(0))
+	assert.True(t, hotp.Validate(9, "520489"))
+	assert.Equal(t, int64(4), hotp.Counter())
+
+	hotp, err = NewHOTP(HashAlgorithmSHA1, secret, 6, WithInitialCounter(8))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(8), hotp.Counter())
+	assert.Equal(t, "399871", hotp.Next())
+	assert.Equal(t, "520489", hotp.Next())
+	assert.Equal(t, int64(10), hotp.Counter())
+
+	hotp, err = NewHOTP(HashAlgorithmSHA1, secret, 6, WithInitialCounter(math.MaxInt64))
+	assert.NoError(t, err)
+	assert.Equal(t, "", hotp.Next())
+	assert.Equal(t, int64(math.MaxInt64), hotp.Counter())
+
+	hotp, err = NewHOTP(HashAlgorithmSHA1, secret, 6)
+	assert.NoError(t, err)
+	hotp.Close()
+	assert.Equal(t, "", hotp.Next())
+	assert.Equal(t, int64(0), hotp.Counter())
+
+	_, err = NewHOTP(HashAlgorithmSHA1, secret, 6, WithInitialCounter(-1))
+	assert.EqualError(t, err, "invalid counter")
+}
+
+func TestHOTPNextConcurrent(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	hotp, err := NewHOTP(HashAlgorithmSHA1, secret, 6)
+	assert.NoError(t, err)
+	codes := make([]string, 100)
+	var wg sync.WaitGroup
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codes[i] = hotp.Next()
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, int64(len(codes)), hotp.Counter())
+	assert.ElementsMatch(t, hotp.GenerateRange(0, int64(len(codes))), codes)
+}
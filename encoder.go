@@ -0,0 +1,94 @@
+package otp
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Encoder renders the value produced by dynamic truncation as a code of the specified length.
+//
+// Encoders must be deterministic: the same value and length must always give the same code, since validation renders
+// the expected code with the same encoder and compares the result. They must also return codes of exactly the
+// specified length in characters, and be safe for concurrent use.
+type Encoder interface {
+	// Encode renders the 31-bit truncated value as a code of the specified length.
+	Encode(value uint64, length int) string
+}
+
+// symbolEncoder is implemented by encoders whose codes only contain the symbols of a known alphabet, which allows
+// telling malformed codes apart. Codes of other encoders are only checked for their length.
+type symbolEncoder interface {
+	symbols() string
+}
+
+// DecimalEncoder renders codes as zero-padded decimal digits, as defined by RFC 4226. It is the default encoder.
+var DecimalEncoder Encoder = decimalEncoder{}
+
+// HexEncoder renders codes as zero-padded lowercase hexadecimal digits.
+var HexEncoder Encoder = alphabetEncoder("0123456789abcdef")
+
+// SteamEncoder renders codes with the alphabet of Steam Guard, whose codes are 5 characters long.
+var SteamEncoder Encoder = steamEncoder{}
+
+// decimalEncoder represents the encoder of decimal codes.
+type decimalEncoder struct{}
+
+func (decimalEncoder) Encode(value uint64, length int) string {
+	return fmt.Sprintf("%0*d", length, value%powersOfTen[length])
+}
+
+func (decimalEncoder) symbols() string {
+	return "0123456789"
+}
+
+// alphabetEncoder represents an encoder of codes in positional notation with the base and symbols of an alphabet.
+type alphabetEncoder string
+
+// NewAlphabetEncoder creates an encoder rendering codes in positional notation, with the base being the number of
+// symbols and the first symbol used for padding. For example, "0123456789" renders the same codes as DecimalEncoder.
+// Symbols must be distinct single-byte characters, and there must be at least two of them.
+func NewAlphabetEncoder(symbols string) (Encoder, error) {
+	if len(symbols) < 2 {
+		return nil, errors.New("invalid alphabet")
+	}
+	for i := 0; i < len(symbols); i += 1 {
+		if symbols[i] >= 0x80 || strings.IndexByte(symbols[:i], symbols[i]) >= 0 {
+			return nil, errors.New("invalid alphabet")
+		}
+	}
+	return alphabetEncoder(symbols), nil
+}
+
+func (symbols alphabetEncoder) Encode(value uint64, length int) string {
+	base := uint64(len(symbols))
+	code := make([]byte, length)
+	for i := length - 1; i >= 0; i -= 1 {
+		code[i] = symbols[value%base]
+		value /= base
+	}
+	return string(code)
+}
+
+func (symbols alphabetEncoder) symbols() string {
+	return string(symbols)
+}
+
+// steamEncoder represents the encoder of Steam Guard codes, which emits the least significant symbol first.
+type steamEncoder struct{}
+
+// steamAlphabet contains the symbols of Steam Guard codes.
+const steamAlphabet = "23456789BCDFGHJKMNPQRTVWXY"
+
+func (steamEncoder) Encode(value uint64, length int) string {
+	code := make([]byte, length)
+	for i := range code {
+		code[i] = steamAlphabet[value%uint64(len(steamAlphabet))]
+		value /= uint64(len(steamAlphabet))
+	}
+	return string(code)
+}
+
+func (steamEncoder) symbols() string {
+	return steamAlphabet
+}
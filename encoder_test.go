@@ -0,0 +1,102 @@
+package otp
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecimalEncoder(t *testing.T) {
+	assert.Equal(t, "755224", DecimalEncoder.Encode(1284755224, 6))
+	assert.Equal(t, "84755224", DecimalEncoder.Encode(1284755224, 8))
+	assert.Equal(t, "000042", DecimalEncoder.Encode(42, 6))
+	assert.Equal(t, "4", DecimalEncoder.Encode(1284755224, 1))
+}
+
+func TestHexEncoder(t *testing.T) {
+	assert.Equal(t, "93cf18", HexEncoder.Encode(1284755224, 6))
+	assert.Equal(t, "4c93cf18", HexEncoder.Encode(1284755224, 8))
+	assert.Equal(t, "00002a", HexEncoder.Encode(42, 6))
+}
+
+func TestSteamEncoder(t *testing.T) {
+	assert.Equal(t, "GG5F5", SteamEncoder.Encode(1284755224, 5))
+	assert.Equal(t, "PV9M4", SteamEncoder.Encode(1094287082, 5))
+	assert.Equal(t, "22222", SteamEncoder.Encode(0, 5))
+}
+
+func TestNewAlphabetEncoder(t *testing.T) {
+	encoder, err := NewAlphabetEncoder("0123456789")
+	assert.NoError(t, err)
+	for _, value := range []uint64{0, 7, 1284755224, 0x7fffffff} {
+		assert.Equal(t, DecimalEncoder.Encode(value, 6), encoder.Encode(value, 6))
+	}
+	encoder, err = NewAlphabetEncoder("01")
+	assert.NoError(t, err)
+	assert.Equal(t, "00101", encoder.Encode(5, 5))
+	assert.Equal(t, "01", encoder.Encode(5, 2))
+
+	for _, symbols := range []string{"", "0", "0120", "01é"} {
+		if _, err := NewAlphabetEncoder(symbols); assert.Error(t, err) {
+			assert.Equal(t, "invalid alphabet", err.Error())
+		}
+	}
+}
+
+func TestWithEncoder(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	hotp, err := NewHOTP(HashAlgorithmSHA1, secret, 6, WithEncoder(HexEncoder))
+	assert.NoError(t, err)
+	assert.Equal(t, "93cf18", hotp.Generate(0))
+	assert.True(t, hotp.Validate(0, "93cf18"))
+	assert.False(t, hotp.Validate(0, "755224"))
+	match, canonical := hotp.ValidateCanonical(0, "93c-f18")
+	assert.True(t, match)
+	assert.Equal(t, "93cf18", canonical)
+
+	totp, err := NewTOTP(HashAlgorithmSHA1, secret, 5, 30, 1, 0, WithEncoder(SteamEncoder))
+	assert.NoError(t, err)
+	assert.Equal(t, "PV9M4", totp.Generate(30))
+	assert.True(t, totp.Validate(59, "PV9M4"))
+	assert.True(t, totp.Validate(60, "PV9M4"))
+	assert.False(t, totp.Validate(90, "PV9M4"))
+
+	if _, err := NewHOTP(HashAlgorithmSHA1, secret, 6, WithEncoder(nil)); assert.Error(t, err) {
+		assert.Equal(t, "invalid encoder", err.Error())
+	}
+	if _, err := NewTOTP(HashAlgorithmSHA1, secret, 6, 30, 0, 0, WithEncoder(nil)); assert.Error(t, err) {
+		assert.Equal(t, "invalid encoder", err.Error())
+	}
+}
+
+func TestWithAlphabet(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	hotp, err := NewHOTP(HashAlgorithmSHA1, secret, 6, WithAlphabet("0123456789abcdef", 8))
+	assert.NoError(t, err)
+	assert.Equal(t, "4c93cf18", hotp.Generate(0))
+	assert.True(t, hotp.Validate(0, "4c93cf18"))
+	assert.False(t, hotp.Validate(0, "93cf18"))
+
+	totp, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 0, 0, WithAlphabet("ABCDEFGHIJKLMNOPQRSTUVWXYZ234567", 6))
+	assert.NoError(t, err)
+	assert.Equal(t, "GJHTYY", totp.Generate(0))
+	assert.True(t, totp.Validate(29, "GJHTYY"))
+
+	// The decimal alphabet renders exactly the codes of the default encoder.
+	hotp, err = NewHOTP(HashAlgorithmSHA1, secret, 8, WithAlphabet("0123456789", 6))
+	assert.NoError(t, err)
+	assert.Equal(t, DecimalEncoder, hotp.(*hotpManager).encoder)
+	for counter, expected := range []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922",
+		"162583", "399871", "520489"} {
+		assert.Equal(t, expected, hotp.Generate(int64(counter)))
+	}
+
+	if _, err := NewHOTP(HashAlgorithmSHA1, secret, 6, WithAlphabet("0", 6)); assert.Error(t, err) {
+		assert.Equal(t, "invalid alphabet", err.Error())
+	}
+	for _, length := range []int{0, -1, 11} {
+		_, err := NewTOTP(HashAlgorithmSHA1, secret, 6, 30, 0, 0, WithAlphabet("0123456789abcdef", length))
+		assert.Equal(t, &ParamError{Param: "codeDigit", Value: length, Min: 1, Max: MaxCodeDigits}, err)
+	}
+}
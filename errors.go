@@ -0,0 +1,83 @@
+package otp
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors reported by constructors and parsers, which can be checked with errors.Is. Errors about parameters
+// out of their valid range are reported with a *ParamError wrapping the sentinel error of the parameter.
+var (
+	// ErrUnknownAlgorithm is returned for hash algorithms that are not defined, or whose names are not recognized.
+	ErrUnknownAlgorithm = errors.New("unknown hash algorithm")
+
+	// ErrInvalidCodeDigit is wrapped by errors reporting an invalid digit count of password codes.
+	ErrInvalidCodeDigit = errors.New("invalid code digit")
+
+	// ErrInvalidTimeStep is wrapped by errors reporting an invalid time step.
+	ErrInvalidTimeStep = errors.New("invalid time step")
+
+	// ErrInvalidLookBackward is wrapped by errors reporting invalid tolerant time steps backward.
+	ErrInvalidLookBackward = errors.New("invalid look-backward value")
+
+	// ErrInvalidLookForward is wrapped by errors reporting invalid tolerant time steps forward.
+	ErrInvalidLookForward = errors.New("invalid look-forward value")
+
+	// ErrMalformedCode is returned by ValidateStrict for codes that could never match, such as codes of the wrong length
+	// or with characters other than digits, as opposed to well-formed codes that do not match.
+	ErrMalformedCode = errors.New("malformed code")
+)
+
+// ParamError represents an error caused by a parameter out of its valid range. Errors about the digit count of password
+// codes include the value and the valid range, such as "invalid code digit 11: must be between 1 and 10". Errors about
+// the code digits, the time step and the tolerant time steps wrap the sentinel error of the parameter, such as
+// ErrInvalidCodeDigit.
+type ParamError struct {
+	// Param is the name of the invalid parameter.
+	Param string
+
+	// Value is the invalid value.
+	Value int
+
+	// Min is the minimum valid value.
+	Min int
+
+	// Max is the maximum valid value, which is math.MaxInt when there is no upper bound.
+	Max int
+}
+
+func (err *ParamError) Error() string {
+	switch err.Param {
+	case "codeDigit":
+		return fmt.Sprintf("%v %d: must be between %d and %d", ErrInvalidCodeDigit, err.Value, err.Min, err.Max)
+	case "timeStep", "lookBackward", "lookForward":
+		return err.Unwrap().Error()
+	case "truncationOffset":
+		return "invalid truncation offset"
+	case "minSecretLength":
+		return "invalid minimum secret length"
+	case "secretSize":
+		return "invalid secret size"
+	case "maxFailures":
+		return "invalid maximum failures"
+	default:
+		return "invalid " + err.Param
+	}
+}
+
+// Unwrap gets the sentinel error of the parameter, such as ErrInvalidCodeDigit for the digit count of password codes,
+// or nil for parameters without one.
+func (err *ParamError) Unwrap() error {
+	switch err.Param {
+	case "codeDigit":
+		return ErrInvalidCodeDigit
+	case "timeStep":
+		return ErrInvalidTimeStep
+	case "lookBackward":
+		return ErrInvalidLookBackward
+	case "lookForward":
+		return ErrInvalidLookForward
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,82 @@
+package otp
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParamError(t *testing.T) {
+	assert.Equal(t, "invalid code digit 11: must be between 1 and 10",
+		(&ParamError{Param: "codeDigit", Value: 11, Min: 1, Max: 10}).Error())
+	assert.Equal(t, "invalid time step", (&ParamError{Param: "timeStep"}).Error())
+	assert.Equal(t, "invalid look-backward value", (&ParamError{Param: "lookBackward"}).Error())
+	assert.Equal(t, "invalid look-forward value", (&ParamError{Param: "lookForward"}).Error())
+	assert.Equal(t, "invalid truncation offset", (&ParamError{Param: "truncationOffset"}).Error())
+	assert.Equal(t, "invalid minimum secret length", (&ParamError{Param: "minSecretLength"}).Error())
+	assert.Equal(t, "invalid secret size", (&ParamError{Param: "secretSize"}).Error())
+	assert.Equal(t, "invalid maximum failures", (&ParamError{Param: "maxFailures"}).Error())
+	assert.Equal(t, "invalid window", (&ParamError{Param: "window"}).Error())
+}
+
+func TestParamErrorUnwrap(t *testing.T) {
+	err := error(&ParamError{Param: "codeDigit", Value: 0, Min: 1, Max: MaxCodeDigits})
+	assert.True(t, errors.Is(err, ErrInvalidCodeDigit))
+	assert.Equal(t, ErrInvalidCodeDigit, errors.Unwrap(err))
+	assert.False(t, errors.Is(&ParamError{Param: "timeStep"}, ErrInvalidCodeDigit))
+	assert.Nil(t, errors.Unwrap(&ParamError{Param: "truncationOffset"}))
+
+	_, err = ParseURI("otpauth://totp/alice?secret=GEZDGNBVGY3TQOJQ&digits=six")
+	assert.True(t, errors.Is(err, ErrInvalidCodeDigit))
+	_, err = ParseURI("otpauth://totp/alice?secret=GEZDGNBVGY3TQOJQ&digits=11")
+	assert.True(t, errors.Is(err, ErrInvalidCodeDigit))
+}
+
+func TestConstructorParamError(t *testing.T) {
+	for _, testCase := range []struct {
+		Err      error
+		Expected ParamError
+	}{
+		{second(NewHOTP(HashAlgorithmSHA1, nil, 0)), ParamError{"codeDigit", 0, 1, MaxCodeDigits}},
+		{second(NewHOTP(HashAlgorithmSHA1, nil, 11)), ParamError{"codeDigit", 11, 1, MaxCodeDigits}},
+		{second(NewTOTP(HashAlgorithmSHA1, nil, 11, 30, 0, 0)), ParamError{"codeDigit", 11, 1, MaxCodeDigits}},
+		{second(NewTOTP(HashAlgorithmSHA1, nil, 6, -30, 0, 0)), ParamError{"timeStep", -30, 1, math.MaxInt}},
+		{second(NewTOTP(HashAlgorithmSHA1, nil, 6, 30, -1, 0)), ParamError{"lookBackward", -1, 0, math.MaxInt}},
+		{second(NewTOTP(HashAlgorithmSHA1, nil, 6, 30, 0, -2)), ParamError{"lookForward", -2, 0, math.MaxInt}},
+	} {
+		if paramError, ok := testCase.Err.(*ParamError); assert.True(t, ok) {
+			assert.Equal(t, testCase.Expected, *paramError)
+		}
+	}
+}
+
+func TestSentinelErrors(t *testing.T) {
+	for _, testCase := range []struct {
+		Err      error
+		Sentinel error
+		Message  string
+	}{
+		{second(NewHOTP(HashAlgorithm(-1), nil, 6)), ErrUnknownAlgorithm, "unknown hash algorithm"},
+		{second(NewTOTP(HashAlgorithmSHA1, nil, 0, 30, 0, 0)), ErrInvalidCodeDigit,
+			"invalid code digit 0: must be between 1 and 10"},
+		{second(NewTOTP(HashAlgorithmSHA1, nil, 6, 0, 0, 0)), ErrInvalidTimeStep, "invalid time step"},
+		{second(NewTOTPDuration(HashAlgorithmSHA1, nil, 6, 0, 0, 0)), ErrInvalidTimeStep, "invalid time step"},
+		{second(NewTOTP(HashAlgorithmSHA1, nil, 6, 30, -1, 0)), ErrInvalidLookBackward, "invalid look-backward value"},
+		{second(NewTOTP(HashAlgorithmSHA1, nil, 6, 30, 0, -1)), ErrInvalidLookForward, "invalid look-forward value"},
+		{second(ParseURI("otpauth://totp/alice?secret=GEZDGNBVGY3TQOJQ&algorithm=MD5")), ErrUnknownAlgorithm,
+			"unknown hash algorithm"},
+		{second(ParseURI("otpauth://totp/alice?secret=GEZDGNBVGY3TQOJQ&period=0")), ErrInvalidTimeStep,
+			"invalid time step"},
+	} {
+		assert.True(t, errors.Is(testCase.Err, testCase.Sentinel), testCase.Message)
+		assert.EqualError(t, testCase.Err, testCase.Message)
+	}
+	assert.False(t, errors.Is(&ParamError{Param: "lookBackward"}, ErrInvalidLookForward))
+}
+
+// second gets the second of two values, to make results of constructors usable as expressions.
+func second[T any](_ T, err error) error {
+	return err
+}
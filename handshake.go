@@ -0,0 +1,38 @@
+package otp
+
+import "errors"
+
+// Challenge gets the current time step read from the clock of the manager, for the server to send to the client as
+// the first message of a challenge-response handshake. The client computes the response with Respond, and the server
+// checks it with Verify.
+//
+// The handshake is transport-agnostic: the time step is a plain integer and the response a plain code, which may be
+// carried over any protocol. Both sides must share the secret key and the settings of the manager, but the client does
+// not need a clock, so codes stay valid even if its clock is wrong.
+func (generator *totpManager) Challenge() (int64, error) {
+	if generator.hotp.closed() {
+		return 0, errors.New("manager closed")
+	}
+	return generator.movingFactorTime(generator.clock()), nil
+}
+
+// Respond generates the one-time password of the time step sent by Challenge, for the client to send back to the
+// server. An empty string is returned if the code cannot be generated.
+func (generator *totpManager) Respond(movingFactor int64) string {
+	return generator.hotp.Generate(movingFactor)
+}
+
+// Verify validates whether the one-time password sent back by the client matches the time step sent by Challenge.
+// Only the exact time step is compared, never its neighbours, and time steps outside the tolerant time steps of the
+// current time are rejected, so neither an old challenge nor one made up by the client is accepted. The tolerant time
+// steps should cover the time the handshake may take.
+//
+// Like other validation methods, a response may be accepted more than once while its time step stays within the
+// tolerant time steps. Protocols that must reject replays should also record accepted time steps.
+func (generator *totpManager) Verify(movingFactor int64, code string) bool {
+	current := generator.movingFactorTime(generator.clock())
+	if movingFactor < current-int64(generator.lookBackward) || movingFactor > current+int64(generator.lookForward) {
+		return false
+	}
+	return generator.hotp.Validate(movingFactor, code)
+}
@@ -0,0 +1,48 @@
+package otp
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandshake(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	now := time.Unix(1234567890, 0)
+	server, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 1, 0, WithClock(func() time.Time { return now }))
+	assert.NoError(t, err)
+	// The client clock is far off, which does not matter since the time step comes from the server.
+	client, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 1, 0,
+		WithClock(func() time.Time { return time.Unix(0, 0) }))
+	assert.NoError(t, err)
+
+	factor, err := server.Challenge()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(41152263), factor)
+	response := client.Respond(factor)
+	assert.Equal(t, "89005924", response)
+	assert.True(t, server.Verify(factor, response))
+
+	// Only the time step of the challenge matches.
+	assert.False(t, server.Verify(factor, client.Respond(factor-1)))
+	assert.False(t, server.Verify(factor-1, response))
+	assert.False(t, server.Verify(factor, "00000000"))
+	assert.False(t, server.Verify(factor, ""))
+
+	// The response is accepted while the challenge is within the tolerant time steps.
+	now = now.Add(30 * time.Second)
+	assert.True(t, server.Verify(factor, response))
+	now = now.Add(30 * time.Second)
+	assert.False(t, server.Verify(factor, response))
+
+	// Challenges from the future are not accepted, so a client cannot make one up.
+	future := factor + 10
+	assert.False(t, server.Verify(future, client.Respond(future)))
+
+	server.Close()
+	_, err = server.Challenge()
+	assert.EqualError(t, err, "manager closed")
+	assert.False(t, server.Verify(factor, response))
+}
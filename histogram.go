@@ -0,0 +1,51 @@
+package otp
+
+import "sync"
+
+// skewHistogram represents the number of successful validations at each time step offset. It is safe for concurrent
+// use.
+type skewHistogram struct {
+	mutex  sync.Mutex
+	counts map[int]int64
+}
+
+// observe records a successful validation at the specified offset.
+func (histogram *skewHistogram) observe(offset int) {
+	histogram.mutex.Lock()
+	defer histogram.mutex.Unlock()
+	if histogram.counts == nil {
+		histogram.counts = make(map[int]int64)
+	}
+	histogram.counts[offset] += 1
+}
+
+// snapshot gets a copy of the counts.
+func (histogram *skewHistogram) snapshot() map[int]int64 {
+	histogram.mutex.Lock()
+	defer histogram.mutex.Unlock()
+	counts := make(map[int]int64, len(histogram.counts))
+	for offset, count := range histogram.counts {
+		counts[offset] = count
+	}
+	return counts
+}
+
+// WithSkewHistogram makes the manager count successful validations by Validate and ValidateTime at each time step
+// offset, which can be read with SkewHistogram. Operators can use the distribution to find out whether clocks of users
+// tend to run ahead or behind, and tune the tolerant time steps accordingly. Only offsets and counts are recorded,
+// never secrets or codes.
+func WithSkewHistogram() TOTPOption {
+	return totpOption(func(generator *totpManager) error {
+		generator.skew = &skewHistogram{}
+		return nil
+	})
+}
+
+// SkewHistogram gets the number of successful validations at each time step offset, where negative offsets are time
+// steps in the past. The result is a copy, and is empty unless the manager was created with WithSkewHistogram.
+func (generator *totpManager) SkewHistogram() map[int]int64 {
+	if generator.skew == nil {
+		return map[int]int64{}
+	}
+	return generator.skew.snapshot()
+}
@@ -0,0 +1,55 @@
+package otp
+
+import (
+	"encoding/hex"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSkewHistogram(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 1, 2, WithSkewHistogram())
+	assert.NoError(t, err)
+	assert.Empty(t, generator.SkewHistogram())
+
+	assert.True(t, generator.Validate(1234567890, "89005924"))
+	assert.True(t, generator.Validate(1234567890, "89005924"))
+	assert.True(t, generator.Validate(1234567920, "89005924"))
+	assert.True(t, generator.Validate(1234567830, "89005924"))
+	assert.False(t, generator.Validate(1234567800, "89005924"))
+	assert.False(t, generator.Validate(1234567890, "00000000"))
+	histogram := generator.SkewHistogram()
+	assert.Equal(t, map[int]int64{0: 2, -1: 1, 2: 1}, histogram)
+
+	histogram[0] = 100
+	assert.Equal(t, int64(2), generator.SkewHistogram()[0])
+}
+
+func TestSkewHistogramDisabled(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 1, 2)
+	assert.NoError(t, err)
+	assert.True(t, generator.Validate(1234567890, "89005924"))
+	assert.Empty(t, generator.SkewHistogram())
+}
+
+func TestSkewHistogramConcurrent(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 1, 1, WithSkewHistogram())
+	assert.NoError(t, err)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i += 1 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j += 1 {
+				generator.Validate(1234567890, "89005924")
+				generator.Validate(1234567920, "89005924")
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, map[int]int64{0: 400, -1: 400}, generator.SkewHistogram())
+}
@@ -0,0 +1,26 @@
+package otp
+
+// SecretEra represents a secret key that was in use during a range of epochs, from ValidFrom inclusive to ValidUntil
+// exclusive. Manager validates codes of the era, and is usually created with the same settings as the live manager but
+// the secret key of the era.
+type SecretEra struct {
+	Manager    TOTPManager
+	ValidFrom  int64
+	ValidUntil int64
+}
+
+// MatchSecretEra gets the index of the first era that was in use at the specified epoch and whose manager validates the
+// one-time password, which tells which secret key a logged code was generated with. -1 and false are returned when no
+// era matches. Eras are checked in order, and may overlap such as during a rotation.
+//
+// This is intended for offline analysis of past authentications, such as after an incident spanning secret rotations,
+// and not for live authentication: it exits early and is therefore not constant time. Use ValidateAnyConstantTime to
+// accept several secret keys during a live rotation instead.
+func MatchSecretEra(eras []SecretEra, epoch int64, code string) (int, bool) {
+	for i, era := range eras {
+		if epoch >= era.ValidFrom && epoch < era.ValidUntil && era.Manager.Validate(epoch, code) {
+			return i, true
+		}
+	}
+	return -1, false
+}
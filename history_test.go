@@ -0,0 +1,44 @@
+package otp
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchSecretEra(t *testing.T) {
+	oldSecret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	newSecret, _ := hex.DecodeString("3031323334353637383930313233343536373839")
+	oldManager, err := NewTOTP(HashAlgorithmSHA1, oldSecret, 8, 30, 1, 0)
+	assert.NoError(t, err)
+	newManager, err := NewTOTP(HashAlgorithmSHA1, newSecret, 8, 30, 1, 0)
+	assert.NoError(t, err)
+	eras := []SecretEra{
+		{Manager: oldManager, ValidFrom: 0, ValidUntil: 1234567900},
+		{Manager: newManager, ValidFrom: 1234567890, ValidUntil: 2000000000},
+	}
+
+	index, match := MatchSecretEra(eras, 1234567890, "89005924")
+	assert.True(t, match)
+	assert.Equal(t, 0, index)
+
+	index, match = MatchSecretEra(eras, 1234567895, newManager.Generate(1234567895))
+	assert.True(t, match)
+	assert.Equal(t, 1, index)
+
+	// The old secret key was no longer in use, even though its manager accepts the code.
+	assert.True(t, oldManager.Validate(1234567920, "89005924"))
+	index, match = MatchSecretEra(eras, 1234567920, "89005924")
+	assert.False(t, match)
+	assert.Equal(t, -1, index)
+
+	// The new secret key was not in use yet.
+	index, match = MatchSecretEra(eras, 1234567880, newManager.Generate(1234567880))
+	assert.False(t, match)
+	assert.Equal(t, -1, index)
+
+	index, match = MatchSecretEra(nil, 1234567890, "89005924")
+	assert.False(t, match)
+	assert.Equal(t, -1, index)
+}
@@ -0,0 +1,74 @@
+package otp
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// MarshalJSON encodes the settings of the manager as JSON, such as {"type":"hotp","algorithm":"SHA1","digits":6,
+// "secret":"GEZDGNBV..."}, for persisting them. Refers to the TOTP counterpart for details.
+func (generator *hotpManager) MarshalJSON() ([]byte, error) {
+	if generator.closed() {
+		return nil, errors.New("manager closed")
+	}
+	if !generator.encodable() {
+		return nil, errors.New("settings cannot be encoded")
+	}
+	return json.Marshal(generator.config(OTPTypeHOTP))
+}
+
+// MarshalJSON encodes the settings of the manager as JSON, such as {"type":"totp","algorithm":"SHA1","digits":6,
+// "period":30,"lookBackward":1,"secret":"GEZDGNBV..."}, for persisting them. ParseJSON creates a manager with the same
+// settings from the result.
+//
+// The secret key is included in base32, so the result is as sensitive as the secret key itself: it should be stored
+// encrypted and must never be logged. Only the settings passed to the constructors are included, so an error is
+// returned for managers with options that change the generated codes, such as device binding, expected prefixes,
+// checksum digits, encoders, truncation offsets, padding, counter sizes and T0, rather than encoding a manager that
+// generates different codes after a round trip. Options that do not change the codes, such as clocks, lenient input
+// and skew histograms, are left out.
+func (generator *totpManager) MarshalJSON() ([]byte, error) {
+	if generator.hotp.closed() {
+		return nil, errors.New("manager closed")
+	}
+	if !generator.hotp.encodable() || generator.t0 != 0 || generator.epochOffset != 0 {
+		return nil, errors.New("settings cannot be encoded")
+	}
+	config := generator.hotp.config(OTPTypeTOTP)
+	if generator.stepDuration != 0 {
+		config.Period = generator.stepDuration.Seconds()
+	} else {
+		config.Period = float64(generator.timeStep)
+	}
+	config.LookBackward = generator.lookBackward
+	config.LookForward = generator.lookForward
+	return json.Marshal(config)
+}
+
+// encodable reports whether the codes of the manager only depend on the settings included in a Config.
+func (generator *hotpManager) encodable() bool {
+	return len(generator.binding) == 0 && generator.encoder == DecimalEncoder && generator.prefix == "" &&
+		!generator.checksum && generator.offset == dynamicTruncation && generator.padding == PaddingZero &&
+		generator.counterBytes == 8
+}
+
+// config gets the settings shared by HOTP and TOTP managers.
+func (generator *hotpManager) config(kind OTPType) Config {
+	algorithm, _ := generator.algorithm.name()
+	return Config{
+		Type:      kind.String(),
+		Algorithm: algorithm,
+		Digits:    generator.codeDigits,
+		Secret:    generator.SecretBase32(),
+	}
+}
+
+// ParseJSON creates a manager from settings encoded by MarshalJSON, which is an HOTPManager or a TOTPManager. The
+// settings are decoded as a Config, and refers to its Build method for how they are validated.
+func ParseJSON(data []byte) (Manager, error) {
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	return config.Build()
+}
@@ -0,0 +1,146 @@
+package otp
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	hotp, err := NewHOTP(HashAlgorithmSHA256, secret, 8)
+	assert.NoError(t, err)
+	data, err := json.Marshal(hotp)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"type":"hotp","algorithm":"SHA256","digits":8,"secret":"GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"}`,
+		string(data))
+
+	totp, err := NewTOTP(HashAlgorithmSHA1, secret, 6, 30, 1, 2)
+	assert.NoError(t, err)
+	data, err = json.Marshal(totp)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"type":"totp","algorithm":"SHA1","digits":6,"period":30,"lookBackward":1,"lookForward":2,`+
+		`"secret":"GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"}`, string(data))
+
+	totp, err = NewTOTPDuration(HashAlgorithmSHA1, secret, 6, 1500*time.Millisecond, 0, 0)
+	assert.NoError(t, err)
+	data, err = json.Marshal(totp)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"type":"totp","algorithm":"SHA1","digits":6,"period":1.5,`+
+		`"secret":"GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"}`, string(data))
+}
+
+func TestParseJSONRoundTrip(t *testing.T) {
+	for _, algorithm := range []HashAlgorithm{HashAlgorithmSHA1, HashAlgorithmSHA256, HashAlgorithmSHA512} {
+		hotp, err := NewHOTP(algorithm, nil, 7)
+		assert.NoError(t, err)
+		data, err := json.Marshal(hotp)
+		assert.NoError(t, err)
+		parsed, err := ParseJSON(data)
+		assert.NoError(t, err)
+		assert.Equal(t, hotp.Secret(), parsed.Secret())
+		assert.Equal(t, 7, parsed.(*hotpManager).codeDigits)
+		for counter := int64(0); counter < 10; counter += 1 {
+			assert.Equal(t, hotp.Generate(counter), parsed.Generate(counter))
+		}
+
+		totp, err := NewTOTP(algorithm, nil, 8, 60, 2, 1)
+		assert.NoError(t, err)
+		data, err = json.Marshal(totp)
+		assert.NoError(t, err)
+		parsed, err = ParseJSON(data)
+		assert.NoError(t, err)
+		assert.IsType(t, &totpManager{}, parsed)
+		assert.Equal(t, totp.Secret(), parsed.Secret())
+		assert.Equal(t, algorithm, parsed.(*totpManager).hotp.algorithm)
+		assert.Equal(t, 60, parsed.(*totpManager).timeStep)
+		assert.Equal(t, 2, parsed.(*totpManager).lookBackward)
+		assert.Equal(t, 1, parsed.(*totpManager).lookForward)
+		for epoch := int64(1234567890); epoch < 1234568890; epoch += 45 {
+			assert.Equal(t, totp.Generate(epoch), parsed.Generate(epoch))
+		}
+	}
+
+	totp, err := NewTOTPDuration(HashAlgorithmSHA1, nil, 6, 500*time.Millisecond, 0, 0)
+	assert.NoError(t, err)
+	data, err := json.Marshal(totp)
+	assert.NoError(t, err)
+	parsed, err := ParseJSON(data)
+	assert.NoError(t, err)
+	assert.Equal(t, 500*time.Millisecond, parsed.(*totpManager).stepDuration)
+	now := time.Unix(1234567890, 250000000)
+	assert.Equal(t, totp.(TOTPManager).GenerateTime(now), parsed.(TOTPManager).GenerateTime(now))
+}
+
+func TestMarshalJSONUnencodable(t *testing.T) {
+	// A bound manager would parse back into an unbound one generating different codes, so it is not encoded.
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	bound, err := NewTOTPBound(HashAlgorithmSHA1, secret, 8, 30, 1, 0, []byte("device-1"))
+	assert.NoError(t, err)
+	_, err = bound.(*totpManager).MarshalJSON()
+	assert.EqualError(t, err, "settings cannot be encoded")
+	_, err = json.Marshal(bound)
+	assert.Error(t, err)
+
+	for _, opt := range []Option{WithChecksum(true), WithExpectedPrefix("TK"), WithAlphabet("ABCDEFGH", 8),
+		WithTruncationOffset(0), WithCounterBytes(4), WithPadding(PaddingNone)} {
+		hotp, err := NewHOTP(HashAlgorithmSHA1, secret, 6, opt)
+		assert.NoError(t, err)
+		_, err = json.Marshal(hotp)
+		assert.Error(t, err)
+		totp, err := NewTOTP(HashAlgorithmSHA1, secret, 6, 30, 1, 0, opt)
+		assert.NoError(t, err)
+		_, err = json.Marshal(totp)
+		assert.Error(t, err)
+	}
+	for _, opt := range []TOTPOption{WithT0(60), WithEpochOffset(60)} {
+		totp, err := NewTOTP(HashAlgorithmSHA1, secret, 6, 30, 1, 0, opt)
+		assert.NoError(t, err)
+		_, err = json.Marshal(totp)
+		assert.Error(t, err)
+	}
+
+	// Options that do not change the codes are left out, and the codes survive a round trip.
+	totp, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 1, 0, WithLenientInput(), WithSkewHistogram())
+	assert.NoError(t, err)
+	data, err := json.Marshal(totp)
+	assert.NoError(t, err)
+	parsed, err := ParseJSON(data)
+	assert.NoError(t, err)
+	assert.Equal(t, totp.Generate(1234567890), parsed.Generate(1234567890))
+}
+
+func TestParseJSONFailure(t *testing.T) {
+	for _, testCase := range []struct {
+		Data  string
+		Error string
+	}{
+		{`{"type":"totp","algorithm":"MD5","digits":6,"period":30,"secret":"GEZDGNBV"}`, "unknown hash algorithm"},
+		{`{"type":"totp","digits":6,"period":30,"secret":"GEZDGNBV"}`, "unknown hash algorithm"},
+		{`{"type":"totp","algorithm":"SHA1","digits":6,"period":30}`, "invalid secret"},
+		{`{"type":"totp","algorithm":"SHA1","digits":6,"period":30,"secret":"GEZDGNB1"}`, "invalid secret"},
+		{`{"type":"totp","algorithm":"SHA1","digits":0,"period":30,"secret":"GEZDGNBV"}`,
+			"invalid code digit 0: must be between 1 and 10"},
+		{`{"type":"hotp","algorithm":"SHA1","digits":11,"secret":"GEZDGNBV"}`,
+			"invalid code digit 11: must be between 1 and 10"},
+		{`{"type":"totp","algorithm":"SHA1","digits":6,"secret":"GEZDGNBV"}`, "invalid time step"},
+		{`{"type":"totp","algorithm":"SHA1","digits":6,"period":-0.5,"secret":"GEZDGNBV"}`, "invalid time step"},
+		{`{"type":"totp","algorithm":"SHA1","digits":6,"period":1e20,"secret":"GEZDGNBV"}`, "invalid time step"},
+		{`{"type":"totp","algorithm":"SHA1","digits":6,"period":30,"lookBackward":-1,"secret":"GEZDGNBV"}`,
+			"invalid look-backward value"},
+		{`{"type":"totp","algorithm":"SHA1","digits":6,"period":30,"lookForward":-1,"secret":"GEZDGNBV"}`,
+			"invalid look-forward value"},
+		{`{"type":"motp","algorithm":"SHA1","digits":6,"secret":"GEZDGNBV"}`, "invalid type"},
+	} {
+		_, err := ParseJSON([]byte(testCase.Data))
+		assert.EqualError(t, err, testCase.Error, testCase.Data)
+	}
+
+	_, err := ParseJSON([]byte(`{"type":`))
+	assert.Error(t, err)
+	_, err = ParseJSON([]byte(`{"type":"totp","digits":"six"}`))
+	assert.Error(t, err)
+}
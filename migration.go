@@ -0,0 +1,200 @@
+package otp
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// MigrationEntry represents an account in a migration payload exported by Google Authenticator.
+type MigrationEntry struct {
+	// Issuer is the provider of the account, taken from the issuer field or else from the name. It may be empty.
+	Issuer string
+
+	// AccountName is the name of the account, taken from the name field.
+	AccountName string
+
+	// Counter is the counter of HOTP accounts. It is always 0 for TOTP accounts.
+	Counter int64
+
+	// Manager is the manager configured from the account, which is an HOTPManager or a TOTPManager.
+	Manager Manager
+}
+
+// Field numbers and enumerations of the MigrationPayload protocol buffer message of Google Authenticator.
+const (
+	migrationPayloadOTPParameters = 1
+
+	migrationSecret    = 1
+	migrationName      = 2
+	migrationIssuer    = 3
+	migrationAlgorithm = 4
+	migrationDigits    = 5
+	migrationType      = 6
+	migrationCounter   = 7
+
+	migrationTypeHOTP = 1
+	migrationTypeTOTP = 2
+)
+
+// ParseMigration parses a migration URI exported by Google Authenticator, such as
+// "otpauth-migration://offline?data=CjEKCkhlbGxvId6t...", and gets the accounts it contains in order.
+//
+// The data parameter is a base64-encoded MigrationPayload protocol buffer message. Secret key, name, issuer, algorithm,
+// digits, type and counter of every account are read, and unknown fields are ignored. Google Authenticator only
+// exports 6-digit or 8-digit codes with a period of 30 seconds. TOTP managers allow one time step backward for network
+// delay, as recommended by RFC 6238, and none forward. Secret keys shorter than 16 bytes are accepted, since they have
+// already been issued. Accounts exported with large batches are split into several URIs, each of which must be parsed.
+func ParseMigration(uri string) ([]MigrationEntry, error) {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	if parsed.Scheme != "otpauth-migration" {
+		return nil, errors.New("invalid scheme")
+	}
+	// Plus signs that were not escaped have been decoded as spaces.
+	data := strings.ReplaceAll(parsed.Query().Get("data"), " ", "+")
+	payload, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(data, "="))
+	if err != nil {
+		return nil, errors.New("invalid payload")
+	}
+
+	var entries []MigrationEntry
+	err = readProtobuf(payload, func(field int, value uint64, raw []byte) error {
+		if field != migrationPayloadOTPParameters {
+			return nil
+		}
+		if raw == nil {
+			return errors.New("invalid payload")
+		}
+		entry, err := parseMigrationEntry(raw)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// parseMigrationEntry parses an OtpParameters protocol buffer message.
+func parseMigrationEntry(message []byte) (MigrationEntry, error) {
+	var entry MigrationEntry
+	var secret []byte
+	var name string
+	algorithm, codeDigit, kind := HashAlgorithmSHA1, 6, uint64(0)
+	err := readProtobuf(message, func(field int, value uint64, raw []byte) error {
+		switch field {
+		case migrationSecret:
+			secret = raw
+		case migrationName:
+			name = string(raw)
+		case migrationIssuer:
+			entry.Issuer = string(raw)
+		case migrationAlgorithm:
+			switch value {
+			case 0, 1:
+				algorithm = HashAlgorithmSHA1
+			case 2:
+				algorithm = HashAlgorithmSHA256
+			case 3:
+				algorithm = HashAlgorithmSHA512
+			default:
+				return ErrUnknownAlgorithm
+			}
+		case migrationDigits:
+			switch value {
+			case 0, 1:
+				codeDigit = 6
+			case 2:
+				codeDigit = 8
+			default:
+				return ErrInvalidCodeDigit
+			}
+		case migrationType:
+			kind = value
+		case migrationCounter:
+			entry.Counter = int64(value)
+		}
+		return nil
+	})
+	if err != nil {
+		return entry, err
+	}
+	if len(secret) == 0 {
+		return entry, errors.New("invalid secret")
+	}
+
+	issuer, account := splitLabel(name)
+	if entry.Issuer == "" {
+		entry.Issuer = issuer
+	}
+	entry.AccountName = account
+
+	switch kind {
+	case migrationTypeHOTP:
+		if entry.Counter < 0 {
+			return entry, errors.New("invalid counter")
+		}
+		entry.Manager, err = NewHOTP(algorithm, secret, codeDigit, AllowShortSecret())
+	case migrationTypeTOTP:
+		entry.Counter = 0
+		entry.Manager, err = NewTOTP(algorithm, secret, codeDigit, 30, 1, 0, AllowShortSecret())
+	default:
+		return entry, errors.New("invalid type")
+	}
+	return entry, err
+}
+
+// readProtobuf reads the fields of a protocol buffer message, calling fn with the field number and either the value of
+// a varint field or the bytes of a length-delimited field, which are never nil. Fixed-width fields are skipped.
+func readProtobuf(message []byte, fn func(field int, value uint64, raw []byte) error) error {
+	for len(message) > 0 {
+		key, n := binary.Uvarint(message)
+		if n <= 0 || key>>3 == 0 {
+			return errors.New("invalid payload")
+		}
+		message = message[n:]
+		field := int(key >> 3)
+		switch key & 7 {
+		case 0:
+			value, n := binary.Uvarint(message)
+			if n <= 0 {
+				return errors.New("invalid payload")
+			}
+			message = message[n:]
+			if err := fn(field, value, nil); err != nil {
+				return err
+			}
+		case 1:
+			if len(message) < 8 {
+				return errors.New("invalid payload")
+			}
+			message = message[8:]
+		case 2:
+			length, n := binary.Uvarint(message)
+			if n <= 0 || length > uint64(len(message)-n) {
+				return errors.New("invalid payload")
+			}
+			raw := message[n : n+int(length) : n+int(length)]
+			message = message[n+int(length):]
+			if err := fn(field, 0, raw); err != nil {
+				return err
+			}
+		case 5:
+			if len(message) < 4 {
+				return errors.New("invalid payload")
+			}
+			message = message[4:]
+		default:
+			return errors.New("invalid payload")
+		}
+	}
+	return nil
+}
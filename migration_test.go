@@ -0,0 +1,87 @@
+package otp
+
+import (
+	"encoding/base64"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMigration(t *testing.T) {
+	// A single TOTP account, as commonly used to document the format.
+	entries, err := ParseMigration("otpauth-migration://offline?data=" +
+		"CjEKCkhlbGxvId6tvu8SGEV4YW1wbGU6YWxpY2VAZ29vZ2xlLmNvbRoHRXhhbXBsZTAC")
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "Example", entries[0].Issuer)
+		assert.Equal(t, "alice@google.com", entries[0].AccountName)
+		assert.Equal(t, int64(0), entries[0].Counter)
+		assert.Equal(t, "JBSWY3DPEHPK3PXP", entries[0].Manager.SecretBase32())
+		if totp, ok := entries[0].Manager.(TOTPManager); assert.True(t, ok) {
+			assert.Equal(t, "742275", totp.Generate(1234567890))
+			assert.True(t, totp.Validate(1234567920, "742275"))
+		}
+	}
+
+	// A TOTP account with an issuer and an HOTP account using SHA256 and 8 digits, in a batch with a padded payload.
+	payload := "CjgKFDEyMzQ1Njc4OTAxMjM0NTY3ODkwEhFhbGljZUBleGFtcGxlLmNvbRoHRXhhbXBsZSABKAEwAgo0CiAxMjM0NTY3ODkwMTIz" +
+		"NDU2Nzg5MDEyMzQ1Njc4OTAxMhIIQmFuazpib2IgAigCMAE4BxABGAEgACi5YA=="
+	for _, data := range []string{url.QueryEscape(payload), payload} {
+		entries, err = ParseMigration("otpauth-migration://offline?data=" + data)
+		assert.NoError(t, err)
+		if assert.Len(t, entries, 2) {
+			assert.Equal(t, "Example", entries[0].Issuer)
+			assert.Equal(t, "alice@example.com", entries[0].AccountName)
+			if totp, ok := entries[0].Manager.(TOTPManager); assert.True(t, ok) {
+				assert.Equal(t, "755224", totp.Generate(0))
+				assert.Equal(t, "005924", totp.Generate(1234567890))
+			}
+			assert.Equal(t, "Bank", entries[1].Issuer)
+			assert.Equal(t, "bob", entries[1].AccountName)
+			assert.Equal(t, int64(7), entries[1].Counter)
+			if hotp, ok := entries[1].Manager.(HOTPManager); assert.True(t, ok) {
+				assert.Equal(t, HashAlgorithmSHA256, hotp.(*hotpManager).algorithm)
+				assert.Equal(t, "11276785", hotp.Generate(7))
+			}
+		}
+	}
+
+	entries, err = ParseMigration("otpauth-migration://offline?data=")
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestParseMigrationFailure(t *testing.T) {
+	for _, testCase := range []struct {
+		Payload []byte
+		Error   string
+	}{
+		// Truncated key, length and value
+		{[]byte{0x80}, "invalid payload"},
+		{[]byte{0x0a, 0x05, 0x0a}, "invalid payload"},
+		{[]byte{0x10}, "invalid payload"},
+		// Wrong wire type of the accounts
+		{[]byte{0x08, 0x01}, "invalid payload"},
+		// Field number 0 and unsupported wire types
+		{[]byte{0x02, 0x00}, "invalid payload"},
+		{[]byte{0x0b}, "invalid payload"},
+		// Accounts without secret, with unknown algorithm, digits and type
+		{[]byte{0x0a, 0x02, 0x30, 0x02}, "invalid secret"},
+		{[]byte{0x0a, 0x07, 0x0a, 0x01, 0x31, 0x20, 0x04, 0x30, 0x02}, "unknown hash algorithm"},
+		{[]byte{0x0a, 0x07, 0x0a, 0x01, 0x31, 0x28, 0x03, 0x30, 0x02}, "invalid code digit"},
+		{[]byte{0x0a, 0x05, 0x0a, 0x01, 0x31, 0x30, 0x00}, "invalid type"},
+		{[]byte{0x0a, 0x03, 0x0a, 0x01, 0x31}, "invalid type"},
+		{[]byte{0x0a, 0x10, 0x0a, 0x01, 0x31, 0x30, 0x01, 0x38, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+			0x01}, "invalid counter"},
+	} {
+		data := url.QueryEscape(base64.StdEncoding.EncodeToString(testCase.Payload))
+		_, err := ParseMigration("otpauth-migration://offline?data=" + data)
+		assert.EqualError(t, err, testCase.Error, data)
+	}
+
+	_, err := ParseMigration("otpauth://totp/alice?secret=GEZDGNBV")
+	assert.EqualError(t, err, "invalid scheme")
+	_, err = ParseMigration("otpauth-migration://offline?data=!!!")
+	assert.EqualError(t, err, "invalid payload")
+}
@@ -0,0 +1,127 @@
+package otp
+
+import (
+	"crypto/md5"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	// motpTimeStep represents the time step of Mobile-OTP in seconds.
+	motpTimeStep = 10
+
+	// motpCodeLength represents the length of Mobile-OTP codes.
+	motpCodeLength = 6
+
+	// motpDefaultWindow represents the default tolerant time steps of Mobile-OTP in each direction, which is 3 minutes
+	// as used by the reference implementation.
+	motpDefaultWindow = 18
+)
+
+// MOTPManager represents a Mobile-OTP generator and validator. Mobile-OTP is not based on HMAC, so it does not support
+// the key URIs, challenges and options of HOTP and TOTP managers. It is an OTPManager whose moving factor is the epoch,
+// so it can be used with RateLimitedManager and ValidateAnyConstantTime.
+//
+// Managers are safe for concurrent use by multiple goroutines, except for Close.
+type MOTPManager interface {
+	OTPManager
+
+	// GenerateCode generates the one-time password for the specified epoch as a Code.
+	GenerateCode(int64) Code
+
+	// Close overwrites the secret and the PIN with zeros and makes the manager unusable.
+	Close() error
+}
+
+// motpManager represents a Mobile-OTP generator and validator.
+type motpManager struct {
+	secret       []byte
+	pin          []byte
+	lookBackward int
+	lookForward  int
+}
+
+// NewMOTP creates a new Mobile-OTP manager with the specified secret and PIN, for authenticating legacy tokens still
+// used with some VPNs and RADIUS servers. Codes are the first 6 hexadecimal digits of the MD5 of the epoch divided by
+// 10 in decimal, followed by the secret and the PIN, so a code changes every 10 seconds. Validation accepts 3 minutes
+// of clock drift in each direction, as the reference implementation does.
+//
+// Mobile-OTP relies on MD5 and on a short secret, and is much weaker than HOTP and TOTP. It should only be used for
+// tokens that cannot be replaced.
+func NewMOTP(secret, pin string) (MOTPManager, error) {
+	return NewMOTPWindow(secret, pin, motpDefaultWindow, motpDefaultWindow)
+}
+
+// NewMOTPWindow creates a new Mobile-OTP manager like NewMOTP, with the specified tolerant time steps of 10 seconds.
+// Out-of-range parameters are reported with a *ParamError.
+func NewMOTPWindow(secret, pin string, lookBackward, lookForward int) (MOTPManager, error) {
+	if secret == "" {
+		return nil, errors.New("invalid secret")
+	}
+	if pin == "" {
+		return nil, errors.New("invalid PIN")
+	}
+	if lookBackward < 0 {
+		return nil, &ParamError{Param: "lookBackward", Value: lookBackward, Min: 0, Max: math.MaxInt}
+	}
+	if lookForward < 0 {
+		return nil, &ParamError{Param: "lookForward", Value: lookForward, Min: 0, Max: math.MaxInt}
+	}
+	return &motpManager{
+		secret:       []byte(secret),
+		pin:          []byte(pin),
+		lookBackward: lookBackward,
+		lookForward:  lookForward,
+	}, nil
+}
+
+// Generate generates the one-time password for the specified epoch, or an empty string if the manager is closed.
+func (generator *motpManager) Generate(epoch int64) string {
+	return generator.generate(floorDiv(epoch, motpTimeStep))
+}
+
+// generate generates the one-time password for the specified time step.
+func (generator *motpManager) generate(step int64) string {
+	if generator.secret == nil {
+		return ""
+	}
+	message := strconv.AppendInt(nil, step, 10)
+	message = append(message, generator.secret...)
+	message = append(message, generator.pin...)
+	sum := md5.Sum(message)
+	return hex.EncodeToString(sum[:])[:motpCodeLength]
+}
+
+// GenerateCode generates the one-time password for the specified epoch as a Code, or an empty code if the manager is
+// closed.
+func (generator *motpManager) GenerateCode(epoch int64) Code {
+	return Code(generator.Generate(epoch))
+}
+
+// Validate validates whether the one-time password matches the specified epoch within the tolerant time steps. Codes
+// are accepted in either case, and every time step is compared in constant time.
+func (generator *motpManager) Validate(epoch int64, code string) bool {
+	if generator.secret == nil {
+		return false
+	}
+	code = strings.ToLower(code)
+	step := floorDiv(epoch, motpTimeStep)
+	matched := 0
+	for i := -generator.lookBackward; i <= generator.lookForward; i += 1 {
+		matched |= subtle.ConstantTimeCompare([]byte(generator.generate(step+int64(i))), []byte(code))
+	}
+	return matched == 1
+}
+
+// Close overwrites the secret and the PIN with zeros and makes the manager unusable. Refers to the Close method of HOTP
+// managers for the caveats.
+func (generator *motpManager) Close() error {
+	clear(generator.secret)
+	clear(generator.pin)
+	generator.secret, generator.pin = nil, nil
+	return nil
+}
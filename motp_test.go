@@ -0,0 +1,89 @@
+package otp
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMOTPGenerate(t *testing.T) {
+	// Expected values are the first 6 hexadecimal digits of the MD5 of "123456789" + secret + PIN, and so on. They are
+	// self-computed with Python's hashlib module rather than published vectors, since Mobile-OTP publishes none.
+	generator, err := NewMOTP("7ac61d4736f51a2b", "1234")
+	assert.NoError(t, err)
+	assert.Equal(t, "6f8231", generator.Generate(1234567890))
+	assert.Equal(t, "6f8231", generator.Generate(1234567899))
+	assert.Equal(t, "4149e6", generator.Generate(1234567900))
+	assert.Equal(t, "40a599", generator.Generate(0))
+	assert.Equal(t, Code("6f8231"), generator.GenerateCode(1234567890))
+
+	other, err := NewMOTP("7ac61d4736f51a2b", "4321")
+	assert.NoError(t, err)
+	assert.NotEqual(t, "6f8231", other.Generate(1234567890))
+}
+
+func TestMOTPValidate(t *testing.T) {
+	generator, err := NewMOTP("7ac61d4736f51a2b", "1234")
+	assert.NoError(t, err)
+	assert.True(t, generator.Validate(1234567890, "6f8231"))
+	assert.True(t, generator.Validate(1234567890, "6F8231"))
+	assert.True(t, generator.Validate(1234567890-180, "6f8231"))
+	assert.True(t, generator.Validate(1234567890+189, "6f8231"))
+	assert.False(t, generator.Validate(1234567890-190, "6f8231"))
+	assert.False(t, generator.Validate(1234567890+190, "6f8231"))
+	assert.False(t, generator.Validate(1234567890, "6f823"))
+	assert.False(t, generator.Validate(1234567890, ""))
+
+	generator, err = NewMOTPWindow("7ac61d4736f51a2b", "1234", 1, 0)
+	assert.NoError(t, err)
+	assert.True(t, generator.Validate(1234567890, "6f8231"))
+	assert.True(t, generator.Validate(1234567900, "6f8231"))
+	assert.False(t, generator.Validate(1234567910, "6f8231"))
+	assert.False(t, generator.Validate(1234567880, "6f8231"))
+}
+
+func TestMOTPAsOTPManager(t *testing.T) {
+	generator, err := NewMOTP("7ac61d4736f51a2b", "1234")
+	assert.NoError(t, err)
+	other, err := NewMOTP("7ac61d4736f51a2b", "4321")
+	assert.NoError(t, err)
+	index, match := ValidateAnyConstantTime([]OTPManager{other, generator}, 1234567890, "6f8231")
+	assert.True(t, match)
+	assert.Equal(t, 1, index)
+
+	limiter, err := NewRateLimitedManager(generator, nil, 1, time.Minute, time.Minute)
+	assert.NoError(t, err)
+	valid, err := limiter.Validate("alice", 1234567890, "6f8231")
+	assert.NoError(t, err)
+	assert.True(t, valid)
+	valid, err = limiter.Validate("alice", 1234567890, "000000")
+	assert.NoError(t, err)
+	assert.False(t, valid)
+	_, err = limiter.Validate("alice", 1234567890, "6f8231")
+	assert.Equal(t, ErrTooManyAttempts, err)
+}
+
+func TestMOTPClose(t *testing.T) {
+	generator, err := NewMOTP("7ac61d4736f51a2b", "1234")
+	assert.NoError(t, err)
+	secret, pin := generator.(*motpManager).secret, generator.(*motpManager).pin
+	assert.NoError(t, generator.Close())
+	assert.Equal(t, make([]byte, 16), secret)
+	assert.Equal(t, make([]byte, 4), pin)
+	assert.Equal(t, "", generator.Generate(1234567890))
+	assert.False(t, generator.Validate(1234567890, ""))
+	assert.False(t, generator.Validate(1234567890, "6f8231"))
+}
+
+func TestNewMOTPFailure(t *testing.T) {
+	_, err := NewMOTP("", "1234")
+	assert.EqualError(t, err, "invalid secret")
+	_, err = NewMOTP("7ac61d4736f51a2b", "")
+	assert.EqualError(t, err, "invalid PIN")
+	_, err = NewMOTPWindow("7ac61d4736f51a2b", "1234", -1, 0)
+	assert.Equal(t, &ParamError{Param: "lookBackward", Value: -1, Min: 0, Max: math.MaxInt}, err)
+	_, err = NewMOTPWindow("7ac61d4736f51a2b", "1234", 0, -1)
+	assert.Equal(t, &ParamError{Param: "lookForward", Value: -1, Min: 0, Max: math.MaxInt}, err)
+}
@@ -0,0 +1,41 @@
+package otp
+
+import "crypto/subtle"
+
+// ValidateAnyConstantTime validates the one-time password against every one of the specified managers, and gets the
+// index of the first matching manager. This is useful during secret rotation, when codes from both the old and the new
+// secret should be accepted. Nil managers never match, so the slot of the old secret can simply be left nil once the
+// rotation is over. Nil pointers to the managers of this package are skipped as well, but nil slots holding other
+// implementations must be untyped nil, since their Validate method is called otherwise. -1 and false are returned when
+// no manager matches.
+//
+// All managers are always evaluated and the results are combined in constant time, so the response time does not
+// reveal which manager matched. The cost is that validation always takes as long as validating against every manager,
+// even when the first one matches.
+func ValidateAnyConstantTime(managers []OTPManager, movingFactor int64, code string) (int, bool) {
+	index, matched := -1, 0
+	for i, manager := range managers {
+		match := 0
+		if !isNilManager(manager) && manager.Validate(movingFactor, code) {
+			match = 1
+		}
+		index = subtle.ConstantTimeSelect(match&^matched, i, index)
+		matched |= match
+	}
+	return index, matched == 1
+}
+
+// isNilManager reports whether the manager is nil, or a nil pointer to one of the managers of this package.
+func isNilManager(manager OTPManager) bool {
+	switch manager := manager.(type) {
+	case nil:
+		return true
+	case *hotpManager:
+		return manager == nil
+	case *totpManager:
+		return manager == nil
+	case *motpManager:
+		return manager == nil
+	}
+	return false
+}
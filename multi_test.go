@@ -0,0 +1,57 @@
+package otp
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateAnyConstantTime(t *testing.T) {
+	oldSecret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	newSecret, _ := hex.DecodeString("3031323334353637383930313233343536373839")
+	oldManager, err := NewTOTP(HashAlgorithmSHA1, oldSecret, 8, 30, 0, 0)
+	assert.NoError(t, err)
+	newManager, err := NewTOTP(HashAlgorithmSHA1, newSecret, 8, 30, 0, 0)
+	assert.NoError(t, err)
+	managers := []OTPManager{oldManager, newManager}
+
+	index, match := ValidateAnyConstantTime(managers, 1234567890, "89005924")
+	assert.True(t, match)
+	assert.Equal(t, 0, index)
+
+	index, match = ValidateAnyConstantTime(managers, 1234567890, newManager.Generate(1234567890))
+	assert.True(t, match)
+	assert.Equal(t, 1, index)
+
+	index, match = ValidateAnyConstantTime([]OTPManager{oldManager, oldManager}, 1234567890, "89005924")
+	assert.True(t, match)
+	assert.Equal(t, 0, index)
+
+	index, match = ValidateAnyConstantTime(managers, 1234567890, "00000000")
+	assert.False(t, match)
+	assert.Equal(t, -1, index)
+
+	index, match = ValidateAnyConstantTime([]OTPManager{nil, newManager}, 1234567890, newManager.Generate(1234567890))
+	assert.True(t, match)
+	assert.Equal(t, 1, index)
+
+	index, match = ValidateAnyConstantTime([]OTPManager{nil}, 1234567890, "89005924")
+	assert.False(t, match)
+	assert.Equal(t, -1, index)
+
+	index, match = ValidateAnyConstantTime(nil, 1234567890, "89005924")
+	assert.False(t, match)
+	assert.Equal(t, -1, index)
+
+	// Nil pointers to the managers of this package are skipped like untyped nils, rather than panicking in Validate.
+	var retired *totpManager
+	index, match = ValidateAnyConstantTime([]OTPManager{retired, newManager}, 1234567890,
+		newManager.Generate(1234567890))
+	assert.True(t, match)
+	assert.Equal(t, 1, index)
+	index, match = ValidateAnyConstantTime([]OTPManager{(*hotpManager)(nil), (*motpManager)(nil)}, 1234567890,
+		"89005924")
+	assert.False(t, match)
+	assert.Equal(t, -1, index)
+}
@@ -0,0 +1,340 @@
+package otp
+
+import (
+	"crypto/hmac"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// ocraChallengeLength represents the length in bytes of the challenge field of OCRA messages.
+	ocraChallengeLength = 128
+
+	// minOCRACodeDigits represents the minimum digit count of OCRA responses, as defined by RFC 6287.
+	minOCRACodeDigits = 4
+)
+
+// OCRAManager represents an OCRA challenge-response generator and validator described in RFC 6287.
+//
+// Managers are safe for concurrent use by multiple goroutines.
+type OCRAManager interface {
+	// Suite gets the OCRA suite the manager was created with.
+	Suite() string
+
+	// Compute computes the response to the challenge with the specified data inputs.
+	Compute(string, ...OCRAInput) string
+
+	// ComputeError computes the response to the challenge with the specified data inputs, and gets any error that
+	// occurred.
+	ComputeError(string, ...OCRAInput) (string, error)
+
+	// Validate validates whether the response to the challenge matches with the specified data inputs.
+	Validate(string, string, ...OCRAInput) bool
+}
+
+// OCRAInput provides a data input of an OCRA computation other than the challenge, as required by the OCRA suite.
+type OCRAInput func(*ocraInputs)
+
+// ocraInputs represents the resolved data inputs of an OCRA computation.
+type ocraInputs struct {
+	counter      int64
+	hasCounter   bool
+	password     []byte
+	session      []byte
+	hasSession   bool
+	epoch        int64
+	hasTimestamp bool
+}
+
+// OCRACounter provides the counter, for suites with the C data input.
+func OCRACounter(counter int64) OCRAInput {
+	return func(inputs *ocraInputs) {
+		inputs.counter = counter
+		inputs.hasCounter = true
+	}
+}
+
+// OCRAPasswordHash provides the hash of the PIN or password, for suites with the P data input, such as PSHA1. The hash
+// must be computed with the algorithm named by the suite, so that servers can store the hash instead of the PIN.
+func OCRAPasswordHash(hash []byte) OCRAInput {
+	return func(inputs *ocraInputs) {
+		inputs.password = hash
+	}
+}
+
+// OCRASession provides the session information, for suites with the S data input, such as S064. Session information
+// shorter than the length named by the suite is padded with leading zeros.
+func OCRASession(session []byte) OCRAInput {
+	return func(inputs *ocraInputs) {
+		inputs.session = session
+		inputs.hasSession = true
+	}
+}
+
+// OCRATime provides the epoch in seconds, for suites with the T data input, such as T1M. The timestamp is the number of
+// time steps of the suite elapsed since the Unix epoch.
+func OCRATime(epoch int64) OCRAInput {
+	return func(inputs *ocraInputs) {
+		inputs.epoch = epoch
+		inputs.hasTimestamp = true
+	}
+}
+
+// ocraManager represents an OCRA challenge-response generator and validator.
+type ocraManager struct {
+	suite         string
+	hashAlgorithm HashAlgorithm
+	secret        []byte
+	codeDigits    int
+	counter       bool
+	challengeType byte
+	password      HashAlgorithm
+	hasPassword   bool
+	sessionLength int
+	timeStep      int64
+}
+
+// NewOCRA creates a new OCRA challenge-response manager with the specified OCRA suite and secret key, such as
+// "OCRA-1:HOTP-SHA1-6:QN08" for responses of 6 digits to numeric challenges of up to 8 digits.
+//
+// Suites consist of the algorithm OCRA-1, the crypto function HOTP-SHA1, HOTP-SHA256 or HOTP-SHA512 followed by the
+// digit count between 4 and 10, and the data inputs in the order C, Qxnn, Pxxx, Snnn and Tnx, where only the challenge
+// is required. Responses without truncation, with a digit count of 0, are not supported. The secret key is required,
+// and the manager keeps its own copy of it.
+//
+// The length named by the challenge format is not enforced, so that the concatenated challenges of mutual challenge-
+// response, which are twice as long, are accepted as well. Challenges only need to fit into the 128 bytes of the
+// message.
+func NewOCRA(suite string, secret []byte) (OCRAManager, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("invalid secret")
+	}
+	manager := &ocraManager{suite: suite, secret: append([]byte(nil), secret...)}
+
+	parts := strings.Split(suite, ":")
+	if len(parts) != 3 || parts[0] != "OCRA-1" {
+		return nil, errors.New("invalid suite")
+	}
+
+	function := strings.Split(parts[1], "-")
+	if len(function) != 3 || function[0] != "HOTP" {
+		return nil, errors.New("invalid suite")
+	}
+	algorithm, ok := parseOCRAAlgorithm(function[1])
+	if !ok {
+		return nil, errors.New("invalid suite")
+	}
+	manager.hashAlgorithm = algorithm
+	codeDigit, err := strconv.Atoi(function[2])
+	if err != nil {
+		return nil, errors.New("invalid suite")
+	}
+	if codeDigit < minOCRACodeDigits || codeDigit > MaxCodeDigits {
+		return nil, &ParamError{Param: "codeDigit", Value: codeDigit, Min: minOCRACodeDigits, Max: MaxCodeDigits}
+	}
+	manager.codeDigits = codeDigit
+
+	if err := manager.parseDataInputs(strings.Split(parts[2], "-")); err != nil {
+		return nil, err
+	}
+	return manager, nil
+}
+
+// parseDataInputs parses the data inputs of the OCRA suite, which must appear in the order defined by RFC 6287.
+func (manager *ocraManager) parseDataInputs(inputs []string) error {
+	if len(inputs) > 0 && inputs[0] == "C" {
+		manager.counter = true
+		inputs = inputs[1:]
+	}
+
+	// The challenge is required, such as QN08 for numeric challenges of up to 8 digits.
+	if len(inputs) == 0 || len(inputs[0]) != 4 || inputs[0][0] != 'Q' {
+		return errors.New("invalid suite")
+	}
+	challengeType, maxLength := inputs[0][1], inputs[0][2:]
+	if length, _ := strconv.Atoi(maxLength); !strings.ContainsRune("ANH", rune(challengeType)) ||
+		!decimalDigits(maxLength) || length < 4 || length > 64 {
+		return errors.New("invalid suite")
+	}
+	manager.challengeType = challengeType
+	inputs = inputs[1:]
+
+	if len(inputs) > 0 && strings.HasPrefix(inputs[0], "P") {
+		algorithm, ok := parseOCRAAlgorithm(inputs[0][1:])
+		if !ok {
+			return errors.New("invalid suite")
+		}
+		manager.password = algorithm
+		manager.hasPassword = true
+		inputs = inputs[1:]
+	}
+
+	if len(inputs) > 0 && strings.HasPrefix(inputs[0], "S") {
+		length, _ := strconv.Atoi(inputs[0][1:])
+		if len(inputs[0]) != 4 || !decimalDigits(inputs[0][1:]) || length <= 0 {
+			return errors.New("invalid suite")
+		}
+		manager.sessionLength = length
+		inputs = inputs[1:]
+	}
+
+	if len(inputs) > 0 && strings.HasPrefix(inputs[0], "T") && len(inputs[0]) >= 3 {
+		count, _ := strconv.Atoi(inputs[0][1 : len(inputs[0])-1])
+		var unit time.Duration
+		var limit int
+		switch inputs[0][len(inputs[0])-1] {
+		case 'S':
+			unit, limit = time.Second, 59
+		case 'M':
+			unit, limit = time.Minute, 59
+		case 'H':
+			unit, limit = time.Hour, 48
+		}
+		if unit == 0 || !decimalDigits(inputs[0][1:len(inputs[0])-1]) || count <= 0 || count > limit {
+			return errors.New("invalid suite")
+		}
+		manager.timeStep = int64(time.Duration(count) * unit / time.Second)
+		inputs = inputs[1:]
+	}
+
+	if len(inputs) != 0 {
+		return errors.New("invalid suite")
+	}
+	return nil
+}
+
+// parseOCRAAlgorithm parses the name of a hash algorithm allowed in OCRA suites.
+func parseOCRAAlgorithm(name string) (HashAlgorithm, bool) {
+	switch name {
+	case "SHA1":
+		return HashAlgorithmSHA1, true
+	case "SHA256":
+		return HashAlgorithmSHA256, true
+	case "SHA512":
+		return HashAlgorithmSHA512, true
+	}
+	return 0, false
+}
+
+func (manager *ocraManager) Suite() string {
+	return manager.suite
+}
+
+// Compute computes the response to the challenge with the specified data inputs, or an empty string if the challenge
+// or the data inputs do not match the suite. Refers to ComputeError for details.
+func (manager *ocraManager) Compute(challenge string, inputs ...OCRAInput) string {
+	response, _ := manager.ComputeError(challenge, inputs...)
+	return response
+}
+
+// ComputeError computes the response to the challenge with the specified data inputs. Every data input named by the
+// suite must be provided, and data inputs not named by it are ignored.
+//
+// Numeric challenges consist of decimal digits, hexadecimal challenges of hexadecimal digits, and alphanumeric
+// challenges are used as raw bytes.
+func (manager *ocraManager) ComputeError(challenge string, inputs ...OCRAInput) (string, error) {
+	var resolved ocraInputs
+	for _, input := range inputs {
+		input(&resolved)
+	}
+
+	message := append([]byte(manager.suite), 0)
+	if manager.counter {
+		if !resolved.hasCounter {
+			return "", errors.New("missing counter")
+		}
+		message = binary.BigEndian.AppendUint64(message, uint64(resolved.counter))
+	}
+
+	question, err := manager.encodeChallenge(challenge)
+	if err != nil {
+		return "", err
+	}
+	message = append(message, question...)
+
+	if manager.hasPassword {
+		hash, _ := manager.password.hash()
+		if len(resolved.password) != hash().Size() {
+			return "", errors.New("invalid password hash")
+		}
+		message = append(message, resolved.password...)
+	}
+
+	if manager.sessionLength != 0 {
+		if !resolved.hasSession || len(resolved.session) > manager.sessionLength {
+			return "", errors.New("invalid session information")
+		}
+		message = append(message, make([]byte, manager.sessionLength-len(resolved.session))...)
+		message = append(message, resolved.session...)
+	}
+
+	if manager.timeStep != 0 {
+		if !resolved.hasTimestamp {
+			return "", errors.New("missing timestamp")
+		}
+		message = binary.BigEndian.AppendUint64(message, uint64(floorDiv(resolved.epoch, manager.timeStep)))
+	}
+
+	hash, _ := manager.hashAlgorithm.hash()
+	mac := hmac.New(hash, manager.secret)
+	if _, err := mac.Write(message); err != nil {
+		return "", err
+	}
+	return Truncate(mac.Sum(nil), manager.codeDigits), nil
+}
+
+// encodeChallenge encodes the challenge as the challenge field of the message: numeric challenges are converted to
+// hexadecimal first, and the bytes are padded with trailing zeros to 128 bytes.
+func (manager *ocraManager) encodeChallenge(challenge string) ([]byte, error) {
+	if challenge == "" {
+		return nil, errors.New("invalid challenge")
+	}
+	var question []byte
+	switch manager.challengeType {
+	case 'N':
+		value, ok := new(big.Int).SetString(challenge, 10)
+		if !ok || !decimalDigits(challenge) {
+			return nil, errors.New("invalid challenge")
+		}
+		challenge = value.Text(16)
+		fallthrough
+	case 'H':
+		if len(challenge)%2 != 0 {
+			challenge += "0"
+		}
+		decoded, err := hex.DecodeString(challenge)
+		if err != nil {
+			return nil, errors.New("invalid challenge")
+		}
+		question = decoded
+	default:
+		question = []byte(challenge)
+	}
+	if len(question) > ocraChallengeLength {
+		return nil, errors.New("invalid challenge")
+	}
+	return append(question, make([]byte, ocraChallengeLength-len(question))...), nil
+}
+
+// decimalDigits checks whether the string consists of decimal digits only.
+func decimalDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate validates whether the response to the challenge matches with the specified data inputs. Challenges or data
+// inputs that do not match the suite are reported as a mismatch. The response is compared in constant time.
+func (manager *ocraManager) Validate(challenge, response string, inputs ...OCRAInput) bool {
+	expected, err := manager.ComputeError(challenge, inputs...)
+	return err == nil && subtle.ConstantTimeCompare([]byte(expected), []byte(response)) == 1
+}
@@ -0,0 +1,159 @@
+package otp
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// ocraTestKeys represents the secret keys of the test vectors of RFC 6287.
+var ocraTestKeys = map[HashAlgorithm]string{
+	HashAlgorithmSHA1:   "3132333435363738393031323334353637383930",
+	HashAlgorithmSHA256: "3132333435363738393031323334353637383930313233343536373839303132",
+	HashAlgorithmSHA512: strings.Repeat("31323334353637383930", 6) + "31323334",
+}
+
+// ocraTestTime represents the epoch of the timestamp 132d0b6 in minutes used by the test vectors of RFC 6287.
+const ocraTestTime = 0x132d0b6 * 60
+
+func newTestOCRA(t *testing.T, suite string, algorithm HashAlgorithm) OCRAManager {
+	secret, _ := hex.DecodeString(ocraTestKeys[algorithm])
+	manager, err := NewOCRA(suite, secret)
+	assert.NoError(t, err)
+	return manager
+}
+
+func TestOCRAOneWayRFC(t *testing.T) {
+	manager := newTestOCRA(t, "OCRA-1:HOTP-SHA1-6:QN08", HashAlgorithmSHA1)
+	assert.Equal(t, "OCRA-1:HOTP-SHA1-6:QN08", manager.Suite())
+	for i, expected := range []string{"237653", "243178", "653583", "740991", "608993", "388898", "816933", "224598",
+		"750600", "294470"} {
+		challenge := strings.Repeat(strconv.Itoa(i), 8)
+		assert.Equal(t, expected, manager.Compute(challenge))
+		assert.True(t, manager.Validate(challenge, expected))
+	}
+
+	pin := sha1.Sum([]byte("1234"))
+	manager = newTestOCRA(t, "OCRA-1:HOTP-SHA256-8:C-QN08-PSHA1", HashAlgorithmSHA256)
+	for i, expected := range []string{"65347737", "86775851", "78192410", "71565254", "10104329", "65983500",
+		"70069104", "91771096", "75011558", "08522129"} {
+		assert.Equal(t, expected, manager.Compute("12345678", OCRACounter(int64(i)), OCRAPasswordHash(pin[:])))
+	}
+
+	manager = newTestOCRA(t, "OCRA-1:HOTP-SHA256-8:QN08-PSHA1", HashAlgorithmSHA256)
+	for i, expected := range []string{"83238735", "01501458", "17957585", "86776967", "86807031"} {
+		assert.Equal(t, expected, manager.Compute(strings.Repeat(strconv.Itoa(i), 8), OCRAPasswordHash(pin[:])))
+	}
+
+	manager = newTestOCRA(t, "OCRA-1:HOTP-SHA512-8:C-QN08", HashAlgorithmSHA512)
+	for i, expected := range []string{"07016083", "63947962", "70123924", "25341727", "33203315", "34205738",
+		"44343969", "51946085", "20403879", "31409299"} {
+		assert.Equal(t, expected, manager.Compute(strings.Repeat(strconv.Itoa(i), 8), OCRACounter(int64(i))))
+	}
+
+	manager = newTestOCRA(t, "OCRA-1:HOTP-SHA512-8:QN08-T1M", HashAlgorithmSHA512)
+	for i, expected := range []string{"95209754", "55907591", "22048402", "24218844", "36209546"} {
+		challenge := strings.Repeat(strconv.Itoa(i), 8)
+		assert.Equal(t, expected, manager.Compute(challenge, OCRATime(ocraTestTime)))
+		assert.Equal(t, expected, manager.Compute(challenge, OCRATime(ocraTestTime+59)))
+		assert.NotEqual(t, expected, manager.Compute(challenge, OCRATime(ocraTestTime+60)))
+	}
+}
+
+func TestOCRAMutualRFC(t *testing.T) {
+	manager := newTestOCRA(t, "OCRA-1:HOTP-SHA256-8:QA08", HashAlgorithmSHA256)
+	for i, expected := range []string{"28247970", "01984843", "65387857", "03351211", "83412541"} {
+		challenge := "CLI2222" + strconv.Itoa(i) + "SRV1111" + strconv.Itoa(i)
+		assert.Equal(t, expected, manager.Compute(challenge))
+	}
+}
+
+func TestOCRASignatureRFC(t *testing.T) {
+	manager := newTestOCRA(t, "OCRA-1:HOTP-SHA256-8:QA08", HashAlgorithmSHA256)
+	for i, expected := range []string{"53095496", "04110475", "31331128", "76028668", "46554205"} {
+		assert.Equal(t, expected, manager.Compute("SIG1"+strconv.Itoa(i)+"000"))
+	}
+	manager = newTestOCRA(t, "OCRA-1:HOTP-SHA512-8:QA10-T1M", HashAlgorithmSHA512)
+	assert.Equal(t, "77537423", manager.Compute("SIG1000000", OCRATime(ocraTestTime)))
+}
+
+func TestOCRAHexChallenge(t *testing.T) {
+	// Expected value computed with a reference implementation
+	manager := newTestOCRA(t, "OCRA-1:HOTP-SHA1-6:QH08", HashAlgorithmSHA1)
+	assert.Equal(t, "698477", manager.Compute("ABCDEF12"))
+	assert.Equal(t, "698477", manager.Compute("abcdef12"))
+}
+
+func TestOCRASession(t *testing.T) {
+	manager := newTestOCRA(t, "OCRA-1:HOTP-SHA1-6:QN08-S064", HashAlgorithmSHA1)
+	session := []byte("session")
+	padded := append(make([]byte, 64-len(session)), session...)
+	response, err := manager.ComputeError("12345678", OCRASession(session))
+	assert.NoError(t, err)
+	assert.Equal(t, response, manager.Compute("12345678", OCRASession(padded)))
+	assert.NotEqual(t, response, manager.Compute("12345678", OCRASession([]byte("other"))))
+	_, err = manager.ComputeError("12345678", OCRASession(make([]byte, 65)))
+	assert.EqualError(t, err, "invalid session information")
+}
+
+func TestOCRAInvalidInputs(t *testing.T) {
+	manager := newTestOCRA(t, "OCRA-1:HOTP-SHA512-8:C-QN08-PSHA256-S016-T30S", HashAlgorithmSHA512)
+	pin := make([]byte, 32)
+	for _, testCase := range []struct {
+		Challenge string
+		Inputs    []OCRAInput
+		Error     string
+	}{
+		{"12345678", []OCRAInput{OCRAPasswordHash(pin), OCRASession(nil), OCRATime(0)}, "missing counter"},
+		{"12345678", []OCRAInput{OCRACounter(0), OCRASession(nil), OCRATime(0)}, "invalid password hash"},
+		{"12345678", []OCRAInput{OCRACounter(0), OCRAPasswordHash(pin[:20]), OCRASession(nil), OCRATime(0)},
+			"invalid password hash"},
+		{"12345678", []OCRAInput{OCRACounter(0), OCRAPasswordHash(pin), OCRATime(0)}, "invalid session information"},
+		{"12345678", []OCRAInput{OCRACounter(0), OCRAPasswordHash(pin), OCRASession(nil)}, "missing timestamp"},
+		{"", []OCRAInput{OCRACounter(0), OCRAPasswordHash(pin), OCRASession(nil), OCRATime(0)}, "invalid challenge"},
+		{"1234567a", []OCRAInput{OCRACounter(0), OCRAPasswordHash(pin), OCRASession(nil), OCRATime(0)},
+			"invalid challenge"},
+		{"+1234567", []OCRAInput{OCRACounter(0), OCRAPasswordHash(pin), OCRASession(nil), OCRATime(0)},
+			"invalid challenge"},
+	} {
+		response, err := manager.ComputeError(testCase.Challenge, testCase.Inputs...)
+		assert.EqualError(t, err, testCase.Error)
+		assert.Equal(t, "", response)
+		assert.Equal(t, "", manager.Compute(testCase.Challenge, testCase.Inputs...))
+		assert.False(t, manager.Validate(testCase.Challenge, "", testCase.Inputs...))
+	}
+	response, err := manager.ComputeError("12345678", OCRACounter(0), OCRAPasswordHash(pin), OCRASession(nil),
+		OCRATime(0))
+	assert.NoError(t, err)
+	assert.Len(t, response, 8)
+
+	manager = newTestOCRA(t, "OCRA-1:HOTP-SHA1-6:QH08", HashAlgorithmSHA1)
+	assert.Equal(t, "", manager.Compute("12345g"))
+	assert.Equal(t, "", manager.Compute(strings.Repeat("ab", 129)))
+	assert.NotEqual(t, "", manager.Compute(strings.Repeat("ab", 128)))
+	manager = newTestOCRA(t, "OCRA-1:HOTP-SHA1-6:QA08", HashAlgorithmSHA1)
+	assert.Equal(t, "", manager.Compute(strings.Repeat("a", 129)))
+}
+
+func TestNewOCRAFailure(t *testing.T) {
+	secret, _ := hex.DecodeString(ocraTestKeys[HashAlgorithmSHA1])
+	for _, suite := range []string{"", "OCRA-1:HOTP-SHA1-6", "OCRA-2:HOTP-SHA1-6:QN08", "OCRA-1:TOTP-SHA1-6:QN08",
+		"OCRA-1:HOTP-MD5-6:QN08", "OCRA-1:HOTP-SHA1-x:QN08", "OCRA-1:HOTP-SHA1-6:C", "OCRA-1:HOTP-SHA1-6:QX08",
+		"OCRA-1:HOTP-SHA1-6:QN03", "OCRA-1:HOTP-SHA1-6:QN65", "OCRA-1:HOTP-SHA1-6:QN+8", "OCRA-1:HOTP-SHA1-6:QN08-C",
+		"OCRA-1:HOTP-SHA1-6:QN08-PMD5", "OCRA-1:HOTP-SHA1-6:QN08-S64", "OCRA-1:HOTP-SHA1-6:QN08-S000",
+		"OCRA-1:HOTP-SHA1-6:QN08-T60S", "OCRA-1:HOTP-SHA1-6:QN08-T49H", "OCRA-1:HOTP-SHA1-6:QN08-T0M",
+		"OCRA-1:HOTP-SHA1-6:QN08-T1D", "OCRA-1:HOTP-SHA1-6:QN08-T1M-S064", "OCRA-1:HOTP-SHA1-6:QN08-T"} {
+		_, err := NewOCRA(suite, secret)
+		assert.EqualError(t, err, "invalid suite", suite)
+	}
+	_, err := NewOCRA("OCRA-1:HOTP-SHA1-0:QN08", secret)
+	assert.Equal(t, &ParamError{Param: "codeDigit", Value: 0, Min: 4, Max: MaxCodeDigits}, err)
+	_, err = NewOCRA("OCRA-1:HOTP-SHA1-11:QN08", secret)
+	assert.Equal(t, &ParamError{Param: "codeDigit", Value: 11, Min: 4, Max: MaxCodeDigits}, err)
+	_, err = NewOCRA("OCRA-1:HOTP-SHA1-6:QN08", nil)
+	assert.EqualError(t, err, "invalid secret")
+}
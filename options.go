@@ -0,0 +1,279 @@
+package otp
+
+import (
+	"errors"
+	"io"
+	"math"
+	"time"
+)
+
+// HOTPOption configures optional behavior of an HMAC-based one-time password (HOTP) manager.
+type HOTPOption interface {
+	applyHOTP(*hotpManager) error
+}
+
+// TOTPOption configures optional behavior of a time-based one-time password (TOTP) manager.
+type TOTPOption interface {
+	applyTOTP(*totpManager) error
+}
+
+// Option configures optional behavior of both HOTP and TOTP managers.
+type Option interface {
+	HOTPOption
+	TOTPOption
+}
+
+// hotpOption represents an option applicable to HOTP managers, and to TOTP managers through their HOTP manager.
+type hotpOption func(*hotpManager) error
+
+func (option hotpOption) applyHOTP(generator *hotpManager) error {
+	return option(generator)
+}
+
+func (option hotpOption) applyTOTP(generator *totpManager) error {
+	return option(generator.hotp)
+}
+
+// totpOption represents an option only applicable to TOTP managers.
+type totpOption func(*totpManager) error
+
+func (option totpOption) applyTOTP(generator *totpManager) error {
+	return option(generator)
+}
+
+// WithSecret sets the secret key. A new secret key is generated if it is nil, which is the default.
+func WithSecret(secret []byte) Option {
+	return hotpOption(func(generator *hotpManager) error {
+		generator.secret = secret
+		return nil
+	})
+}
+
+// WithRandomReader reads generated secret keys from the specified source instead of crypto/rand.Reader, such as a
+// hardware random number generator, or a deterministic source in tests. It is only used when no secret key is provided.
+// The source must be cryptographically secure, and failing to fill the whole secret key is reported as an error.
+func WithRandomReader(random io.Reader) Option {
+	return hotpOption(func(generator *hotpManager) error {
+		if random == nil {
+			return errors.New("invalid random reader")
+		}
+		generator.random = random
+		return nil
+	})
+}
+
+// WithGeneratedSecretSize generates secret keys of the specified number of bytes instead of the default key size of the
+// algorithm, for policies mandating a key length. Sizes below 16 bytes, the minimum required by RFC 4226, are rejected.
+// It is only used when no secret key is provided.
+func WithGeneratedSecretSize(size int) Option {
+	return hotpOption(func(generator *hotpManager) error {
+		if size < minSecretLength {
+			return &ParamError{Param: "secretSize", Value: size, Min: minSecretLength, Max: math.MaxInt}
+		}
+		generator.secretSize = size
+		return nil
+	})
+}
+
+// WithMinSecretLength rejects provided secret keys shorter than the specified number of bytes, instead of 16 bytes by
+// default. It does not affect generated secret keys, whose length is the default key size of the algorithm.
+func WithMinSecretLength(length int) Option {
+	return hotpOption(func(generator *hotpManager) error {
+		if length <= 0 {
+			return &ParamError{Param: "minSecretLength", Value: length, Min: 1, Max: math.MaxInt}
+		}
+		generator.minSecret = length
+		return nil
+	})
+}
+
+// AllowShortSecret accepts provided secret keys of any non-zero length, such as the 10-byte secret keys issued by some
+// services or the test vectors of RFCs. Short secret keys are easier to brute-force, so this should only be used for
+// secret keys that cannot be replaced.
+func AllowShortSecret() Option {
+	return WithMinSecretLength(1)
+}
+
+// WithDigits sets the digit count of password codes, which cannot be longer than 10 digits. The default is 6 digits.
+func WithDigits(codeDigit int) Option {
+	return hotpOption(func(generator *hotpManager) error {
+		if codeDigit <= 0 || codeDigit > MaxCodeDigits {
+			return &ParamError{Param: "codeDigit", Value: codeDigit, Min: 1, Max: MaxCodeDigits}
+		}
+		generator.codeDigits = codeDigit
+		return nil
+	})
+}
+
+// WithTimeStep sets the time step in seconds. The default is 30 seconds.
+func WithTimeStep(timeStep int) TOTPOption {
+	return totpOption(func(generator *totpManager) error {
+		if timeStep <= 0 {
+			return &ParamError{Param: "timeStep", Value: timeStep, Min: 1, Max: math.MaxInt}
+		}
+		generator.timeStep = timeStep
+		generator.stepDuration = 0
+		return nil
+	})
+}
+
+// WithSkewWindow sets the tolerant time steps backward and forward accepted when validating. The default is one time
+// step backward and none forward.
+func WithSkewWindow(lookBackward, lookForward int) TOTPOption {
+	return totpOption(func(generator *totpManager) error {
+		if lookBackward < 0 {
+			return &ParamError{Param: "lookBackward", Value: lookBackward, Min: 0, Max: math.MaxInt}
+		}
+		if lookForward < 0 {
+			return &ParamError{Param: "lookForward", Value: lookForward, Min: 0, Max: math.MaxInt}
+		}
+		generator.lookBackward = lookBackward
+		generator.lookForward = lookForward
+		return nil
+	})
+}
+
+// WithEncoder renders codes with the specified encoder instead of DecimalEncoder. Validation renders the expected code
+// with the same encoder.
+func WithEncoder(encoder Encoder) Option {
+	return hotpOption(func(generator *hotpManager) error {
+		if encoder == nil {
+			return errors.New("invalid encoder")
+		}
+		generator.encoder = encoder
+		return nil
+	})
+}
+
+// WithAlphabet renders codes of the specified length in positional notation with the specified symbols, as created by
+// NewAlphabetEncoder. The length replaces the code digits passed to the constructor and cannot be longer than 10
+// symbols. For example, WithAlphabet("0123456789abcdef", 6) renders 6-digit hexadecimal codes. Decimal codes are
+// rendered by default, which is the behavior defined by RFC 4226.
+func WithAlphabet(symbols string, length int) Option {
+	return hotpOption(func(generator *hotpManager) error {
+		encoder, err := NewAlphabetEncoder(symbols)
+		if err != nil {
+			return err
+		}
+		if length <= 0 || length > MaxCodeDigits {
+			return &ParamError{Param: "codeDigit", Value: length, Min: 1, Max: MaxCodeDigits}
+		}
+		if symbols == "0123456789" {
+			encoder = DecimalEncoder
+		}
+		generator.encoder = encoder
+		generator.codeDigits = length
+		return nil
+	})
+}
+
+// WithChecksum appends the checksum digit described in section 5.3 of RFC 4226 to generated codes, and requires it
+// when validating, so that codes with a single mistyped digit do not match. The checksum digit is computed with the
+// Luhn algorithm over the code digits, and is not counted in them: 6-digit codes become 7 characters long. Checksums
+// can only be combined with decimal codes, and are not supported by authenticator apps.
+func WithChecksum(enabled bool) Option {
+	return hotpOption(func(generator *hotpManager) error {
+		generator.checksum = enabled
+		return nil
+	})
+}
+
+// WithTruncationOffset selects the 4 bytes of the HMAC result at the specified fixed offset when truncating, instead of
+// the offset selected by dynamic truncation as described in RFC 4226, for compatibility with legacy tokens using a
+// fixed offset. The offset must leave 4 bytes within the HMAC result, so it cannot exceed 16 for SHA1. Codes are not
+// compatible with authenticator apps.
+func WithTruncationOffset(offset int) Option {
+	return hotpOption(func(generator *hotpManager) error {
+		maxOffset := generator.hashAlgorithm().Size() - 4
+		if offset < 0 || offset > maxOffset {
+			return &ParamError{Param: "truncationOffset", Value: offset, Min: 0, Max: maxOffset}
+		}
+		generator.offset = offset
+		return nil
+	})
+}
+
+// WithCounterBytes sets the width in bytes of the big-endian counter at the start of the HMAC message, for
+// compatibility with legacy tokens using 4-byte counters. It must be 4 or 8, and defaults to 8 as defined by RFC 4226.
+// With 4 bytes, counters and time steps beyond 2^32-1 have no code. Codes are not compatible with authenticator apps.
+func WithCounterBytes(n int) Option {
+	return hotpOption(func(generator *hotpManager) error {
+		if n != 4 && n != 8 {
+			return errors.New("invalid counter bytes")
+		}
+		generator.counterBytes = n
+		return nil
+	})
+}
+
+// WithExpectedPrefix makes validation require codes to start with the specified prefix, such as the serial number some
+// tokens display in front of the code. The prefix is checked in constant time and removed, and the rest is validated
+// as usual. Generated codes do not include the prefix, and ValidateCanonical gets the canonical form without it.
+func WithExpectedPrefix(prefix string) Option {
+	return hotpOption(func(generator *hotpManager) error {
+		generator.prefix = prefix
+		return nil
+	})
+}
+
+// WithLenientInput makes validation accept codes as typed or pasted by users, such as " 123 456 " or "123-456", by
+// removing whitespace and hyphens before comparing. Unlike ValidateCanonical, leading zeros are not restored, so codes
+// of the wrong length are still rejected. It should not be used with encoders whose symbols include whitespace or
+// hyphens.
+func WithLenientInput() Option {
+	return hotpOption(func(generator *hotpManager) error {
+		generator.lenient = true
+		return nil
+	})
+}
+
+// WithClock makes GenerateNow, ValidateNow, Challenge and Verify read the current time from the specified clock instead
+// of time.Now, such as a fixed clock in tests or an NTP-corrected clock. Methods taking an explicit epoch or time are
+// not affected.
+func WithClock(clock func() time.Time) TOTPOption {
+	return totpOption(func(generator *totpManager) error {
+		if clock == nil {
+			return errors.New("invalid clock")
+		}
+		generator.clock = clock
+		return nil
+	})
+}
+
+// WithSecretDerivedT0 derives T0, the epoch at which time steps start counting, from the secret key instead of using
+// 0, so that the boundaries of time steps are not aligned to whole minutes and differ between secret keys. This
+// prevents an observer from correlating code changes with wall-clock boundaries.
+//
+// Codes are NOT compatible with standard authenticator apps or any other implementation of RFC 6238 unless they
+// derive T0 the same way, so this is only suitable for server-to-server use. It has no effect on time steps that are
+// not a whole number of seconds.
+func WithSecretDerivedT0(derived bool) TOTPOption {
+	return totpOption(func(generator *totpManager) error {
+		generator.derivedT0 = derived
+		return nil
+	})
+}
+
+// WithT0 sets T0, the epoch at which time steps start counting as defined by RFC 6238, so that the time step of an
+// epoch is (epoch - T0) / timeStep. T0 is 0 by default, which is what authenticator apps use, but some hardware tokens
+// count time steps from the moment they were initialized. Epochs before T0 belong to negative time steps, which have no
+// code. It replaces a secret-derived T0 set by an earlier option, and can be combined with WithEpochOffset, which
+// compensates the clock of a client instead.
+func WithT0(t0 int64) TOTPOption {
+	return totpOption(func(generator *totpManager) error {
+		generator.t0 = t0
+		generator.derivedT0 = false
+		return nil
+	})
+}
+
+// WithEpochOffset adds a fixed number of seconds to every epoch before the time step is computed, which compensates a
+// client whose clock is known to be off by that much, such as an embedded device with a miscalibrated clock. Unlike the
+// tolerant time steps, which search several time steps around the epoch, the offset shifts the epoch itself, and both
+// can be combined. The offset may be negative.
+func WithEpochOffset(seconds int) TOTPOption {
+	return totpOption(func(generator *totpManager) error {
+		generator.epochOffset = int64(seconds)
+		return nil
+	})
+}
@@ -0,0 +1,460 @@
+package otp
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithEpochOffset(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 0, 0, WithEpochOffset(100))
+	assert.NoError(t, err)
+	assert.Equal(t, "89005924", generator.Generate(1234567790))
+	assert.Equal(t, "89005924", generator.GenerateTime(time.Unix(1234567819, 0)))
+	assert.True(t, generator.Validate(1234567790, "89005924"))
+	assert.False(t, generator.Validate(1234567890, "89005924"))
+	assert.Equal(t, int64(1234567820), generator.NextChangeTime(1234567790))
+	assert.Equal(t, 30, generator.RemainingSeconds(1234567790))
+	assert.Equal(t, int64(1234567819), generator.AcceptedUntil(1234567790))
+
+	generator, err = NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 0, 0, WithEpochOffset(-100))
+	assert.NoError(t, err)
+	assert.Equal(t, "89005924", generator.Generate(1234567990))
+	assert.False(t, generator.Validate(1234567890, "89005924"))
+}
+
+func TestWithEpochOffsetAndWindow(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 1, 1, WithEpochOffset(-300))
+	assert.NoError(t, err)
+	assert.False(t, generator.Validate(1234568190-31, "89005924"))
+	assert.True(t, generator.Validate(1234568190-30, "89005924"))
+	assert.True(t, generator.Validate(1234568190, "89005924"))
+	assert.True(t, generator.Validate(1234568190+59, "89005924"))
+	assert.False(t, generator.Validate(1234568190+60, "89005924"))
+	assert.False(t, generator.Validate(1234567890, "89005924"))
+}
+
+func TestWithT0(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 1, 0, WithT0(1000000000))
+	assert.NoError(t, err)
+	// The RFC 6238 test vectors, counted from T0 instead of 0.
+	assert.Equal(t, "94287082", generator.Generate(1000000000+59))
+	assert.Equal(t, "07081804", generator.Generate(1000000000+1111111109))
+	assert.Equal(t, "07081804", generator.GenerateTime(time.Unix(1000000000+1111111109, 0)))
+	assert.NotEqual(t, "07081804", generator.Generate(1111111109))
+	assert.Equal(t, int64(1), generator.MovingFactor(1000000000+59))
+	assert.Equal(t, int64(1000000060), generator.NextChangeTime(1000000000+59))
+	assert.Equal(t, 1, generator.RemainingSeconds(1000000000+59))
+	assert.Equal(t, int64(1000000089), generator.AcceptedUntil(1000000000+59))
+
+	// The tolerant time steps are counted from T0 as well.
+	assert.False(t, generator.Validate(1000000000+29, "94287082"))
+	assert.True(t, generator.Validate(1000000000+30, "94287082"))
+	assert.True(t, generator.Validate(1000000000+89, "94287082"))
+	assert.False(t, generator.Validate(1000000000+90, "94287082"))
+	match, offset := generator.ValidateWithSkew(1000000000+60, "94287082")
+	assert.True(t, match)
+	assert.Equal(t, -1, offset)
+	assert.True(t, generator.ValidateTime(time.Unix(1000000000+89, 0), "94287082"))
+
+	// Epochs before T0 have no code.
+	_, err = generator.GenerateError(1000000000 - 1)
+	assert.EqualError(t, err, "invalid counter")
+	assert.Contains(t, generator.CompatibilityReport(), "T0 other than 0 is not supported by authenticator apps")
+
+	// T0 can be combined with the epoch offset, and replaces a secret-derived T0.
+	generator, err = NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 0, 0, WithSecretDerivedT0(true), WithT0(1000000000),
+		WithEpochOffset(100))
+	assert.NoError(t, err)
+	assert.Equal(t, "94287082", generator.Generate(1000000000+59-100))
+	generator, err = NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 0, 0, WithT0(0))
+	assert.NoError(t, err)
+	assert.Equal(t, "94287082", generator.Generate(59))
+	assert.NotContains(t, generator.CompatibilityReport(), "T0 other than 0 is not supported by authenticator apps")
+}
+
+func TestWithExpectedPrefix(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	hotp, err := NewHOTP(HashAlgorithmSHA1, secret, 6, WithExpectedPrefix("TK42"))
+	assert.NoError(t, err)
+	assert.Equal(t, "755224", hotp.Generate(0))
+	assert.True(t, hotp.Validate(0, "TK42755224"))
+	assert.False(t, hotp.Validate(0, "TK43755224"))
+	assert.False(t, hotp.Validate(0, "755224"))
+	assert.False(t, hotp.Validate(0, "TK4"))
+	assert.False(t, hotp.Validate(0, ""))
+	match, canonical := hotp.ValidateCanonical(0, "TK42755 224")
+	assert.True(t, match)
+	assert.Equal(t, "755224", canonical)
+	_, match, err = hotp.SearchCounter(0, "TK42969429", 5)
+	assert.NoError(t, err)
+	assert.True(t, match)
+
+	totp, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 1, 0, WithExpectedPrefix("TK42"))
+	assert.NoError(t, err)
+	assert.Equal(t, "89005924", totp.Generate(1234567890))
+	assert.True(t, totp.Validate(1234567890, "TK4289005924"))
+	assert.True(t, totp.Validate(1234567920, "TK4289005924"))
+	assert.True(t, totp.ValidateTime(time.Unix(1234567890, 0), "TK4289005924"))
+	assert.False(t, totp.Validate(1234567890, "XX4289005924"))
+	assert.False(t, totp.Validate(1234567890, "89005924"))
+	assert.False(t, totp.Validate(1234567890, "TK42"))
+	assert.Equal(t, []int{0}, totp.ValidateAllOffsets(1234567890, "TK4289005924"))
+	assert.Nil(t, totp.ValidateAllOffsets(1234567890, "89005924"))
+	_, match = totp.ValidateAt([]int64{1234567890}, "TK4289005924")
+	assert.True(t, match)
+	_, match = totp.ValidateAt([]int64{1234567890}, "89005924")
+	assert.False(t, match)
+}
+
+func TestWithLenientInput(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	hotp, err := NewHOTP(HashAlgorithmSHA1, secret, 6)
+	assert.NoError(t, err)
+	assert.False(t, hotp.Validate(0, "755 224"))
+
+	hotp, err = NewHOTP(HashAlgorithmSHA1, secret, 6, WithLenientInput())
+	assert.NoError(t, err)
+	for _, code := range []string{"755224", "755 224", "755-224", " 755224\n", "\t755 - 224 ", "7 5 5 2 2 4"} {
+		assert.True(t, hotp.Validate(0, code), code)
+	}
+	for _, code := range []string{"55224", " 55 224", "0755224", "755 2245", "755_224", "", " - "} {
+		assert.False(t, hotp.Validate(0, code), code)
+	}
+
+	hotp, err = NewHOTP(HashAlgorithmSHA1, secret, 6, WithLenientInput(), WithExpectedPrefix("TK-"))
+	assert.NoError(t, err)
+	assert.True(t, hotp.Validate(0, " TK-755 224"))
+	assert.True(t, hotp.Validate(0, "TK- 755-224"))
+	assert.False(t, hotp.Validate(0, "TK755224"))
+	assert.False(t, hotp.Validate(0, "T K-755224"))
+
+	totp, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 1, 0, WithLenientInput())
+	assert.NoError(t, err)
+	assert.True(t, totp.Validate(1234567890, "8900 5924"))
+	assert.True(t, totp.Validate(1234567920, "8900-5924 "))
+	assert.True(t, totp.ValidateTime(time.Unix(1234567890, 0), " 8900 5924"))
+	assert.False(t, totp.Validate(1234567890, "900 5924"))
+}
+
+func TestWithSecretDerivedT0(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 0, 0, WithSecretDerivedT0(true))
+	assert.NoError(t, err)
+	// T0 derived from the secret is 13, so steps start 13 seconds after the standard boundaries.
+	assert.Equal(t, "89005924", generator.Generate(1234567890+13))
+	assert.Equal(t, "89005924", generator.Generate(1234567890+42))
+	assert.NotEqual(t, "89005924", generator.Generate(1234567890+12))
+	assert.NotEqual(t, "89005924", generator.Generate(1234567890+43))
+	assert.True(t, generator.Validate(1234567890+13, "89005924"))
+	assert.False(t, generator.Validate(1234567890, "89005924"))
+	assert.Equal(t, int64(1234567890+43), generator.NextChangeTime(1234567890+13))
+	assert.Equal(t, int64(1234567890+42), generator.AcceptedUntil(1234567890+13))
+	assert.Contains(t, generator.CompatibilityReport(), "secret-derived T0 is not supported by authenticator apps")
+
+	again, _ := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 0, 0, WithSecretDerivedT0(true))
+	assert.Equal(t, generator.Generate(1234567890), again.Generate(1234567890))
+
+	standard, _ := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 0, 0, WithSecretDerivedT0(false))
+	assert.Equal(t, "89005924", standard.Generate(1234567890))
+	assert.NotContains(t, standard.CompatibilityReport(), "secret-derived T0 is not supported by authenticator apps")
+}
+
+func TestWithClock(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	var reads int
+	now := time.Unix(1234567890, 0)
+	clock := func() time.Time {
+		reads += 1
+		return now
+	}
+	generator, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 1, 1, WithClock(clock))
+	assert.NoError(t, err)
+	assert.Equal(t, "89005924", generator.GenerateNow())
+	assert.Equal(t, 1, reads)
+
+	reads = 0
+	assert.True(t, generator.ValidateNow("89005924"))
+	assert.Equal(t, 1, reads)
+	now = now.Add(30 * time.Second)
+	assert.True(t, generator.ValidateNow("89005924"))
+	now = now.Add(30 * time.Second)
+	assert.False(t, generator.ValidateNow("89005924"))
+	assert.Equal(t, 3, reads)
+
+	// Methods taking an explicit epoch do not read the clock.
+	reads = 0
+	assert.True(t, generator.Validate(1234567890, "89005924"))
+	assert.Equal(t, 0, reads)
+
+	_, err = NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 1, 1, WithClock(nil))
+	assert.EqualError(t, err, "invalid clock")
+
+	// The look-backward value covers a step boundary passing between the two calls.
+	generator, err = NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 1, 0)
+	assert.NoError(t, err)
+	assert.True(t, generator.ValidateNow(generator.GenerateNow()))
+}
+
+func TestWithChecksum(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	hotp, err := NewHOTP(HashAlgorithmSHA1, secret, 6, WithChecksum(true))
+	assert.NoError(t, err)
+	for counter, expected := range []string{"7552243", "2870822", "3591526", "9694290", "3383148", "2546760",
+		"2879229", "1625839", "3998713", "5204896"} {
+		assert.Equal(t, expected, hotp.Generate(int64(counter)))
+		assert.True(t, hotp.Validate(int64(counter), expected))
+	}
+	assert.False(t, hotp.Validate(0, "755224"))
+	assert.False(t, hotp.Validate(0, "7552240"))
+	// A single mistyped digit changes the checksum.
+	assert.False(t, hotp.Validate(0, "7562243"))
+	match, canonical := hotp.ValidateCanonical(0, "755 2243")
+	assert.True(t, match)
+	assert.Equal(t, "7552243", canonical)
+	assert.True(t, hotp.ValidateAutoDigits(0, "7552243", []int{6}))
+	assert.False(t, hotp.ValidateAutoDigits(0, "7552243", []int{7}))
+
+	totp, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 1, 0, WithChecksum(true))
+	assert.NoError(t, err)
+	assert.Equal(t, "890059249", totp.Generate(1234567890))
+	assert.True(t, totp.Validate(1234567890, "890059249"))
+	assert.True(t, totp.ValidateAutoDigits(1234567890, "890059249", []int{8}))
+	assert.False(t, totp.Validate(1234567890, "89005924"))
+	assert.False(t, totp.Validate(1234567890, "890159249"))
+
+	hotp, err = NewHOTP(HashAlgorithmSHA1, secret, 6, WithChecksum(false))
+	assert.NoError(t, err)
+	assert.Equal(t, "755224", hotp.Generate(0))
+
+	_, err = NewHOTP(HashAlgorithmSHA1, secret, 6, WithChecksum(true), WithEncoder(HexEncoder))
+	assert.EqualError(t, err, "checksum requires decimal codes")
+	_, err = NewTOTP(HashAlgorithmSHA1, secret, 6, 30, 0, 0, WithEncoder(HexEncoder), WithChecksum(true))
+	assert.EqualError(t, err, "checksum requires decimal codes")
+}
+
+func TestWithTruncationOffset(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	hotp, err := NewHOTP(HashAlgorithmSHA1, secret, 6, WithTruncationOffset(4))
+	assert.NoError(t, err)
+	// HMAC results of counters 0 to 2 are listed in appendix D of RFC 4226.
+	for counter, expected := range []string{"455891", "647552", "359152"} {
+		assert.Equal(t, expected, hotp.Generate(int64(counter)))
+		assert.True(t, hotp.Validate(int64(counter), expected))
+	}
+	assert.False(t, hotp.Validate(1, "287082"))
+
+	hotp, err = NewHOTP(HashAlgorithmSHA1, secret, 6, WithTruncationOffset(0))
+	assert.NoError(t, err)
+	assert.Equal(t, "755224", hotp.Generate(0))
+	assert.Equal(t, "717529", hotp.Generate(1))
+
+	totp, err := NewTOTP(HashAlgorithmSHA1, secret, 6, 30, 0, 0, WithTruncationOffset(16))
+	assert.NoError(t, err)
+	assert.Equal(t, "782699", totp.Generate(30))
+
+	for _, testCase := range []struct {
+		HashAlgorithm HashAlgorithm
+		Offset        int
+		Max           int
+	}{
+		{HashAlgorithmSHA1, -1, 16},
+		{HashAlgorithmSHA1, 17, 16},
+		{HashAlgorithmSHA256, 29, 28},
+		{HashAlgorithmSHA512, 61, 60},
+	} {
+		_, err := NewHOTP(testCase.HashAlgorithm, secret, 6, WithTruncationOffset(testCase.Offset))
+		assert.Equal(t, &ParamError{Param: "truncationOffset", Value: testCase.Offset, Min: 0, Max: testCase.Max}, err)
+		assert.EqualError(t, err, "invalid truncation offset")
+	}
+	_, err = NewHOTP(HashAlgorithmSHA512, secret, 6, WithTruncationOffset(60))
+	assert.NoError(t, err)
+}
+
+func TestWithPadding(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	for _, testCase := range []struct {
+		Padding  Padding
+		Expected string
+	}{
+		{PaddingZero, "07081804"},
+		{PaddingNone, "7081804"},
+		{PaddingSpace, " 7081804"},
+	} {
+		totp, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 0, 0, WithPadding(testCase.Padding))
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.Expected, totp.Generate(1111111109))
+		assert.Equal(t, "89005924", totp.Generate(1234567890))
+		assert.True(t, totp.Validate(1111111109, testCase.Expected))
+		valid, err := totp.ValidateStrict(1111111109, testCase.Expected)
+		assert.NoError(t, err)
+		assert.True(t, valid)
+	}
+
+	// Codes without padding are validated by their numeric value.
+	totp, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 0, 0, WithPadding(PaddingNone))
+	assert.NoError(t, err)
+	for _, code := range []string{"7081804", "07081804", " 7081804"} {
+		assert.True(t, totp.Validate(1111111109, code), code)
+	}
+	for _, code := range []string{"", " ", "0", "708180", "70818040", "007081804", "17081804"} {
+		assert.False(t, totp.Validate(1111111109, code), code)
+	}
+	_, err = totp.ValidateStrict(1111111109, "007081804")
+	assert.Equal(t, ErrMalformedCode, err)
+	_, err = totp.ValidateStrict(1111111109, "   ")
+	assert.Equal(t, ErrMalformedCode, err)
+	assert.Contains(t, totp.CompatibilityReport(), "codes without leading zeros are not supported by authenticator apps")
+
+	// Zero padding stays strict about the length.
+	totp, err = NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 0, 0)
+	assert.NoError(t, err)
+	assert.False(t, totp.Validate(1111111109, "7081804"))
+	assert.False(t, totp.Validate(1111111109, " 7081804"))
+
+	// A code of only zeros keeps its last zero.
+	hotp, err := NewHOTP(HashAlgorithmSHA1, secret, 1, WithPadding(PaddingSpace))
+	assert.NoError(t, err)
+	hotp.(*hotpManager).padding = PaddingNone
+	assert.Equal(t, "0", hotp.(*hotpManager).pad("000"))
+	hotp.(*hotpManager).padding = PaddingSpace
+	assert.Equal(t, "  0", hotp.(*hotpManager).pad("000"))
+
+	_, err = NewHOTP(HashAlgorithmSHA1, secret, 6, WithPadding(Padding(3)))
+	assert.EqualError(t, err, "invalid padding")
+	_, err = NewHOTP(HashAlgorithmSHA1, secret, 6, WithPadding(Padding(-1)))
+	assert.EqualError(t, err, "invalid padding")
+	_, err = NewHOTP(HashAlgorithmSHA1, secret, 6, WithEncoder(HexEncoder), WithPadding(PaddingNone))
+	assert.EqualError(t, err, "padding requires decimal codes")
+}
+
+func TestWithCounterBytes(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	standard, err := NewHOTP(HashAlgorithmSHA1, secret, 6)
+	assert.NoError(t, err)
+	legacy, err := NewHOTP(HashAlgorithmSHA1, secret, 6, WithCounterBytes(4))
+	assert.NoError(t, err)
+	for counter, expected := range []string{"613114", "675152", "823798"} {
+		assert.Equal(t, expected, legacy.Generate(int64(counter)))
+		assert.NotEqual(t, standard.Generate(int64(counter)), legacy.Generate(int64(counter)))
+		assert.True(t, legacy.Validate(int64(counter), expected))
+		assert.False(t, standard.Validate(int64(counter), expected))
+	}
+	assert.Contains(t, legacy.CompatibilityReport(), "4-byte counters are not supported by authenticator apps")
+
+	// Counters that do not fit in 4 bytes have no code rather than being truncated.
+	assert.NotEmpty(t, legacy.Generate(math.MaxUint32))
+	assert.Equal(t, "", legacy.Generate(math.MaxUint32+1))
+	assert.False(t, legacy.Validate(math.MaxUint32+1, "613114"))
+
+	standard, err = NewHOTP(HashAlgorithmSHA1, secret, 6, WithCounterBytes(8))
+	assert.NoError(t, err)
+	assert.Equal(t, "755224", standard.Generate(0))
+
+	totp, err := NewTOTP(HashAlgorithmSHA1, secret, 6, 30, 0, 0, WithCounterBytes(4))
+	assert.NoError(t, err)
+	assert.Equal(t, "675152", totp.Generate(30))
+
+	for _, n := range []int{-1, 0, 2, 5, 16} {
+		_, err = NewHOTP(HashAlgorithmSHA1, secret, 6, WithCounterBytes(n))
+		assert.EqualError(t, err, "invalid counter bytes")
+	}
+}
+
+func TestMinSecretLength(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	_, err := NewHOTP(HashAlgorithmSHA1, secret, 6)
+	assert.NoError(t, err)
+	_, err = NewTOTP(HashAlgorithmSHA1, secret[:16], 6, 30, 0, 0)
+	assert.NoError(t, err)
+
+	for _, short := range [][]byte{secret[:8], secret[:15], {}} {
+		_, err = NewHOTP(HashAlgorithmSHA1, short, 6)
+		assert.EqualError(t, err, "secret too short")
+		_, err = NewTOTP(HashAlgorithmSHA1, short, 6, 30, 0, 0)
+		assert.EqualError(t, err, "secret too short")
+	}
+
+	hotp, err := NewHOTP(HashAlgorithmSHA1, secret[:8], 6, AllowShortSecret())
+	assert.NoError(t, err)
+	assert.Len(t, hotp.Secret(), 8)
+	_, err = NewTOTP(HashAlgorithmSHA1, secret[:1], 6, 30, 0, 0, AllowShortSecret())
+	assert.NoError(t, err)
+	_, err = NewHOTP(HashAlgorithmSHA1, []byte{}, 6, AllowShortSecret())
+	assert.EqualError(t, err, "secret too short")
+
+	_, err = NewHOTP(HashAlgorithmSHA1, secret, 6, WithMinSecretLength(32))
+	assert.EqualError(t, err, "secret too short")
+	hotp, err = NewHOTP(HashAlgorithmSHA1, nil, 6, WithMinSecretLength(32))
+	assert.NoError(t, err)
+	assert.Len(t, hotp.Secret(), 20)
+	_, err = NewHOTP(HashAlgorithmSHA1, secret, 6, WithMinSecretLength(0))
+	assert.Equal(t, &ParamError{Param: "minSecretLength", Value: 0, Min: 1, Max: math.MaxInt}, err)
+
+	// Secret keys that have already been issued are accepted when parsing.
+	manager, err := ParseURI("otpauth://totp/alice?secret=GEZDGNBVGY3TQOJQ")
+	assert.NoError(t, err)
+	assert.Len(t, manager.Secret(), 10)
+	manager, err = ParseJSON([]byte(`{"type":"hotp","algorithm":"SHA1","digits":6,"secret":"GEZDGNBV"}`))
+	assert.NoError(t, err)
+	assert.Len(t, manager.Secret(), 5)
+}
+
+func TestWithRandomReader(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	hotp, err := NewHOTP(HashAlgorithmSHA1, nil, 6, WithRandomReader(bytes.NewReader(secret)))
+	assert.NoError(t, err)
+	assert.Equal(t, secret, hotp.Secret())
+	assert.Equal(t, "755224", hotp.Generate(0))
+	totp, err := NewTOTP(HashAlgorithmSHA1, nil, 8, 30, 0, 0, WithRandomReader(bytes.NewReader(secret)))
+	assert.NoError(t, err)
+	assert.Equal(t, "89005924", totp.Generate(1234567890))
+
+	// The reader is not used when a secret key is provided.
+	hotp, err = NewHOTP(HashAlgorithmSHA1, secret, 6, WithRandomReader(bytes.NewReader(nil)))
+	assert.NoError(t, err)
+	assert.Equal(t, secret, hotp.Secret())
+
+	_, err = NewHOTP(HashAlgorithmSHA1, nil, 6, WithRandomReader(bytes.NewReader(secret[:19])))
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+	_, err = NewTOTP(HashAlgorithmSHA256, nil, 6, 30, 0, 0, WithRandomReader(bytes.NewReader(secret)))
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+	_, err = NewHOTP(HashAlgorithmSHA1, nil, 6, WithRandomReader(bytes.NewReader(nil)))
+	assert.Equal(t, io.EOF, err)
+	_, err = NewHOTP(HashAlgorithmSHA1, nil, 6, WithRandomReader(nil))
+	assert.EqualError(t, err, "invalid random reader")
+}
+
+func TestWithGeneratedSecretSize(t *testing.T) {
+	for _, size := range []int{16, 20, 48, 128} {
+		hotp, err := NewHOTP(HashAlgorithmSHA1, nil, 6, WithGeneratedSecretSize(size))
+		assert.NoError(t, err)
+		assert.Len(t, hotp.Secret(), size)
+		totp, err := NewTOTP(HashAlgorithmSHA512, nil, 6, 30, 0, 0, WithGeneratedSecretSize(size))
+		assert.NoError(t, err)
+		assert.Len(t, totp.Secret(), size)
+	}
+
+	// The size is read from the random source, and provided secret keys are kept as they are.
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	hotp, err := NewHOTP(HashAlgorithmSHA256, nil, 6, WithGeneratedSecretSize(20),
+		WithRandomReader(bytes.NewReader(secret)))
+	assert.NoError(t, err)
+	assert.Equal(t, secret, hotp.Secret())
+	hotp, err = NewHOTP(HashAlgorithmSHA1, secret, 6, WithGeneratedSecretSize(64))
+	assert.NoError(t, err)
+	assert.Equal(t, secret, hotp.Secret())
+
+	for _, size := range []int{-1, 0, 15} {
+		_, err = NewHOTP(HashAlgorithmSHA1, nil, 6, WithGeneratedSecretSize(size))
+		assert.Equal(t, &ParamError{Param: "secretSize", Value: size, Min: 16, Max: math.MaxInt}, err)
+	}
+}
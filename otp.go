@@ -3,16 +3,26 @@
 package otp
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha1"
 	"crypto/sha256"
+	"crypto/sha3"
 	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base32"
 	"encoding/binary"
 	"errors"
-	"fmt"
 	"hash"
+	"io"
 	"math"
+	"math/bits"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
 )
 
 // HashAlgorithm identifies the hash algorithm used for HMAC.
@@ -27,13 +37,86 @@ const (
 
 	// HashAlgorithmSHA512 represents SHA512 algorithm.
 	HashAlgorithmSHA512
+
+	// HashAlgorithmSHA3_256 represents SHA3-256 algorithm. It is not defined by RFC 6238, and is not supported by
+	// authenticator apps.
+	HashAlgorithmSHA3_256
+
+	// HashAlgorithmSHA3_512 represents SHA3-512 algorithm. It is not defined by RFC 6238, and is not supported by
+	// authenticator apps.
+	HashAlgorithmSHA3_512
+
+	// HashAlgorithmSHA224 represents SHA224 algorithm, the truncated variant of SHA256 preferred by some FIPS-oriented
+	// environments. It is not defined by RFC 6238, and is not supported by authenticator apps.
+	HashAlgorithmSHA224
+
+	// HashAlgorithmSHA384 represents SHA384 algorithm, the truncated variant of SHA512 preferred by some FIPS-oriented
+	// environments. It is not defined by RFC 6238, and is not supported by authenticator apps.
+	HashAlgorithmSHA384
 )
 
+// OTPType identifies whether a manager generates HMAC-based or time-based one-time passwords.
+type OTPType int
+
 const (
-	// maxCodeDigits represents maximum digits of password code.
-	maxCodeDigits = 8
+	// OTPTypeHOTP represents HMAC-based one-time passwords, as defined by RFC 4226.
+	OTPTypeHOTP OTPType = iota
+
+	// OTPTypeTOTP represents time-based one-time passwords, as defined by RFC 6238.
+	OTPTypeTOTP
+)
+
+// String gets the name of the type used in key URIs, "hotp" or "totp", or "unknown" for unknown types.
+func (otpType OTPType) String() string {
+	switch otpType {
+	case OTPTypeHOTP:
+		return "hotp"
+	case OTPTypeTOTP:
+		return "totp"
+	default:
+		return "unknown"
+	}
+}
+
+// ErrAmbiguousCode is returned when a one-time password matches more than one counter in the searched window.
+var ErrAmbiguousCode = errors.New("code matches multiple counters")
+
+const (
+	// MaxCodeDigits is the maximum digit count of password codes, for checking input before creating a manager.
+	// Truncated values are below 2^31, which has 10 decimal digits, so longer codes would only add leading zeros.
+	MaxCodeDigits = 10
+
+	// minSecretLength represents the default minimum length in bytes of provided secret keys, which is the 128 bits
+	// required by section 4 of RFC 4226.
+	minSecretLength = 16
+
+	// maxRangeSteps represents maximum number of time steps scanned when validating against a range of epochs.
+	maxRangeSteps = 1000
+
+	// truncationMask represents the mask applied to the 4 bytes selected by dynamic truncation. It clears the most
+	// significant bit so that the result is the same whether it is treated as a signed or an unsigned integer, as
+	// required by RFC 4226. The mask does not depend on the code digits: codes of every length are derived from the
+	// same 31-bit value.
+	truncationMask = 0x7fffffff
+
+	// dynamicTruncation represents the truncation offset of managers using dynamic truncation, where the offset is
+	// selected by the HMAC result itself.
+	dynamicTruncation = -1
 )
 
+// powersOfTen contains the powers of ten up to the maximum code digits, which are the moduli used to reduce truncated
+// values to code digits. Integer moduli avoid the floating-point math.Pow10 in the generation path.
+var powersOfTen = func() (powers [MaxCodeDigits + 1]uint64) {
+	powers[0] = 1
+	for i := 1; i < len(powers); i += 1 {
+		powers[i] = powers[i-1] * 10
+	}
+	return
+}()
+
+// secretEncoding represents the base32 encoding of secret keys used by authenticator apps.
+var secretEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
 // hash gets the hash function specified by the algorithm enum.
 func (algorithm HashAlgorithm) hash() (func() hash.Hash, error) {
 	switch algorithm {
@@ -43,30 +126,119 @@ func (algorithm HashAlgorithm) hash() (func() hash.Hash, error) {
 		return sha256.New, nil
 	case HashAlgorithmSHA512:
 		return sha512.New, nil
+	case HashAlgorithmSHA3_256:
+		return func() hash.Hash { return sha3.New256() }, nil
+	case HashAlgorithmSHA3_512:
+		return func() hash.Hash { return sha3.New512() }, nil
+	case HashAlgorithmSHA224:
+		return sha256.New224, nil
+	case HashAlgorithmSHA384:
+		return sha512.New384, nil
 	default:
-		return nil, errors.New("unknown hash algorithm")
+		return nil, ErrUnknownAlgorithm
+	}
+}
+
+// name gets the algorithm name used in query parameters.
+func (algorithm HashAlgorithm) name() (string, error) {
+	switch algorithm {
+	case HashAlgorithmSHA1:
+		return "SHA1", nil
+	case HashAlgorithmSHA256:
+		return "SHA256", nil
+	case HashAlgorithmSHA512:
+		return "SHA512", nil
+	case HashAlgorithmSHA3_256:
+		return "SHA3-256", nil
+	case HashAlgorithmSHA3_512:
+		return "SHA3-512", nil
+	case HashAlgorithmSHA224:
+		return "SHA224", nil
+	case HashAlgorithmSHA384:
+		return "SHA384", nil
+	default:
+		return "", ErrUnknownAlgorithm
+	}
+}
+
+// parseAlgorithmName gets the algorithm from its name in query parameters. Names are matched case-insensitively.
+func parseAlgorithmName(name string) (HashAlgorithm, error) {
+	for _, algorithm := range []HashAlgorithm{HashAlgorithmSHA1, HashAlgorithmSHA256, HashAlgorithmSHA512,
+		HashAlgorithmSHA3_256, HashAlgorithmSHA3_512, HashAlgorithmSHA224, HashAlgorithmSHA384} {
+		if algorithmName, _ := algorithm.name(); strings.EqualFold(name, algorithmName) {
+			return algorithm, nil
+		}
+	}
+	return 0, ErrUnknownAlgorithm
+}
+
+// standard checks whether the algorithm is defined by RFC 6238, and may therefore be emitted in key URIs.
+func (algorithm HashAlgorithm) standard() bool {
+	return algorithm == HashAlgorithmSHA1 || algorithm == HashAlgorithmSHA256 || algorithm == HashAlgorithmSHA512
+}
+
+// String gets the name of the algorithm, such as "SHA1", or "unknown" for unknown algorithms.
+func (algorithm HashAlgorithm) String() string {
+	name, err := algorithm.name()
+	if err != nil {
+		return "unknown"
+	}
+	return name
+}
+
+// MarshalText encodes the algorithm as its name, such as "SHA1", so that it is readable in configuration files. An
+// error is returned for unknown algorithms.
+func (algorithm HashAlgorithm) MarshalText() ([]byte, error) {
+	name, err := algorithm.name()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(name), nil
+}
+
+// UnmarshalText decodes the algorithm from its name, which is matched case-insensitively.
+func (algorithm *HashAlgorithm) UnmarshalText(text []byte) error {
+	parsed, err := parseAlgorithmName(string(text))
+	if err != nil {
+		return err
 	}
+	*algorithm = parsed
+	return nil
 }
 
-// DefaultKeyByteSize gets the default value of HMAC key size in bytes.
+// DefaultKeyByteSize gets the default value of HMAC key size in bytes, which is the output size of the hash function
+// as recommended by RFC 4226. It can be used for checking externally supplied secret keys before creating a manager.
+// An error is returned for unknown algorithms.
 func (algorithm HashAlgorithm) DefaultKeyByteSize() (int, error) {
 	switch algorithm {
 	case HashAlgorithmSHA1:
 		return 20, nil
-	case HashAlgorithmSHA256:
+	case HashAlgorithmSHA224:
+		return 28, nil
+	case HashAlgorithmSHA256, HashAlgorithmSHA3_256:
 		return 32, nil
-	case HashAlgorithmSHA512:
+	case HashAlgorithmSHA384:
+		return 48, nil
+	case HashAlgorithmSHA512, HashAlgorithmSHA3_512:
 		return 64, nil
 	default:
-		return 0, errors.New("unknown hash algorithm")
+		return 0, ErrUnknownAlgorithm
 	}
 }
 
-// generateSecret generates a new secret key.
-func (algorithm HashAlgorithm) generateSecret() ([]byte, error) {
-	keyByteSize, _ := algorithm.DefaultKeyByteSize()
+// generateSecret generates a new secret key of the specified size read from the random source, or of the default key
+// size of the algorithm if the size is 0. A short read is reported as an error rather than resulting in a truncated
+// secret key.
+func (algorithm HashAlgorithm) generateSecret(random io.Reader, size int) ([]byte, error) {
+	keyByteSize, err := algorithm.DefaultKeyByteSize()
+	if err != nil {
+		return nil, err
+	}
+	if size != 0 {
+		keyByteSize = size
+	}
 	secret := make([]byte, keyByteSize)
-	_, err := rand.Read(secret)
+	_, err = io.ReadFull(random, secret)
 	if err != nil {
 		return nil, err
 	}
@@ -74,6 +246,21 @@ func (algorithm HashAlgorithm) generateSecret() ([]byte, error) {
 }
 
 // OTPManager represents an HMAC-based or time-based one-time password generator and validator.
+//
+// The interface only has the methods every one-time password manager provides, so that other implementations, such as
+// the Mobile-OTP manager or mocks in tests, can satisfy it. The other features are provided by Manager, HOTPManager
+// and TOTPManager.
+//
+// Validating methods treat malformed codes, such as codes of the wrong length or with characters other than digits,
+// exactly like well-formed codes that do not match: they report a mismatch rather than an error. Otherwise, an attacker
+// could learn the expected code length by telling errors and mismatches apart. ValidateStrict is the exception, for
+// login pages that want to tell users about malformed codes.
+//
+// Managers are safe for concurrent use by multiple goroutines, so a single manager can be shared by all requests. The
+// settings never change after construction, every call computes codes with its own HMAC state, and the little mutable
+// state of optional features, such as the skew histogram and the used codes of ValidateOnce, is guarded by a mutex.
+// Encoders, clocks and used code stores passed as options must be safe for concurrent use as well. Close is the only
+// exception, and must only be called once the manager is no longer in use.
 type OTPManager interface {
 	// Generate generates the one-time password with the specified moving factor.
 	Generate(int64) string
@@ -82,75 +269,842 @@ type OTPManager interface {
 	Validate(int64, string) bool
 }
 
+// Manager represents the methods shared by HOTP and TOTP managers. Functions creating managers of either type, such as
+// ParseURI and ParseJSON, return a Manager, whose Type tells whether it is an HOTPManager or a TOTPManager.
+type Manager interface {
+	OTPManager
+
+	// GenerateRaw gets the truncated 31-bit value of the HMAC result with the specified moving factor.
+	GenerateRaw(int64) uint32
+
+	// GenerateCode generates the one-time password with the specified moving factor as a Code.
+	GenerateCode(int64) Code
+
+	// GenerateError generates the one-time password with the specified moving factor, and gets any error that occurred.
+	GenerateError(int64) (string, error)
+
+	// GenerateRange generates the one-time passwords for the specified number of consecutive moving factors starting at
+	// the specified one.
+	GenerateRange(int64, int64) []string
+
+	// ValidateAutoDigits validates whether the one-time password matches, using its length as the code digits when
+	// the length is among the allowed ones.
+	ValidateAutoDigits(int64, string, []int) bool
+
+	// ChallengeResponse computes the response to a challenge with the specified moving factor.
+	ChallengeResponse(int64, string) string
+
+	// ValidateChallengeResponse validates whether the response to a challenge matches.
+	ValidateChallengeResponse(int64, string, string) bool
+
+	// ValidateStrict validates whether the one-time password matches, and reports codes that could never match with
+	// ErrMalformedCode.
+	ValidateStrict(int64, string) (bool, error)
+
+	// ValidateCanonical validates whether the one-time password matches after normalizing it, and gets the canonical
+	// form of the matching code.
+	ValidateCanonical(int64, string) (bool, string)
+
+	// QueryParams gets the parameters of the manager as URL query values, with the secret key encoded in base32.
+	QueryParams(...URIOption) url.Values
+
+	// ProvisioningURI gets the key URI of the manager for provisioning authenticator apps.
+	ProvisioningURI(string, string, ...URIOption) (string, error)
+
+	// CompatibilityReport gets warnings about settings that mainstream authenticator apps may not support.
+	CompatibilityReport() []string
+
+	// CheckSecret gets warnings about the length of the secret key.
+	CheckSecret() []SecretWarning
+
+	// Secret gets a copy of the secret key.
+	Secret() []byte
+
+	// Close overwrites the secret key with zeros and makes the manager unusable.
+	Close() error
+
+	// SecretBase32 gets the secret key encoded in base32 without padding.
+	SecretBase32() string
+
+	// SecretBase32Grouped gets the secret key encoded in base32, in lowercase groups of four characters.
+	SecretBase32Grouped() string
+
+	// Algorithm gets the hash algorithm used for HMAC.
+	Algorithm() HashAlgorithm
+
+	// CodeDigits gets the digit count of password codes.
+	CodeDigits() int
+
+	// Type gets whether the manager is an HOTPManager or a TOTPManager.
+	Type() OTPType
+}
+
+// HOTPManager represents an HMAC-based one-time password generator and validator.
+type HOTPManager interface {
+	Manager
+
+	// QueryParamsAt gets the parameters of the manager as URL query values, with the specified counter.
+	QueryParamsAt(int64, ...URIOption) (url.Values, error)
+
+	// SearchCounter searches the counters from the specified one up to the window for the one-time password.
+	SearchCounter(int64, string, int) (int64, bool, error)
+
+	// ValidateWindow validates whether the one-time password matches any counter from the specified one up to the window,
+	// and gets the matching counter.
+	ValidateWindow(int64, string, int) (int64, bool)
+
+	// Resynchronize searches the counters from the specified one up to the window for the one-time password, and gets
+	// the counter following the matching one.
+	Resynchronize(int64, string, int) (int64, bool)
+
+	// RecoverySheet generates consecutive codes paired with their counters.
+	RecoverySheet(int64, int64) ([]IndexedCode, error)
+
+	// Next generates the one-time password of the counter held by the manager, and advances the counter.
+	Next() string
+
+	// Counter gets the counter held by the manager.
+	Counter() int64
+}
+
+// TOTPManager represents a time-based one-time password generator and validator.
+//
+// Time step N covers epochs from N*timeStep inclusive to (N+1)*timeStep exclusive, so an epoch exactly at a step
+// boundary belongs to the step that starts there. All methods follow this convention.
+//
+// Time steps before 0, such as those of epochs before T0, or those looked backward from the first time steps, have no
+// code, since the counter of RFC 4226 is unsigned: generating for them fails, and tolerant time steps before 0 are
+// skipped when validating.
+type TOTPManager interface {
+	Manager
+
+	// MovingFactor gets the time step the specified epoch belongs to.
+	MovingFactor(int64) int64
+
+	// TimeStep gets the time step in seconds, or 0 if it is not a whole number of seconds.
+	TimeStep() int
+
+	// TimeStepDuration gets the time step as a duration.
+	TimeStepDuration() time.Duration
+
+	// LookBackward gets the tolerant time steps backward accepted when validating.
+	LookBackward() int
+
+	// LookForward gets the tolerant time steps forward accepted when validating.
+	LookForward() int
+
+	// RemainingSeconds gets the number of seconds until the time step of the specified epoch ends.
+	RemainingSeconds(int64) int
+
+	// NextChangeTime gets the epoch at which the next time step starts.
+	NextChangeTime(int64) int64
+
+	// GenerateTime generates the one-time password for the specified time.
+	GenerateTime(time.Time) string
+
+	// ValidateTime validates whether the one-time password matches the specified time.
+	ValidateTime(time.Time, string) bool
+
+	// GenerateNow generates the one-time password for the current time read from the clock of the manager.
+	GenerateNow() string
+
+	// ValidateNow validates whether the one-time password matches the current time read from the clock of the manager.
+	ValidateNow(string) bool
+
+	// ValidateContext validates whether the one-time password matches, and gives up once the context is done.
+	ValidateContext(context.Context, int64, string) (bool, error)
+
+	// ValidateOnce validates whether the one-time password matches, and rejects codes that have already been accepted.
+	ValidateOnce(int64, string) bool
+
+	// ValidateWithSkew validates whether the one-time password matches, and gets the offset in time steps of the matching
+	// time step.
+	ValidateWithSkew(int64, string) (bool, int)
+
+	// ValidateWithRefreshHint validates whether the one-time password matches, and gets the number of seconds the
+	// matching code remains the current one.
+	ValidateWithRefreshHint(int64, string) (bool, int)
+
+	// ValidateWithExpiry validates whether the one-time password matches, and gets the number of seconds left in the
+	// time step of the matching code.
+	ValidateWithExpiry(int64, string) (bool, int)
+
+	// SkewHistogram gets the number of successful validations at each time step offset.
+	SkewHistogram() map[int]int64
+
+	// SessionOTP derives a manager whose codes are only valid within the specified session.
+	SessionOTP([]byte) (TOTPManager, error)
+
+	// Clone creates a copy of the manager with the specified options applied on top of its settings.
+	Clone(...TOTPOption) (TOTPManager, error)
+
+	// StepsUntil gets the number of full time steps that fit between two epochs.
+	StepsUntil(int64, int64) int64
+
+	// AcceptedUntil gets the last epoch at which the code of the time step of the specified epoch is still accepted.
+	AcceptedUntil(int64) int64
+
+	// AcceptedCodes gets the codes of every time step within the tolerant window of the specified epoch.
+	AcceptedCodes(int64) []string
+
+	// ValidateWithClockError validates whether the one-time password matches, with the tolerant window widened by the
+	// specified maximum clock error.
+	ValidateWithClockError(int64, string, time.Duration) bool
+
+	// ValidateAllOffsets gets the offsets of all time steps within the tolerant window matching the one-time password.
+	ValidateAllOffsets(int64, string) []int
+
+	// ValidateConsecutive validates whether two one-time passwords match two consecutive time steps within the tolerant
+	// window.
+	ValidateConsecutive(int64, string, string) bool
+
+	// Challenge gets the current time step for the server to send as a challenge.
+	Challenge() (int64, error)
+
+	// Respond generates the one-time password of the time step sent as a challenge.
+	Respond(int64) string
+
+	// Verify validates whether the one-time password matches the time step sent as a challenge.
+	Verify(int64, string) bool
+
+	// ValidateInRange validates whether the one-time password matches any time step between two epochs, and gets an
+	// epoch of the matching one.
+	ValidateInRange(int64, int64, string) (bool, int64, error)
+
+	// ValidateAt validates whether the one-time password matches any of the specified epochs, and gets the first
+	// matching one.
+	ValidateAt([]int64, string) (int64, bool)
+}
+
 // hotpManager represents an HMAC-based one-time password (HOTP) generator and validator.
 type hotpManager struct {
+	algorithm     HashAlgorithm
 	hashAlgorithm func() hash.Hash
 	secret        []byte
 	codeDigits    int
+	binding       []byte
+	encoder       Encoder
+	prefix        string
+	checksum      bool
+	offset        int
+	minSecret     int
+	random        io.Reader
+	secretSize    int
+	lenient       bool
+	counterBytes  int
+	counter       *hotpCounter
+	padding       Padding
 }
 
 // NewHOTP creates a new HMAC-based one-time password (HOTP) manager with specified hash algorithm, secret keys and
 // digit count of password codes.
 //
 // When provided secret key is nil, a new secret key will be generated with cryptographically secure pseudo-random
-// number generator provided by the operation system. By default, length of the secret key is 20 bytes for SHA1
-// algorithm, 32 bytes for SHA256 algorithm and 64 bytes for SHA512 algorithm.
+// number generator provided by the operation system, or read from the source set with WithRandomReader. By default,
+// length of the secret key is 20 bytes for SHA1 algorithm, 28 bytes for SHA224, 32 bytes for SHA256 and SHA3-256
+// algorithms, 48 bytes for SHA384 and 64 bytes for SHA512 and SHA3-512 algorithms, unless another size is set with
+// WithGeneratedSecretSize.
+//
+// Provided secret keys shorter than 16 bytes, the minimum required by RFC 4226, are rejected unless AllowShortSecret is
+// used. The manager keeps its own copy of the provided secret key, so that Close does not modify the caller's slice.
+//
+// Code digit cannot be longer than 10 digits. Out-of-range parameters are reported with a *ParamError.
 //
-// Code digit cannot be longer than 8 digits.
-func NewHOTP(algorithm HashAlgorithm, secret []byte, codeDigit int) (OTPManager, error) {
-	var generator hotpManager
+// Optional behavior can be configured with options.
+func NewHOTP(algorithm HashAlgorithm, secret []byte, codeDigit int, opts ...HOTPOption) (HOTPManager, error) {
+	generator, err := newHOTPManager(algorithm)
+	if err != nil {
+		return nil, err
+	}
+	generator.secret = secret
+
+	// Check code digits
+	if codeDigit <= 0 || codeDigit > MaxCodeDigits {
+		return nil, &ParamError{Param: "codeDigit", Value: codeDigit, Min: 1, Max: MaxCodeDigits}
+	}
+	generator.codeDigits = codeDigit
+
+	for _, opt := range opts {
+		if err := opt.applyHOTP(generator); err != nil {
+			return nil, err
+		}
+	}
+	if err := generator.complete(); err != nil {
+		return nil, err
+	}
+
+	return generator, nil
+}
 
-	// Check algorithm
+// newHOTPManager creates an HOTP manager with the specified hash algorithm and default settings, which are 6-digit
+// decimal codes with dynamic truncation. The secret key is left nil until complete is called.
+func newHOTPManager(algorithm HashAlgorithm) (*hotpManager, error) {
 	hashAlgorithm, err := algorithm.hash()
 	if err != nil {
 		return nil, err
 	}
-	generator.hashAlgorithm = hashAlgorithm
+	return &hotpManager{
+		algorithm:     algorithm,
+		hashAlgorithm: hashAlgorithm,
+		codeDigits:    6,
+		encoder:       DecimalEncoder,
+		offset:        dynamicTruncation,
+		minSecret:     minSecretLength,
+		random:        rand.Reader,
+		counterBytes:  8,
+		counter:       &hotpCounter{},
+	}, nil
+}
 
-	// Check secret key
-	if secret == nil {
-		generator.secret, err = algorithm.generateSecret()
+// complete generates a new secret key if none was provided, and checks whether the provided secret key is long enough
+// and the options can be combined. It is called once all parameters and options have been applied.
+func (generator *hotpManager) complete() error {
+	if generator.secret != nil && len(generator.secret) < generator.minSecret {
+		return errors.New("secret too short")
+	}
+	if generator.secret == nil {
+		secret, err := generator.algorithm.generateSecret(generator.random, generator.secretSize)
 		if err != nil {
-			return nil, err
+			return err
 		}
-	} else {
 		generator.secret = secret
+	} else {
+		generator.secret = append([]byte(nil), generator.secret...)
 	}
+	return generator.checkOptions()
+}
 
-	// Check code digits
-	if codeDigit <= 0 || codeDigit > maxCodeDigits {
-		return nil, errors.New("invalid code digit")
-	}
-	generator.codeDigits = codeDigit
+// Close overwrites the secret key of the manager with zeros and makes the manager unusable: Generate returns an empty
+// string, GenerateError and ProvisioningURI return an error and no code validates anymore. It always returns nil, and
+// closing a closed manager has no effect.
+//
+// Wiping is best-effort and only limits the time the secret key stays readable in a memory dump. Copies made before,
+// such as by the garbage collector moving memory, by Secret or by the caller keeping the slice passed to the
+// constructor, are not wiped. Close must not be called concurrently with other methods of the manager.
+func (generator *hotpManager) Close() error {
+	clear(generator.secret)
+	generator.secret = nil
+	return nil
+}
 
-	return &generator, nil
+// closed checks whether the manager has been closed. Secret keys are never nil otherwise.
+func (generator *hotpManager) closed() bool {
+	return generator.secret == nil
 }
 
 func (generator *hotpManager) Generate(movingFactor int64) string {
-	message := make([]byte, 8)
-	binary.BigEndian.PutUint64(message, uint64(movingFactor))
+	return generator.generate(movingFactor, generator.codeDigits)
+}
 
-	mac := hmac.New(generator.hashAlgorithm, generator.secret)
-	mac.Write(message)
-	hashResult := mac.Sum(nil)
+// GenerateError generates the one-time password with the specified moving factor like Generate, but gets any error
+// that occurred instead of ignoring it. Generate returns an empty string in that case.
+//
+// The moving factor is the 8-byte counter of RFC 4226, which is unsigned, so negative moving factors are rejected with
+// an error instead of wrapping around to huge counters. No code validates against a negative moving factor either.
+func (generator *hotpManager) GenerateError(movingFactor int64) (string, error) {
+	return generator.generateMessage(movingFactor, nil, generator.codeDigits)
+}
+
+// generate generates the one-time password with the specified moving factor and code digits, or an empty string if an
+// error occurred.
+func (generator *hotpManager) generate(movingFactor int64, codeDigits int) string {
+	code, _ := generator.generateMessage(movingFactor, nil, codeDigits)
+	return code
+}
+
+// message builds the HMAC message from the moving factor, the device identifier and the challenge.
+func (generator *hotpManager) message(movingFactor int64, challenge []byte) []byte {
+	message := make([]byte, generator.counterBytes, generator.counterBytes+len(generator.binding)+len(challenge))
+	if generator.counterBytes == 4 {
+		binary.BigEndian.PutUint32(message, uint32(movingFactor))
+	} else {
+		binary.BigEndian.PutUint64(message, uint64(movingFactor))
+	}
+	message = append(message, generator.binding...)
+	return append(message, challenge...)
+}
+
+// generateMessage generates the one-time password for the HMAC message built from the moving factor and the challenge
+// with the specified code digits. Negative moving factors are rejected.
+func (generator *hotpManager) generateMessage(movingFactor int64, challenge []byte, codeDigits int) (string, error) {
+	state := generator.newMAC()
+	if state == nil {
+		return "", errors.New("manager closed")
+	}
+	return generator.generateMAC(state, movingFactor, challenge, codeDigits)
+}
+
+// macState represents an HMAC state keyed with the secret key. The key setup is the costly part of computing an HMAC,
+// so methods computing several codes create the state once with newMAC, and pass it to generateMAC or validateMAC for
+// every moving factor. The state must not be shared between goroutines.
+type macState struct {
+	mac  hash.Hash
+	used bool
+}
+
+// newMAC creates an HMAC state keyed with the secret key, or gets nil if the manager is closed.
+func (generator *hotpManager) newMAC() *macState {
+	if generator.closed() {
+		return nil
+	}
+	return &macState{mac: hmac.New(generator.hashAlgorithm, generator.secret)}
+}
+
+// generateMAC generates the one-time password like generateMessage with an HMAC state created by newMAC, which is reset
+// first unless it has not been used yet.
+func (generator *hotpManager) generateMAC(state *macState, movingFactor int64, challenge []byte, codeDigits int) (string, error) {
+	hashResult, err := generator.hashMAC(state, movingFactor, challenge)
+	if err != nil {
+		return "", err
+	}
+	return generator.encodeHash(hashResult, codeDigits), nil
+}
+
+// hashMAC computes the HMAC result of the message built from the moving factor and the challenge with an HMAC state
+// created by newMAC, which is reset first unless it has not been used yet. Negative moving factors are rejected.
+func (generator *hotpManager) hashMAC(state *macState, movingFactor int64, challenge []byte) ([]byte, error) {
+	if movingFactor < 0 || generator.counterBytes == 4 && movingFactor > math.MaxUint32 {
+		return nil, errors.New("invalid counter")
+	}
+	if state.used {
+		state.mac.Reset()
+	}
+	state.used = true
+	if _, err := state.mac.Write(generator.message(movingFactor, challenge)); err != nil {
+		return nil, err
+	}
+	return state.mac.Sum(nil), nil
+}
+
+// GenerateRaw gets the 31-bit value produced by truncating the HMAC result of the specified moving factor, before it
+// is reduced to code digits and encoded, for deriving codes of custom formats or feeding the value into another
+// protocol. The truncation offset set with WithTruncationOffset is honored. Generate renders this value, so for
+// decimal codes the code is the value modulo 10 to the power of the code digits. 0 is returned if the value cannot be
+// computed, such as for negative moving factors or after Close.
+//
+// The value carries more information about the secret key than a code does, so it must be kept as secret as the codes
+// derived from it.
+func (generator *hotpManager) GenerateRaw(movingFactor int64) uint32 {
+	state := generator.newMAC()
+	if state == nil {
+		return 0
+	}
+	hashResult, err := generator.hashMAC(state, movingFactor, nil)
+	if err != nil {
+		return 0
+	}
+	return generator.truncatedValue(hashResult)
+}
+
+// truncatedValue gets the 31-bit value of the HMAC result at the truncation offset of the manager.
+func (generator *hotpManager) truncatedValue(hashResult []byte) uint32 {
+	offset := generator.offset
+	if offset == dynamicTruncation {
+		offset = dynamicOffset(hashResult)
+	}
+	return truncateAt(hashResult, offset)
+}
+
+// encodeHash truncates the HMAC result and encodes it as a one-time password with the specified code digits.
+func (generator *hotpManager) encodeHash(hashResult []byte, codeDigits int) string {
+	if generator.offset == dynamicTruncation && generator.encoder == DecimalEncoder && !generator.checksum {
+		return generator.pad(Truncate(hashResult, codeDigits))
+	}
+	code := generator.encoder.Encode(uint64(generator.truncatedValue(hashResult)), codeDigits)
+	if generator.checksum {
+		code += string('0' + luhnCheckDigit(code))
+	}
+	return generator.pad(code)
+}
+
+// GenerateRange generates count one-time passwords for consecutive counters starting at the start counter. It is
+// equivalent to calling Generate for each counter, but the HMAC state is set up only once. No codes are generated when
+// count is not positive, and the codes of negative counters are empty.
+func (generator *hotpManager) GenerateRange(start, count int64) []string {
+	if count <= 0 {
+		return nil
+	}
+	codes := make([]string, count)
+	state := generator.newMAC()
+	if state == nil {
+		return codes
+	}
+	for i := range codes {
+		// Matches Generate, which returns an empty string on error.
+		codes[i], _ = generator.generateMAC(state, start+int64(i), nil, generator.codeDigits)
+	}
+	return codes
+}
 
-	offset := hashResult[len(hashResult)-1] & 0xf
-	truncated := binary.BigEndian.Uint32(hashResult[offset:offset+4]) & 0x7fffffff
-	code := truncated % uint32(math.Pow10(generator.codeDigits))
+// checkOptions checks whether the options applied to the manager can be combined.
+func (generator *hotpManager) checkOptions() error {
+	if generator.checksum && generator.encoder != DecimalEncoder {
+		return errors.New("checksum requires decimal codes")
+	}
+	if generator.padding != PaddingZero && generator.encoder != DecimalEncoder {
+		return errors.New("padding requires decimal codes")
+	}
+	return nil
+}
 
-	return fmt.Sprintf(fmt.Sprintf("%%0%dd", generator.codeDigits), code)
+// codeLength gets the length of generated codes, which includes the checksum digit if enabled.
+func (generator *hotpManager) codeLength() int {
+	if generator.checksum {
+		return generator.codeDigits + 1
+	}
+	return generator.codeDigits
+}
+
+// autoCodeDigits gets the code digits of a code whose length is taken as the code digits, excluding the checksum digit
+// if enabled.
+func (generator *hotpManager) autoCodeDigits(code string) int {
+	if generator.checksum {
+		return len(code) - 1
+	}
+	return len(code)
+}
+
+func (generator *hotpManager) GenerateCode(movingFactor int64) Code {
+	return Code(generator.Generate(movingFactor))
 }
 
 func (generator *hotpManager) Validate(movingFactor int64, code string) bool {
-	return generator.Generate(movingFactor) == code
+	code, ok := generator.prepareInput(code)
+	return ok && generator.validate(movingFactor, code)
+}
+
+// validate validates whether the one-time password without prefix matches. Codes are compared in constant time, so
+// the response time does not reveal how many leading characters of a guess are correct. Nothing matches if the
+// expected code cannot be generated.
+func (generator *hotpManager) validate(movingFactor int64, code string) bool {
+	return generator.validateMAC(generator.newMAC(), movingFactor, code)
+}
+
+// validateMAC validates like validate with an HMAC state created by newMAC. Nothing matches if the state is nil.
+func (generator *hotpManager) validateMAC(state *macState, movingFactor int64, code string) bool {
+	return generator.validateMessageMAC(state, movingFactor, nil, code)
+}
+
+// validateMessageMAC validates like validateMAC the code of the HMAC message built from the moving factor and the
+// challenge.
+func (generator *hotpManager) validateMessageMAC(state *macState, movingFactor int64,
+	challenge []byte, code string) bool {
+	if state == nil {
+		return false
+	}
+	if generator.padding != PaddingZero && len(code) > generator.codeLength() {
+		return false
+	}
+	expected, err := generator.generateMAC(state, movingFactor, challenge, generator.codeDigits)
+	return err == nil && Code(generator.unpad(expected)).Equal(Code(generator.unpad(code)))
+}
+
+// prepareInput removes the expected prefix from the code, and checks in constant time whether the code starts with it.
+// With lenient input, surrounding whitespace is trimmed first, and whitespace and hyphens are removed from the rest.
+func (generator *hotpManager) prepareInput(code string) (string, bool) {
+	if generator.lenient {
+		code = strings.TrimSpace(code)
+	}
+	if len(code) < len(generator.prefix) {
+		return "", false
+	}
+	prefix, rest := code[:len(generator.prefix)], code[len(generator.prefix):]
+	if generator.lenient {
+		rest = strings.Map(func(r rune) rune {
+			if unicode.IsSpace(r) || r == '-' {
+				return -1
+			}
+			return r
+		}, rest)
+	}
+	return rest, subtle.ConstantTimeCompare([]byte(prefix), []byte(generator.prefix)) == 1
+}
+
+// ValidateAutoDigits validates whether the one-time password matches, taking the length of the code as the code digits
+// instead of the configured ones. This allows a single endpoint to accept, for example, both 6-digit and 8-digit codes
+// during a migration without storing the code digits of every user. Codes whose length is not in allowed are rejected
+// without any work being done.
+//
+// Be aware that accepting several lengths lowers the security to that of the shortest allowed length, since an
+// attacker can always choose to guess the shortest code.
+func (generator *hotpManager) ValidateAutoDigits(movingFactor int64, code string, allowed []int) bool {
+	code, ok := generator.prepareInput(code)
+	if !ok {
+		return false
+	}
+	codeDigits := generator.autoCodeDigits(code)
+	if !allowedCodeDigits(codeDigits, allowed) {
+		return false
+	}
+	return Code(generator.generate(movingFactor, codeDigits)).Equal(Code(code))
+}
+
+// SearchCounter searches the counters from counter to counter+window inclusive for the one-time password, and gets the
+// first matching counter.
+//
+// Since codes are truncated, the same code may be generated for different counters. The whole window is always
+// searched, and ErrAmbiguousCode is returned along with the first match when more than one counter matches. A server
+// should then ask for another code instead of resynchronizing to a counter that may be wrong.
+func (generator *hotpManager) SearchCounter(counter int64, code string, window int) (int64, bool, error) {
+	code, ok := generator.prepareInput(code)
+	if !ok {
+		return counter, false, nil
+	}
+	if window < 0 {
+		return counter, false, errors.New("invalid window")
+	}
+	matched, matches := counter, 0
+	state := generator.newMAC()
+	for i := int64(0); i <= int64(window); i += 1 {
+		if generator.validateMAC(state, counter+i, code) {
+			if matches == 0 {
+				matched = counter + i
+			}
+			matches += 1
+		}
+	}
+	if matches > 1 {
+		return matched, true, ErrAmbiguousCode
+	}
+	return matched, matches == 1, nil
+}
+
+// ValidateWindow validates whether the one-time password matches any counter from counter to counter+window inclusive,
+// the look-ahead window of section 7.2 of RFC 4226, and gets the matching counter. After a successful validation, the
+// server should store the matching counter plus one as the next counter to expect, so that the code cannot be used
+// again.
+//
+// The original counter and false are returned when nothing matches, when the window is negative, or when more than one
+// counter matches. Refers to SearchCounter for details.
+func (generator *hotpManager) ValidateWindow(counter int64, code string, window int) (int64, bool) {
+	matched, ok, err := generator.SearchCounter(counter, code, window)
+	if !ok || err != nil {
+		return counter, false
+	}
+	return matched, true
+}
+
+// Resynchronize searches the counters from counter to counter+window inclusive for the one-time password, as described
+// in section 7.4 of RFC 4226, and gets the counter following the matching one, which is the next counter the server
+// should expect. This is needed when the counter of a client has advanced past the one of the server, such as when the
+// button of a hardware token is pressed without logging in.
+//
+// The original counter and false are returned when nothing matches, when the window is negative, or when more than
+// one counter matches, since resynchronizing to a counter that may be wrong is worse than asking for another code.
+func (generator *hotpManager) Resynchronize(counter int64, code string, window int) (int64, bool) {
+	matched, ok, err := generator.SearchCounter(counter, code, window)
+	if !ok || err != nil {
+		return counter, false
+	}
+	return matched + 1, true
+}
+
+// ValidateStrict validates whether the one-time password matches like Validate, but reports codes that could never
+// match, such as codes of the wrong length, with characters other than those of the encoder or without the expected
+// prefix, with ErrMalformedCode instead of reporting a mismatch. This lets login pages tell users who typed something
+// that is not a code apart from users who typed a wrong code.
+//
+// The expected format of codes is revealed to whoever sees the difference, which is harmless when it is public anyway,
+// such as for codes shown by authenticator apps. Codes of custom encoders are only checked for their length.
+func (generator *hotpManager) ValidateStrict(movingFactor int64, code string) (bool, error) {
+	code, ok := generator.prepareInput(code)
+	if !ok || !generator.wellFormed(code) {
+		return false, ErrMalformedCode
+	}
+	return generator.validate(movingFactor, code), nil
+}
+
+// wellFormed checks whether the code without prefix has the length of generated codes, and only contains symbols of
+// the encoder if they are known.
+func (generator *hotpManager) wellFormed(code string) bool {
+	if len(code) > generator.codeLength() || generator.padding == PaddingZero && len(code) != generator.codeLength() {
+		return false
+	}
+	if code = generator.unpad(code); code == "" {
+		return false
+	}
+	encoder, ok := generator.encoder.(symbolEncoder)
+	if !ok {
+		return true
+	}
+	symbols := encoder.symbols()
+	for i := 0; i < len(code); i += 1 {
+		if strings.IndexByte(symbols, code[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (generator *hotpManager) ValidateCanonical(movingFactor int64, code string) (bool, string) {
+	code, ok := generator.prepareInput(code)
+	if !ok {
+		return false, ""
+	}
+	canonical, ok := generator.canonicalCode(code)
+	if !ok || !generator.validate(movingFactor, canonical) {
+		return false, ""
+	}
+	return true, canonical
+}
+
+// canonicalCode normalizes a code typed by a user into the form of generated codes, by removing spaces and hyphens and,
+// for decimal codes, restoring leading zeros the user may have dropped. False is returned if the code is empty, is
+// longer than the code digits, or, for decimal codes, contains other characters than digits. Codes of other encoders
+// must have exactly the length of the code digits once spaces and hyphens are removed.
+func (generator *hotpManager) canonicalCode(code string) (string, bool) {
+	decimal := generator.encoder == DecimalEncoder
+	length := generator.codeLength()
+	symbols := make([]rune, 0, length)
+	for _, r := range code {
+		switch {
+		case unicode.IsSpace(r) || r == '-':
+			continue
+		case decimal && (r < '0' || r > '9') || len(symbols) == length:
+			return "", false
+		}
+		symbols = append(symbols, r)
+	}
+	if len(symbols) == 0 || !decimal && len(symbols) != length {
+		return "", false
+	}
+	return strings.Repeat("0", length-len(symbols)) + string(symbols), true
+}
+
+// allowedCodeDigits checks whether the code digits are valid and among the allowed ones.
+func allowedCodeDigits(codeDigits int, allowed []int) bool {
+	if codeDigits <= 0 || codeDigits > MaxCodeDigits {
+		return false
+	}
+	for _, digits := range allowed {
+		if digits == codeDigits {
+			return true
+		}
+	}
+	return false
+}
+
+// QueryParams gets the parameters of the manager as URL query values. The counter is always 0 since the manager does
+// not keep track of the moving factor. Use QueryParamsAt to export a counter that has already advanced.
+func (generator *hotpManager) QueryParams(opts ...URIOption) url.Values {
+	params, _ := generator.QueryParamsAt(0, opts...)
+	return params
+}
+
+// QueryParamsAt gets the parameters of the manager as URL query values, with the specified counter emitted verbatim.
+// This is useful for handing an HOTP credential that has already been used to a new device. Negative counters are
+// rejected.
+func (generator *hotpManager) QueryParamsAt(counter int64, opts ...URIOption) (url.Values, error) {
+	if counter < 0 {
+		return nil, errors.New("invalid counter")
+	}
+	params := generator.params(opts)
+	params.Set("counter", strconv.FormatInt(counter, 10))
+	return params, nil
+}
+
+// Secret gets a copy of the secret key, such as one generated when nil was passed to the constructor, for storing it or
+// showing it to the user. Modifying the returned slice does not affect the manager.
+func (generator *hotpManager) Secret() []byte {
+	return append([]byte(nil), generator.secret...)
+}
+
+// SecretBase32 gets the secret key encoded in base32 without padding, as used in key URIs.
+func (generator *hotpManager) SecretBase32() string {
+	return EncodeSecret(generator.secret)
+}
+
+// SecretBase32Grouped gets the secret key encoded in base32 and split into lowercase groups of four characters
+// separated by spaces, which is how authenticator apps display secret keys for manual entry.
+func (generator *hotpManager) SecretBase32Grouped() string {
+	encoded := strings.ToLower(generator.SecretBase32())
+	groups := make([]string, 0, (len(encoded)+3)/4)
+	for len(encoded) > 4 {
+		groups = append(groups, encoded[:4])
+		encoded = encoded[4:]
+	}
+	groups = append(groups, encoded)
+	return strings.Join(groups, " ")
+}
+
+// Algorithm gets the hash algorithm used for HMAC.
+func (generator *hotpManager) Algorithm() HashAlgorithm {
+	return generator.algorithm
+}
+
+// CodeDigits gets the digit count of password codes, not including the check digit appended by WithChecksum.
+func (generator *hotpManager) CodeDigits() int {
+	return generator.codeDigits
+}
+
+// Type gets OTPTypeHOTP, since the manager is an HOTPManager.
+func (generator *hotpManager) Type() OTPType {
+	return OTPTypeHOTP
+}
+
+// params gets the query parameters shared by HOTP and TOTP managers.
+func (generator *hotpManager) params(opts []URIOption) url.Values {
+	options := newURIOptions(opts)
+	algorithm, _ := generator.algorithm.name()
+	if options.lowercaseAlgorithm {
+		algorithm = strings.ToLower(algorithm)
+	}
+	params := url.Values{}
+	for name, values := range options.extraParams {
+		if !keyURIParams[name] {
+			params[name] = append([]string(nil), values...)
+		}
+	}
+	if options.image != "" {
+		params.Set("image", options.image)
+	}
+	params.Set("secret", generator.SecretBase32())
+	params.Set("algorithm", algorithm)
+	params.Set("digits", strconv.Itoa(generator.codeDigits))
+	return params
+}
+
+// Truncate performs the dynamic truncation described in RFC 4226 on an HMAC result, and renders the 31-bit value as a
+// decimal code of the specified digits, padded with leading zeros. It is what HOTP and TOTP managers do with default
+// options, and can be reused by schemes computing their HMAC results differently. An empty string is returned if the
+// HMAC result is too short for the selected offset, or the digits are not between 1 and 10.
+func Truncate(hashResult []byte, digits int) string {
+	if len(hashResult) == 0 || dynamicOffset(hashResult)+4 > len(hashResult) || digits < 1 || digits > MaxCodeDigits {
+		return ""
+	}
+	return DecimalEncoder.Encode(uint64(truncate(hashResult)), digits)
+}
+
+// truncate performs the dynamic truncation described in RFC 4226 on an HMAC result, returning the 31-bit value
+// before it is reduced to code digits.
+func truncate(hashResult []byte) uint32 {
+	return truncateAt(hashResult, dynamicOffset(hashResult))
+}
+
+// dynamicOffset gets the offset selected by dynamic truncation, which is the low 4 bits of the last byte of the HMAC
+// result.
+func dynamicOffset(hashResult []byte) int {
+	return int(hashResult[len(hashResult)-1] & 0xf)
+}
+
+// truncateAt gets the 31-bit value of the 4 bytes of an HMAC result starting at the specified offset.
+func truncateAt(hashResult []byte, offset int) uint32 {
+	return binary.BigEndian.Uint32(hashResult[offset:offset+4]) & truncationMask
 }
 
 // totpManager represents an time-based one-time password (HOTP) generator and validator.
 type totpManager struct {
 	hotp         *hotpManager
 	timeStep     int
+	stepDuration time.Duration
 	lookBackward int
 	lookForward  int
+	epochOffset  int64
+	t0           int64
+	derivedT0    bool
+	clock        func() time.Time
+	skew         *skewHistogram
+	used         UsedCodeStore
 }
 
 // NewTOTP initializes a new time-based one-time password (TOTP) manager with specified hash algorithm, secret key,
@@ -158,47 +1112,601 @@ type totpManager struct {
 //
 // A new secret key will be generated if provided one is nil. Refers to NewHOTP function for details.
 //
-// Code digit cannot be longer than 8 digits.
+// Code digit cannot be longer than 10 digits.
 //
 // Tolerant time steps are only used for validating. These parameters can be used to allow certain clock drift
 // between a client and the TOTP manager. Settings to 0 to accept no time drift at all.
-func NewTOTP(algorithm HashAlgorithm, secret []byte, codeDigit, timeStep, lookBackward, lookForward int) (OTPManager, error) {
-	var generator totpManager
+//
+// Out-of-range parameters are reported with a *ParamError. Optional behavior can be configured with options.
+func NewTOTP(algorithm HashAlgorithm, secret []byte, codeDigit, timeStep, lookBackward, lookForward int, opts ...TOTPOption) (TOTPManager, error) {
+	params := []TOTPOption{WithSecret(secret), WithDigits(codeDigit), WithTimeStep(timeStep),
+		WithSkewWindow(lookBackward, lookForward)}
+	return NewTOTPWithOptions(algorithm, append(params, opts...)...)
+}
 
-	hotp, err := NewHOTP(algorithm, secret, codeDigit)
+// NewTOTPWithOptions initializes a new time-based one-time password (TOTP) manager with specified hash algorithm, and
+// all other settings configured with options, such as:
+//
+//	NewTOTPWithOptions(HashAlgorithmSHA1, WithSecret(secret), WithDigits(8), WithSkewWindow(1, 1))
+//
+// Settings without an option take the defaults of authenticator apps: a new secret key, 6 digits and a time step of 30
+// seconds. One time step backward is allowed for network delay, as recommended by RFC 6238, and none forward. Options
+// are applied in order, so a later option overrides an earlier one. Refers to NewTOTP function for details.
+func NewTOTPWithOptions(algorithm HashAlgorithm, opts ...TOTPOption) (TOTPManager, error) {
+	hotp, err := newHOTPManager(algorithm)
 	if err != nil {
 		return nil, err
 	}
-	generator.hotp = hotp.(*hotpManager)
+	generator := totpManager{
+		hotp:         hotp,
+		timeStep:     30,
+		lookBackward: 1,
+		clock:        time.Now,
+		used:         &memoryUsedCodeStore{},
+	}
 
-	if timeStep <= 0 {
-		return nil, errors.New("invalid time step")
+	for _, opt := range opts {
+		if err := opt.applyTOTP(&generator); err != nil {
+			return nil, err
+		}
+	}
+	if err := generator.hotp.complete(); err != nil {
+		return nil, err
+	}
+	if generator.derivedT0 {
+		generator.t0 = generator.hotp.deriveT0(generator.timeStep)
+	}
+
+	return &generator, nil
+}
+
+// NewTOTPBound initializes a new time-based one-time password (TOTP) manager bound to a device identifier. Refers to
+// NewTOTP function for details of the other parameters.
+//
+// The HMAC message is the 8-byte big-endian time step followed by the device identifier, so codes generated for one
+// device are not accepted for another. The identifier must match exactly byte for byte between the client and the
+// server, and codes are not compatible with standard authenticator apps.
+func NewTOTPBound(algorithm HashAlgorithm, secret []byte, codeDigit, timeStep, lookBackward, lookForward int, deviceID []byte, opts ...TOTPOption) (TOTPManager, error) {
+	if len(deviceID) == 0 {
+		return nil, errors.New("invalid device identifier")
 	}
-	generator.timeStep = timeStep
 
-	if lookBackward < 0 {
-		return nil, errors.New("invalid look-backward value")
+	generator, err := NewTOTP(algorithm, secret, codeDigit, timeStep, lookBackward, lookForward, opts...)
+	if err != nil {
+		return nil, err
 	}
-	generator.lookBackward = lookBackward
+	generator.(*totpManager).hotp.binding = append([]byte(nil), deviceID...)
 
-	if lookForward < 0 {
-		return nil, errors.New("invalid look-forward value")
+	return generator, nil
+}
+
+// NewTOTPDuration initializes a new time-based one-time password (TOTP) manager like NewTOTP, but with the time step
+// specified as a duration. Durations that are not a whole number of seconds, such as 500 milliseconds, allow time steps
+// shorter than a second, which RFC 6238 does not define. Such managers do not interoperate with standard authenticator
+// apps, and should be used with GenerateTime and ValidateTime since epochs in seconds cannot tell time steps within the
+// same second apart.
+//
+// Durations that are a whole number of seconds behave exactly like the same time step passed to NewTOTP.
+func NewTOTPDuration(algorithm HashAlgorithm, secret []byte, codeDigit int, timeStep time.Duration, lookBackward, lookForward int, opts ...TOTPOption) (TOTPManager, error) {
+	if timeStep <= 0 {
+		return nil, &ParamError{Param: "timeStep", Value: int(timeStep), Min: 1, Max: math.MaxInt}
+	}
+	if timeStep%time.Second == 0 {
+		return NewTOTP(algorithm, secret, codeDigit, int(timeStep/time.Second), lookBackward, lookForward, opts...)
 	}
-	generator.lookForward = lookForward
 
-	return &generator, nil
+	generator, err := NewTOTP(algorithm, secret, codeDigit, 1, lookBackward, lookForward, opts...)
+	if err != nil {
+		return nil, err
+	}
+	generator.(*totpManager).timeStep = 0
+	generator.(*totpManager).stepDuration = timeStep
+
+	return generator, nil
 }
 
 func (generator *totpManager) Generate(epoch int64) string {
-	return generator.hotp.Generate(epoch / int64(generator.timeStep))
+	return generator.hotp.Generate(generator.MovingFactor(epoch))
+}
+
+// GenerateRaw gets the 31-bit value produced by truncating the HMAC result of the time step of the specified epoch.
+// Refers to the HOTP counterpart for details.
+func (generator *totpManager) GenerateRaw(epoch int64) uint32 {
+	return generator.hotp.GenerateRaw(generator.MovingFactor(epoch))
+}
+
+func (generator *totpManager) GenerateCode(epoch int64) Code {
+	return Code(generator.Generate(epoch))
+}
+
+func (generator *totpManager) GenerateError(epoch int64) (string, error) {
+	return generator.hotp.GenerateError(generator.MovingFactor(epoch))
+}
+
+// GenerateRange generates count one-time passwords for consecutive time steps starting at the time step of the start
+// epoch, such as the current code followed by the upcoming ones. It is equivalent to calling Generate for the first
+// epoch of each time step.
+func (generator *totpManager) GenerateRange(startEpoch, count int64) []string {
+	return generator.hotp.GenerateRange(generator.MovingFactor(startEpoch), count)
 }
 
 func (generator *totpManager) Validate(epoch int64, code string) bool {
+	code, ok := generator.hotp.prepareInput(code)
+	return ok && generator.validateMovingFactor(generator.MovingFactor(epoch), code)
+}
+
+// GenerateTime generates the one-time password for the specified time.
+func (generator *totpManager) GenerateTime(t time.Time) string {
+	return generator.hotp.Generate(generator.movingFactorTime(t))
+}
+
+// GenerateNow generates the one-time password for the current time read from the clock of the manager.
+func (generator *totpManager) GenerateNow() string {
+	return generator.GenerateTime(generator.clock())
+}
+
+// ValidateNow validates whether the one-time password matches the current time read from the clock of the manager
+// within the tolerant time steps. The clock is read exactly once, so the whole tolerant window is computed from the
+// same time.
+func (generator *totpManager) ValidateNow(code string) bool {
+	return generator.ValidateTime(generator.clock(), code)
+}
+
+// ValidateContext validates whether the one-time password matches like Validate, but stops comparing time steps once
+// the context is done, such as when the request times out or the client disconnects. The error of the context is
+// returned in that case, and the code is not accepted.
+func (generator *totpManager) ValidateContext(ctx context.Context, epoch int64, code string) (bool, error) {
+	code, ok := generator.hotp.prepareInput(code)
+	if !ok {
+		return false, nil
+	}
+	_, ok, err := generator.matchOffsetContext(ctx, generator.MovingFactor(epoch), code)
+	return ok, err
+}
+
+// ValidateTime validates whether the one-time password matches the specified time within the tolerant time steps.
+func (generator *totpManager) ValidateTime(t time.Time, code string) bool {
+	code, ok := generator.hotp.prepareInput(code)
+	if !ok {
+		return false
+	}
+	return generator.validateMovingFactor(generator.movingFactorTime(t), code)
+}
+
+// validateMovingFactor validates whether the one-time password matches the specified time step within the tolerant
+// time steps, and records the matching offset if enabled.
+func (generator *totpManager) validateMovingFactor(movingFactor int64, code string) bool {
+	_, ok := generator.matchOffset(movingFactor, code)
+	return ok
+}
+
+// matchOffset gets the offset of the earliest time step within the tolerant time steps that matches the one-time
+// password, and records it if enabled. Every time step is always compared in constant time and the results are
+// combined in constant time, so the response time does not reveal which time step matched.
+func (generator *totpManager) matchOffset(movingFactor int64, code string) (int, bool) {
+	offset, ok, _ := generator.matchOffsetContext(context.Background(), movingFactor, code)
+	return offset, ok
+}
+
+// matchOffsetContext gets the offset of the matching time step like matchOffset, but gives up with the error of the
+// context as soon as it is done.
+func (generator *totpManager) matchOffsetContext(ctx context.Context, movingFactor int64, code string) (int, bool, error) {
+	offset, matched := 0, 0
+	state := generator.hotp.newMAC()
 	for i := -generator.lookBackward; i <= generator.lookForward; i += 1 {
-		movingFactor := (epoch + int64(i*generator.timeStep)) / int64(generator.timeStep)
-		if generator.hotp.Generate(movingFactor) == code {
-			return true
+		if err := ctx.Err(); err != nil {
+			return 0, false, err
+		}
+		match := 0
+		if generator.hotp.validateMAC(state, movingFactor+int64(i), code) {
+			match = 1
 		}
+		offset = subtle.ConstantTimeSelect(match&^matched, i, offset)
+		matched |= match
 	}
-	return false
+	if matched == 0 {
+		return 0, false, nil
+	}
+	if generator.skew != nil {
+		generator.skew.observe(offset)
+	}
+	return offset, true, nil
+}
+
+// ValidateWithSkew validates whether the one-time password matches within the tolerant time steps, and gets the signed
+// offset in time steps of the matching time step relative to the one of the epoch: negative when the client clock is
+// behind, and positive when it is ahead. Servers can store the offset per user to detect persistent clock drift. When
+// more than one time step matches, the earliest one is reported. False and 0 are returned when nothing matches.
+func (generator *totpManager) ValidateWithSkew(epoch int64, code string) (bool, int) {
+	code, ok := generator.hotp.prepareInput(code)
+	if !ok {
+		return false, 0
+	}
+	offset, ok := generator.matchOffset(generator.MovingFactor(epoch), code)
+	return ok, offset
+}
+
+// ValidateWithRefreshHint validates whether the one-time password matches within the tolerant time steps, and gets the
+// number of seconds the matching code remains the current one, so that clients can tell users when their code expires.
+// The hint is 0 when the code does not match, or when it matches an earlier time step that has already ended.
+func (generator *totpManager) ValidateWithRefreshHint(epoch int64, code string) (bool, int) {
+	code, ok := generator.hotp.prepareInput(code)
+	if !ok {
+		return false, 0
+	}
+	movingFactor := generator.MovingFactor(epoch)
+	offset, ok := generator.matchOffset(movingFactor, code)
+	if !ok {
+		return false, 0
+	}
+	refresh := generator.stepStart(movingFactor+int64(offset)+1) - epoch
+	if refresh < 0 {
+		refresh = 0
+	}
+	return true, int(refresh)
+}
+
+// ValidateWithExpiry validates whether the one-time password matches within the tolerant time steps, and gets the
+// number of seconds from the epoch until the time step of the matching code ends, which can be used as the grace
+// period of a login. The expiry is 0 when the code does not match, or when it matches a lookBackward time step that has
+// already ended, even though Validate keeps accepting it until AcceptedUntil.
+func (generator *totpManager) ValidateWithExpiry(epoch int64, code string) (bool, int) {
+	code, ok := generator.hotp.prepareInput(code)
+	if !ok {
+		return false, 0
+	}
+	movingFactor := generator.MovingFactor(epoch)
+	offset, ok := generator.matchOffset(movingFactor, code)
+	if !ok {
+		return false, 0
+	}
+	expiry := generator.stepStart(movingFactor+int64(offset)+1) - epoch
+	if expiry < 0 {
+		expiry = 0
+	}
+	return true, int(expiry)
+}
+
+// ValidateAutoDigits validates whether the one-time password matches within the tolerant time steps, taking the length
+// of the code as the code digits. Refers to the HOTP counterpart for details and caveats.
+func (generator *totpManager) ValidateAutoDigits(epoch int64, code string, allowed []int) bool {
+	code, ok := generator.hotp.prepareInput(code)
+	if !ok {
+		return false
+	}
+	codeDigits := generator.hotp.autoCodeDigits(code)
+	if !allowedCodeDigits(codeDigits, allowed) {
+		return false
+	}
+	matched := false
+	movingFactor := generator.MovingFactor(epoch)
+	for i := -generator.lookBackward; i <= generator.lookForward; i += 1 {
+		if Code(generator.hotp.generate(movingFactor+int64(i), codeDigits)).Equal(Code(code)) {
+			matched = true
+		}
+	}
+	return matched
+}
+
+// ValidateCanonical validates whether the one-time password matches within the tolerant time steps after normalizing
+// it, and gets the canonical zero-padded form of the matching code. Spaces and hyphens are removed and dropped leading
+// zeros are restored, so the canonical form can be recorded consistently however the user typed the code. False and an
+// empty string are returned when the code does not match.
+func (generator *totpManager) ValidateCanonical(epoch int64, code string) (bool, string) {
+	code, ok := generator.hotp.prepareInput(code)
+	if !ok {
+		return false, ""
+	}
+	canonical, ok := generator.hotp.canonicalCode(code)
+	if !ok || !generator.validateMovingFactor(generator.MovingFactor(epoch), canonical) {
+		return false, ""
+	}
+	return true, canonical
+}
+
+// ValidateStrict validates whether the one-time password matches within the tolerant time steps like Validate, but
+// reports codes that could never match with ErrMalformedCode. Refers to the HOTP counterpart for details.
+func (generator *totpManager) ValidateStrict(epoch int64, code string) (bool, error) {
+	code, ok := generator.hotp.prepareInput(code)
+	if !ok || !generator.hotp.wellFormed(code) {
+		return false, ErrMalformedCode
+	}
+	return generator.validateMovingFactor(generator.MovingFactor(epoch), code), nil
+}
+
+func (generator *totpManager) MovingFactor(epoch int64) int64 {
+	if generator.stepDuration != 0 {
+		return generator.movingFactorTime(time.Unix(epoch, 0))
+	}
+	return floorDiv(epoch+generator.shift(), int64(generator.timeStep))
+}
+
+// movingFactorTime gets the time step the specified time belongs to. Time steps of whole seconds are computed from the
+// epoch in seconds so that times far beyond the range of UnixNano still work.
+func (generator *totpManager) movingFactorTime(t time.Time) int64 {
+	t = t.Add(time.Duration(generator.shift()) * time.Second)
+	if generator.stepDuration != 0 {
+		return floorDiv(t.UnixNano(), int64(generator.stepDuration))
+	}
+	return floorDiv(t.Unix(), int64(generator.timeStep))
+}
+
+// stepStart gets the first epoch in seconds not earlier than the start of the specified time step, before the epoch
+// offset is applied.
+func (generator *totpManager) stepStart(movingFactor int64) int64 {
+	if generator.stepDuration != 0 {
+		start := movingFactor * int64(generator.stepDuration)
+		return -floorDiv(-start, int64(time.Second)) - generator.shift()
+	}
+	return movingFactor*int64(generator.timeStep) - generator.shift()
+}
+
+// floorDiv divides a by the positive b, rounding toward negative infinity as the floor function of RFC 6238 does, so
+// that epochs before T0 belong to negative time steps instead of all collapsing into time step 0.
+func floorDiv(a, b int64) int64 {
+	quotient := a / b
+	if a%b < 0 {
+		quotient -= 1
+	}
+	return quotient
+}
+
+// shift gets the number of seconds added to epochs before the time step is computed, which combines the epoch offset
+// and T0.
+func (generator *totpManager) shift() int64 {
+	return generator.epochOffset - generator.t0
+}
+
+// RemainingSeconds gets the number of seconds until the time step of the specified epoch ends. An epoch exactly at a
+// step boundary has the whole time step remaining.
+func (generator *totpManager) RemainingSeconds(epoch int64) int {
+	return int(generator.NextChangeTime(epoch) - epoch)
+}
+
+// NextChangeTime gets the epoch at which the next time step starts, which is when the generated code changes. An epoch
+// exactly at a step boundary changes one full time step later.
+func (generator *totpManager) NextChangeTime(epoch int64) int64 {
+	return generator.stepStart(generator.MovingFactor(epoch) + 1)
+}
+
+// StepsUntil gets the number of full time steps that fit between the from and until epochs, regardless of where the
+// step boundaries are. This helps to size the number of codes to pre-generate for a period. 0 is returned when until
+// is earlier than from, and the number is capped at math.MaxInt64 for spans too large to count.
+func (generator *totpManager) StepsUntil(from, until int64) int64 {
+	if until < from {
+		return 0
+	}
+	// The span always fits in an unsigned integer, and its nanoseconds in the 128-bit product.
+	span := uint64(until) - uint64(from)
+	var steps uint64
+	if generator.stepDuration != 0 {
+		hi, lo := bits.Mul64(span, uint64(time.Second))
+		if hi >= uint64(generator.stepDuration) {
+			return math.MaxInt64
+		}
+		steps, _ = bits.Div64(hi, lo, uint64(generator.stepDuration))
+	} else {
+		steps = span / uint64(generator.timeStep)
+	}
+	return int64(min(steps, math.MaxInt64))
+}
+
+// AcceptedUntil gets the last epoch at which the code of the time step of the specified epoch is still accepted by
+// Validate. Since Validate looks lookBackward time steps into the past, a code keeps being accepted for lookBackward
+// time steps after its own time step ends. Clients can use this to schedule refreshes conservatively.
+func (generator *totpManager) AcceptedUntil(epoch int64) int64 {
+	return generator.stepStart(generator.MovingFactor(epoch)+int64(generator.lookBackward)+1) - 1
+}
+
+// AcceptedCodes gets the codes that Validate accepts at the specified epoch, one for each time step from lookBackward
+// time steps before the one of the epoch to lookForward time steps after it, in that order, so the code of the epoch
+// itself is at index lookBackward. This is meant for debugging and for inspecting generous tolerant windows. The codes
+// are as sensitive as any other generated code, and must not be logged where an attacker could read them in time.
+//
+// The length is always lookBackward + lookForward + 1. Time steps without a code, such as those before 0, have an empty
+// string.
+func (generator *totpManager) AcceptedCodes(epoch int64) []string {
+	first := generator.MovingFactor(epoch) - int64(generator.lookBackward)
+	return generator.hotp.GenerateRange(first, int64(generator.lookBackward+generator.lookForward+1))
+}
+
+// ValidateWithClockError validates whether the one-time password matches, accepting every time step that overlaps the
+// configured tolerant window extended by maxError on both sides. This is intended for servers that know how far off
+// their clock may be, such as from a measured NTP offset. Negative errors are treated as 0.
+func (generator *totpManager) ValidateWithClockError(epoch int64, code string, maxError time.Duration) bool {
+	code, ok := generator.hotp.prepareInput(code)
+	if !ok {
+		return false
+	}
+	if maxError < 0 {
+		maxError = 0
+	}
+	clockError := int64(maxError / time.Second)
+	if maxError%time.Second != 0 {
+		clockError += 1
+	}
+	first := generator.MovingFactor(epoch-clockError) - int64(generator.lookBackward)
+	last := generator.MovingFactor(epoch+clockError) + int64(generator.lookForward)
+	matched := false
+	state := generator.hotp.newMAC()
+	for movingFactor := first; movingFactor <= last; movingFactor += 1 {
+		if generator.hotp.validateMAC(state, movingFactor, code) {
+			matched = true
+		}
+	}
+	return matched
+}
+
+// ValidateAllOffsets gets the signed offsets, in time steps, of every time step within the tolerant window whose code
+// matches the one-time password, in ascending order. Normally at most one offset is returned; more than one means codes
+// collide within the window, which is worth surfacing when diagnosing configurations. Nil is returned when nothing
+// matches.
+func (generator *totpManager) ValidateAllOffsets(epoch int64, code string) []int {
+	code, ok := generator.hotp.prepareInput(code)
+	if !ok {
+		return nil
+	}
+	var offsets []int
+	movingFactor := generator.MovingFactor(epoch)
+	state := generator.hotp.newMAC()
+	for i := -generator.lookBackward; i <= generator.lookForward; i += 1 {
+		if generator.hotp.validateMAC(state, movingFactor+int64(i), code) {
+			offsets = append(offsets, i)
+		}
+	}
+	return offsets
+}
+
+// ValidateConsecutive validates whether the first one-time password matches a time step within the tolerant time steps
+// and the second one matches the time step immediately following it, also within the tolerant time steps. Asking for
+// two consecutive codes proves that the user holds a live token rather than a single leaked code. The pair may straddle
+// the time step of the epoch, such as the previous and the current codes, or the current and the next ones. Codes in
+// the wrong order, or with a time step between them, do not match.
+//
+// The tolerant window must span at least two time steps, or no pair ever matches: the user typically enters the first
+// code, waits for the next one, and submits while the second code is the current one, which needs lookBackward of at
+// least 1. Every pair is compared in constant time, so the response time does not reveal which pair matched.
+func (generator *totpManager) ValidateConsecutive(epoch int64, code1, code2 string) bool {
+	code1, ok1 := generator.hotp.prepareInput(code1)
+	code2, ok2 := generator.hotp.prepareInput(code2)
+	if !ok1 || !ok2 {
+		return false
+	}
+	movingFactor := generator.MovingFactor(epoch)
+	state := generator.hotp.newMAC()
+	matched, previous := 0, 0
+	for i := -generator.lookBackward; i <= generator.lookForward; i += 1 {
+		first, second := 0, 0
+		if generator.hotp.validateMAC(state, movingFactor+int64(i), code1) {
+			first = 1
+		}
+		if generator.hotp.validateMAC(state, movingFactor+int64(i), code2) {
+			second = 1
+		}
+		matched |= previous & second
+		previous = first
+	}
+	return matched == 1
+}
+
+// ValidateInRange validates whether the one-time password matches any time step overlapping the range from startEpoch
+// to endEpoch inclusive, and gets the earliest epoch within the range that belongs to the matching time step. This is
+// useful when a code may have been generated at any time within a known window, such as while a request was queued.
+// The tolerant time steps are not applied. The range may not span more than 1000 time steps.
+func (generator *totpManager) ValidateInRange(startEpoch, endEpoch int64, code string) (bool, int64, error) {
+	code, ok := generator.hotp.prepareInput(code)
+	if !ok {
+		return false, 0, nil
+	}
+	if endEpoch < startEpoch {
+		return false, 0, errors.New("invalid range")
+	}
+	first, last := generator.MovingFactor(startEpoch), generator.MovingFactor(endEpoch)
+	if last-first >= maxRangeSteps {
+		return false, 0, errors.New("range too large")
+	}
+	state := generator.hotp.newMAC()
+	for movingFactor := first; movingFactor <= last; movingFactor += 1 {
+		if generator.hotp.validateMAC(state, movingFactor, code) {
+			epoch := generator.stepStart(movingFactor)
+			if epoch < startEpoch {
+				epoch = startEpoch
+			}
+			return true, epoch, nil
+		}
+	}
+	return false, 0, nil
+}
+
+// ValidateAt validates whether the one-time password matches the time step of any of the specified epochs, and gets the
+// first matching epoch. The tolerant time steps are not applied. This is useful when the candidate times of the client
+// are known but not contiguous. 0 and false are returned when no epoch matches.
+func (generator *totpManager) ValidateAt(epochs []int64, code string) (int64, bool) {
+	code, ok := generator.hotp.prepareInput(code)
+	if !ok {
+		return 0, false
+	}
+	state := generator.hotp.newMAC()
+	for _, epoch := range epochs {
+		if generator.hotp.validateMAC(state, generator.MovingFactor(epoch), code) {
+			return epoch, true
+		}
+	}
+	return 0, false
+}
+
+func (generator *totpManager) QueryParams(opts ...URIOption) url.Values {
+	params := generator.hotp.params(opts)
+	if generator.stepDuration != 0 {
+		params.Set("period", strconv.FormatFloat(generator.stepDuration.Seconds(), 'f', -1, 64))
+	} else {
+		params.Set("period", strconv.Itoa(generator.timeStep))
+	}
+	return params
+}
+
+func (generator *totpManager) Algorithm() HashAlgorithm {
+	return generator.hotp.Algorithm()
+}
+
+func (generator *totpManager) CodeDigits() int {
+	return generator.hotp.CodeDigits()
+}
+
+// Type gets OTPTypeTOTP, since the manager is a TOTPManager.
+func (generator *totpManager) Type() OTPType {
+	return OTPTypeTOTP
+}
+
+// TimeStep gets the time step in seconds, or 0 if the time step is not a whole number of seconds, as created by
+// NewTOTPDuration. TimeStepDuration gets the time step in any case.
+func (generator *totpManager) TimeStep() int {
+	return generator.timeStep
+}
+
+// TimeStepDuration gets the time step as a duration.
+func (generator *totpManager) TimeStepDuration() time.Duration {
+	if generator.stepDuration != 0 {
+		return generator.stepDuration
+	}
+	return time.Duration(generator.timeStep) * time.Second
+}
+
+// LookBackward gets the tolerant time steps backward accepted when validating.
+func (generator *totpManager) LookBackward() int {
+	return generator.lookBackward
+}
+
+// LookForward gets the tolerant time steps forward accepted when validating.
+func (generator *totpManager) LookForward() int {
+	return generator.lookForward
+}
+
+// Close overwrites the secret key of the manager with zeros and makes the manager unusable. Refers to the HOTP
+// counterpart for details.
+func (generator *totpManager) Close() error {
+	return generator.hotp.Close()
+}
+
+func (generator *totpManager) Secret() []byte {
+	return generator.hotp.Secret()
+}
+
+func (generator *totpManager) SecretBase32() string {
+	return generator.hotp.SecretBase32()
+}
+
+func (generator *totpManager) SecretBase32Grouped() string {
+	return generator.hotp.SecretBase32Grouped()
+}
+
+// deriveT0Label represents the HMAC message used for deriving T0 from the secret key.
+const deriveT0Label = "github.com/zesik/otp T0"
+
+// deriveT0 derives a T0 between 0 inclusive and the time step exclusive from the secret key, as the HMAC of a fixed
+// label, read as a big-endian integer and reduced modulo the time step.
+func (generator *hotpManager) deriveT0(timeStep int) int64 {
+	mac := hmac.New(generator.hashAlgorithm, generator.secret)
+	mac.Write([]byte(deriveT0Label))
+	return int64(binary.BigEndian.Uint64(mac.Sum(nil)) % uint64(timeStep))
 }
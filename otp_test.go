@@ -1,8 +1,20 @@
 package otp
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"hash"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -19,69 +31,1082 @@ func TestNewHOTP(t *testing.T) {
 	}
 }
 
+func TestDefaultKeyByteSize(t *testing.T) {
+	for algorithm, expected := range map[HashAlgorithm]int{
+		HashAlgorithmSHA1:     20,
+		HashAlgorithmSHA256:   32,
+		HashAlgorithmSHA512:   64,
+		HashAlgorithmSHA3_256: 32,
+		HashAlgorithmSHA3_512: 64,
+		HashAlgorithmSHA224:   28,
+		HashAlgorithmSHA384:   48,
+	} {
+		size, err := algorithm.DefaultKeyByteSize()
+		assert.NoError(t, err)
+		assert.Equal(t, expected, size)
+	}
+	if _, err := HashAlgorithm(-1).DefaultKeyByteSize(); assert.Error(t, err) {
+		assert.Equal(t, "unknown hash algorithm", err.Error())
+	}
+	if _, err := HashAlgorithm(-1).generateSecret(rand.Reader, 0); assert.Error(t, err) {
+		assert.Equal(t, "unknown hash algorithm", err.Error())
+	}
+}
+
+func TestHashAlgorithmText(t *testing.T) {
+	for algorithm, name := range map[HashAlgorithm]string{
+		HashAlgorithmSHA1:     "SHA1",
+		HashAlgorithmSHA256:   "SHA256",
+		HashAlgorithmSHA512:   "SHA512",
+		HashAlgorithmSHA3_256: "SHA3-256",
+		HashAlgorithmSHA3_512: "SHA3-512",
+		HashAlgorithmSHA224:   "SHA224",
+		HashAlgorithmSHA384:   "SHA384",
+	} {
+		assert.Equal(t, name, algorithm.String())
+		text, err := algorithm.MarshalText()
+		assert.NoError(t, err)
+		assert.Equal(t, name, string(text))
+		for _, text := range []string{name, strings.ToLower(name)} {
+			var parsed HashAlgorithm
+			assert.NoError(t, parsed.UnmarshalText([]byte(text)))
+			assert.Equal(t, algorithm, parsed)
+		}
+	}
+
+	assert.Equal(t, "unknown", HashAlgorithm(-1).String())
+	_, err := HashAlgorithm(-1).MarshalText()
+	assert.EqualError(t, err, "unknown hash algorithm")
+	parsed := HashAlgorithmSHA256
+	for _, text := range []string{"", "MD5", "SHA-1", "unknown"} {
+		assert.EqualError(t, parsed.UnmarshalText([]byte(text)), "unknown hash algorithm", text)
+		assert.Equal(t, HashAlgorithmSHA256, parsed)
+	}
+
+	data, err := json.Marshal(map[string]HashAlgorithm{"algorithm": HashAlgorithmSHA512})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"algorithm":"SHA512"}`, string(data))
+	var config struct{ Algorithm HashAlgorithm }
+	assert.NoError(t, json.Unmarshal([]byte(`{"Algorithm":"sha256"}`), &config))
+	assert.Equal(t, HashAlgorithmSHA256, config.Algorithm)
+	assert.Error(t, json.Unmarshal([]byte(`{"Algorithm":"MD5"}`), &config))
+}
+
 func TestNewHOTPFailure(t *testing.T) {
 	if _, err := NewHOTP(-1, nil, 6); assert.Error(t, err) {
 		assert.Equal(t, "unknown hash algorithm", err.Error())
 	}
 	if _, err := NewHOTP(HashAlgorithmSHA1, nil, 0); assert.Error(t, err) {
-		assert.Equal(t, "invalid code digit", err.Error())
+		assert.Equal(t, "invalid code digit 0: must be between 1 and 10", err.Error())
+		assert.True(t, errors.Is(err, ErrInvalidCodeDigit))
+	}
+	if _, err := NewHOTP(HashAlgorithmSHA1, nil, 11); assert.Error(t, err) {
+		assert.Equal(t, "invalid code digit 11: must be between 1 and 10", err.Error())
+		assert.True(t, errors.Is(err, ErrInvalidCodeDigit))
+	}
+}
+
+func TestHOTPGenerateRFC(t *testing.T) {
+	for _, testCase := range hotpTestMatrix {
+		secret, _ := hex.DecodeString(testCase.HexSecretString)
+		generator, err := NewHOTP(testCase.HashAlgorithm, secret, testCase.CodeDigits)
+		assert.NoError(t, err)
+		actual := generator.Generate(testCase.MovingFactor)
+		assert.Equal(t, testCase.Expected, actual)
+	}
+}
+
+func TestHOTPValidateRFC(t *testing.T) {
+	for _, testCase := range hotpTestMatrix {
+		secret, _ := hex.DecodeString(testCase.HexSecretString)
+		generator, err := NewHOTP(testCase.HashAlgorithm, secret, testCase.CodeDigits)
+		assert.NoError(t, err)
+		match := generator.Validate(testCase.MovingFactor, testCase.Expected)
+		assert.True(t, match)
+	}
+}
+
+func TestHOTPGenerateRange(t *testing.T) {
+	for _, testCase := range hotpTestMatrix {
+		secret, _ := hex.DecodeString(testCase.HexSecretString)
+		generator, err := NewHOTP(testCase.HashAlgorithm, secret, testCase.CodeDigits)
+		assert.NoError(t, err)
+		codes := generator.GenerateRange(testCase.MovingFactor, 5)
+		if assert.Len(t, codes, 5) {
+			assert.Equal(t, testCase.Expected, codes[0])
+			for i, code := range codes {
+				assert.Equal(t, generator.Generate(testCase.MovingFactor+int64(i)), code)
+			}
+		}
+	}
+
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewHOTP(HashAlgorithmSHA1, secret, 6)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"755224", "287082", "359152", "969429"}, generator.GenerateRange(0, 4))
+	assert.Empty(t, generator.GenerateRange(0, 0))
+	assert.Empty(t, generator.GenerateRange(0, -1))
+	assert.Equal(t, []string{"", "", "755224", "287082"}, generator.GenerateRange(-2, 4))
+}
+
+func TestHOTPNegativeCounter(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewHOTP(HashAlgorithmSHA1, secret, 6)
+	assert.NoError(t, err)
+	_, err = generator.GenerateError(-1)
+	assert.EqualError(t, err, "invalid counter")
+	assert.Equal(t, "", generator.Generate(-1))
+	assert.Equal(t, "", generator.ChallengeResponse(-1, "challenge"))
+
+	// The codes of counters 2^64-1 and 2^64-2, which negative counters would wrap around to.
+	for _, code := range []string{"094451", "488204", ""} {
+		assert.False(t, generator.Validate(-1, code))
+		assert.False(t, generator.Validate(-2, code))
+		assert.False(t, generator.ValidateAutoDigits(-1, code, []int{6}))
+		assert.False(t, generator.ValidateChallengeResponse(-1, "", code))
+	}
+	matched, ok, err := generator.SearchCounter(-2, "755224", 3)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int64(0), matched)
+}
+
+// failingHash represents a hash whose writes fail, except the key pads written by HMAC.
+type failingHash struct {
+	hash.Hash
+}
+
+func (h failingHash) Write(p []byte) (int, error) {
+	if len(p) != h.BlockSize() {
+		return 0, errors.New("write failed")
+	}
+	return h.Hash.Write(p)
+}
+
+func TestGenerateError(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	hotp, err := NewHOTP(HashAlgorithmSHA1, secret, 6)
+	assert.NoError(t, err)
+	code, err := hotp.GenerateError(0)
+	assert.NoError(t, err)
+	assert.Equal(t, "755224", code)
+	totp, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 0, 0)
+	assert.NoError(t, err)
+	code, err = totp.GenerateError(1234567890)
+	assert.NoError(t, err)
+	assert.Equal(t, "89005924", code)
+
+	hotp.(*hotpManager).hashAlgorithm = func() hash.Hash { return failingHash{sha1.New()} }
+	code, err = hotp.GenerateError(0)
+	assert.EqualError(t, err, "write failed")
+	assert.Equal(t, "", code)
+	assert.Equal(t, "", hotp.Generate(0))
+	assert.False(t, hotp.Validate(0, ""))
+	assert.False(t, hotp.Validate(0, "755224"))
+}
+
+func TestTruncate(t *testing.T) {
+	// Example from RFC 4226 section 5.4
+	hashResult, _ := hex.DecodeString("1f8698690e02ca16618550ef7f19da8e945b555a")
+	assert.Equal(t, uint32(0x50ef7f19), truncate(hashResult))
+
+	// The most significant bit of the selected bytes is always cleared
+	hashResult, _ = hex.DecodeString("ffffffff00000000000000000000000000000000")
+	assert.Equal(t, uint32(0x7fffffff), truncate(hashResult))
+	hashResult, _ = hex.DecodeString("000000000000000000000000000000800000010f")
+	assert.Equal(t, uint32(0x00000001), truncate(hashResult))
+}
+
+func TestGenerateRaw(t *testing.T) {
+	// Truncated values of counters 0 to 9 are listed in appendix D of RFC 4226.
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	hotp, err := NewHOTP(HashAlgorithmSHA1, secret, 6)
+	assert.NoError(t, err)
+	for counter, expected := range []uint32{1284755224, 1094287082, 137359152, 1726969429, 1640338314, 868254676,
+		1918287922, 82162583, 673399871, 645520489} {
+		assert.Equal(t, expected, hotp.GenerateRaw(int64(counter)))
+		assert.Equal(t, DecimalEncoder.Encode(uint64(expected), 6), hotp.Generate(int64(counter)))
+	}
+
+	totp, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "89005924", DecimalEncoder.Encode(uint64(totp.GenerateRaw(1234567890)), 8))
+
+	hotp, err = NewHOTP(HashAlgorithmSHA1, secret, 6, WithTruncationOffset(4))
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(0x508d9493), hotp.GenerateRaw(0))
+	assert.Equal(t, "455891", hotp.Generate(0))
+
+	assert.Equal(t, uint32(0), hotp.GenerateRaw(-1))
+	hotp.Close()
+	assert.Equal(t, uint32(0), hotp.GenerateRaw(0))
+}
+
+func TestTruncateCode(t *testing.T) {
+	// Example from RFC 4226 section 5.4, whose 31-bit value is 1357872921
+	hashResult, _ := hex.DecodeString("1f8698690e02ca16618550ef7f19da8e945b555a")
+	assert.Equal(t, "872921", Truncate(hashResult, 6))
+	assert.Equal(t, "57872921", Truncate(hashResult, 8))
+	assert.Equal(t, "1357872921", Truncate(hashResult, 10))
+	assert.Equal(t, "1", Truncate(hashResult, 1))
+	hashResult, _ = hex.DecodeString("000000000000000000000000000000800000010f")
+	assert.Equal(t, "000001", Truncate(hashResult, 6))
+
+	// HMAC results of counters 0 to 2 are listed in appendix D of RFC 4226, and Generate gives the same codes.
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	hotp, err := NewHOTP(HashAlgorithmSHA1, secret, 6)
+	assert.NoError(t, err)
+	for counter, hmacResult := range []string{
+		"cc93cf18508d94934c64b65d8ba7667fb7cde4b0",
+		"75a48a19d4cbe100644e8ac1397eea747a2d33ab",
+		"0bacb7fa082fef30782211938bc1c5e70416ff44",
+	} {
+		hashResult, _ := hex.DecodeString(hmacResult)
+		assert.Equal(t, hotp.Generate(int64(counter)), Truncate(hashResult, 6))
+	}
+
+	for _, testCase := range []struct {
+		HashResult string
+		Digits     int
+	}{
+		{"", 6},
+		{"0f", 6},
+		{"0000000000000000000000000000000f", 6},
+		{"1f8698690e02ca16618550ef7f19da8e945b555a", 0},
+		{"1f8698690e02ca16618550ef7f19da8e945b555a", 11},
+	} {
+		hashResult, _ := hex.DecodeString(testCase.HashResult)
+		assert.Equal(t, "", Truncate(hashResult, testCase.Digits), testCase.HashResult)
+	}
+}
+
+func TestHOTPValidateAutoDigits(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewHOTP(HashAlgorithmSHA1, secret, 6)
+	assert.NoError(t, err)
+	assert.True(t, generator.ValidateAutoDigits(0, "755224", []int{6, 8}))
+	assert.True(t, generator.ValidateAutoDigits(0, "84755224", []int{6, 8}))
+	assert.False(t, generator.ValidateAutoDigits(0, "84755224", []int{6}))
+	assert.False(t, generator.ValidateAutoDigits(0, "4755224", []int{6, 8}))
+	assert.False(t, generator.ValidateAutoDigits(0, "755225", []int{6, 8}))
+	assert.False(t, generator.ValidateAutoDigits(0, "", []int{0}))
+	assert.False(t, generator.ValidateAutoDigits(0, "755224", nil))
+}
+
+func TestHOTPSearchCounter(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewHOTP(HashAlgorithmSHA1, secret, 6)
+	assert.NoError(t, err)
+	counter, match, err := generator.SearchCounter(0, "969429", 5)
+	assert.NoError(t, err)
+	assert.True(t, match)
+	assert.Equal(t, int64(3), counter)
+	counter, match, err = generator.SearchCounter(4, "969429", 5)
+	assert.NoError(t, err)
+	assert.False(t, match)
+	assert.Equal(t, int64(4), counter)
+	counter, match, err = generator.SearchCounter(3, "969429", 0)
+	assert.NoError(t, err)
+	assert.True(t, match)
+	assert.Equal(t, int64(3), counter)
+	if _, _, err := generator.SearchCounter(0, "969429", -1); assert.Error(t, err) {
+		assert.Equal(t, "invalid window", err.Error())
+	}
+}
+
+func TestHOTPSearchCounterAmbiguous(t *testing.T) {
+	// With a single code digit, the codes for counters 0 to 9 are 4, 2, 2, 9, 4, 6, 2, 3, 1 and 9
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewHOTP(HashAlgorithmSHA1, secret, 1)
+	assert.NoError(t, err)
+	counter, match, err := generator.SearchCounter(0, "2", 9)
+	assert.Equal(t, ErrAmbiguousCode, err)
+	assert.True(t, match)
+	assert.Equal(t, int64(1), counter)
+	counter, match, err = generator.SearchCounter(2, "2", 3)
+	assert.NoError(t, err)
+	assert.True(t, match)
+	assert.Equal(t, int64(2), counter)
+	counter, match, err = generator.SearchCounter(0, "3", 9)
+	assert.NoError(t, err)
+	assert.True(t, match)
+	assert.Equal(t, int64(7), counter)
+	_, match, err = generator.SearchCounter(0, "5", 9)
+	assert.NoError(t, err)
+	assert.False(t, match)
+}
+
+func TestHOTPValidateWindow(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewHOTP(HashAlgorithmSHA1, secret, 6)
+	assert.NoError(t, err)
+	for _, testCase := range []struct {
+		Counter  int64
+		Window   int
+		Expected int64
+	}{
+		{3, 5, 3},
+		{1, 5, 3},
+		{0, 3, 3},
+		{3, 0, 3},
+	} {
+		counter, match := generator.ValidateWindow(testCase.Counter, "969429", testCase.Window)
+		assert.True(t, match)
+		assert.Equal(t, testCase.Expected, counter)
+	}
+	for _, testCase := range []struct {
+		Counter int64
+		Window  int
+	}{
+		{4, 5},
+		{0, 2},
+		{0, -1},
+	} {
+		counter, match := generator.ValidateWindow(testCase.Counter, "969429", testCase.Window)
+		assert.False(t, match)
+		assert.Equal(t, testCase.Counter, counter)
+	}
+
+	generator, err = NewHOTP(HashAlgorithmSHA1, secret, 1)
+	assert.NoError(t, err)
+	counter, match := generator.ValidateWindow(0, "2", 9)
+	assert.False(t, match)
+	assert.Equal(t, int64(0), counter)
+}
+
+func TestHOTPResynchronize(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewHOTP(HashAlgorithmSHA1, secret, 6)
+	assert.NoError(t, err)
+	counter, match := generator.Resynchronize(0, "969429", 5)
+	assert.True(t, match)
+	assert.Equal(t, int64(4), counter)
+	counter, match = generator.Resynchronize(3, "969429", 0)
+	assert.True(t, match)
+	assert.Equal(t, int64(4), counter)
+	counter, match = generator.Resynchronize(4, "969429", 5)
+	assert.False(t, match)
+	assert.Equal(t, int64(4), counter)
+	counter, match = generator.Resynchronize(0, "969429", -1)
+	assert.False(t, match)
+	assert.Equal(t, int64(0), counter)
+
+	generator, err = NewHOTP(HashAlgorithmSHA1, secret, 1)
+	assert.NoError(t, err)
+	counter, match = generator.Resynchronize(0, "2", 9)
+	assert.False(t, match)
+	assert.Equal(t, int64(0), counter)
+}
+
+func TestHOTPValidateCanonical(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewHOTP(HashAlgorithmSHA1, secret, 6)
+	assert.NoError(t, err)
+	for _, code := range []string{"755224", " 755 224 ", "755-224", "7 5 5 2 2 4"} {
+		match, canonical := generator.ValidateCanonical(0, code)
+		assert.True(t, match, code)
+		assert.Equal(t, "755224", canonical)
+	}
+	for _, code := range []string{"755225", "", " - ", "75522a", "0755224", "7552244"} {
+		match, canonical := generator.ValidateCanonical(0, code)
+		assert.False(t, match, code)
+		assert.Equal(t, "", canonical)
+	}
+}
+
+func TestHOTPValidateStrict(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewHOTP(HashAlgorithmSHA1, secret, 6)
+	assert.NoError(t, err)
+	match, err := generator.ValidateStrict(0, "755224")
+	assert.NoError(t, err)
+	assert.True(t, match)
+	for _, code := range []string{"755225", "000000", "287082"} {
+		match, err := generator.ValidateStrict(0, code)
+		assert.NoError(t, err, code)
+		assert.False(t, match, code)
+	}
+	for _, code := range []string{"", "75522", "7552244", "75522a", "755 22", "７55224"} {
+		match, err := generator.ValidateStrict(0, code)
+		assert.Equal(t, ErrMalformedCode, err, code)
+		assert.False(t, match, code)
+	}
+
+	for _, testCase := range []struct {
+		Options   []HOTPOption
+		Expected  string
+		Wrong     string
+		Malformed []string
+	}{
+		{[]HOTPOption{WithExpectedPrefix("G-")}, "G-755224", "G-755225", []string{"755224", "H-755224", "G-75522"}},
+		{[]HOTPOption{WithChecksum(true)}, "7552243", "7552240", []string{"755224", "755224a"}},
+		{[]HOTPOption{WithEncoder(HexEncoder)}, "93cf18", "93cf19", []string{"93CF18", "93cf1g", "93cf1"}},
+		{[]HOTPOption{WithEncoder(SteamEncoder)}, SteamEncoder.Encode(1284755224, 6), "222222",
+			[]string{"000000", "22222"}},
+		{[]HOTPOption{WithLenientInput()}, " 755-224 ", "755 225", []string{"755 22", "75522a"}},
+		{[]HOTPOption{WithEncoder(countingEncoder{new(int)})}, "755224", "75522a", []string{"75522", "7552244"}},
+	} {
+		generator, err := NewHOTP(HashAlgorithmSHA1, secret, 6, testCase.Options...)
+		assert.NoError(t, err)
+		match, err := generator.ValidateStrict(0, testCase.Expected)
+		assert.NoError(t, err, testCase.Expected)
+		assert.True(t, match, testCase.Expected)
+		match, err = generator.ValidateStrict(0, testCase.Wrong)
+		assert.NoError(t, err, testCase.Wrong)
+		assert.False(t, match, testCase.Wrong)
+		for _, code := range testCase.Malformed {
+			match, err := generator.ValidateStrict(0, code)
+			assert.Equal(t, ErrMalformedCode, err, code)
+			assert.False(t, match, code)
+		}
+	}
+}
+
+func TestGenerate10Digits(t *testing.T) {
+	// The 10-digit codes are the truncated decimal values listed in appendix D of RFC 4226, zero-padded.
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	hotp, err := NewHOTP(HashAlgorithmSHA1, secret, 10)
+	assert.NoError(t, err)
+	for counter, expected := range []string{"1284755224", "1094287082", "0137359152", "1726969429", "1640338314",
+		"0868254676", "1918287922", "0082162583", "0673399871", "0645520489"} {
+		assert.Equal(t, expected, hotp.Generate(int64(counter)))
+		assert.True(t, hotp.Validate(int64(counter), expected))
+	}
+
+	hotp, err = NewHOTP(HashAlgorithmSHA1, secret, 9)
+	assert.NoError(t, err)
+	assert.Equal(t, "284755224", hotp.Generate(0))
+	assert.Equal(t, "137359152", hotp.Generate(2))
+
+	for _, testCase := range []struct {
+		HashAlgorithm   HashAlgorithm
+		HexSecretString string
+		Epoch           int64
+		Expected        string
+	}{
+		{HashAlgorithmSHA1, "3132333435363738393031323334353637383930", 59, "1094287082"},
+		{HashAlgorithmSHA1, "3132333435363738393031323334353637383930", 1111111109, "0907081804"},
+		{HashAlgorithmSHA512, "3132333435363738393031323334353637383930" +
+			"3132333435363738393031323334353637383930" +
+			"313233343536373839303132333435363738393031323334", 1111111109, "0225091201"},
+	} {
+		secret, _ := hex.DecodeString(testCase.HexSecretString)
+		totp, err := NewTOTP(testCase.HashAlgorithm, secret, 10, 30, 0, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.Expected, totp.Generate(testCase.Epoch))
+		assert.True(t, totp.Validate(testCase.Epoch, testCase.Expected))
+	}
+}
+
+func TestGenerateSHA3(t *testing.T) {
+	// The HMAC results are computed independently with Python's hmac and hashlib modules.
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	for _, testCase := range []struct {
+		HashAlgorithm HashAlgorithm
+		MovingFactor  int64
+		HexHMAC       string
+		Expected      string
+	}{
+		{HashAlgorithmSHA3_256, 0, "0088a34eceb9cc5f255ede7cc52bb3bdee7b0b4f25612a80468643ea80fb7cc3", "22170828"},
+		{HashAlgorithmSHA3_256, 1, "6fc70dae0b6b943ebc239278bcfcd010e70ab22550b376572dcceed68bd6f10a", "09902588"},
+		{HashAlgorithmSHA3_256, 2, "d60fe1162dce90f7f90c4a8530b9ead2572fa9eec20a1b4968da3e2bd4236317", "12810314"},
+		{HashAlgorithmSHA3_512, 0, "67f92ac1c616d53c7e341790fcc48233ea04beb47848e71669fedc043d6388d4" +
+			"6d2dab49e651ebca231a4f9583c28c6b7bf4baf18ddaa67c223edee8620f4692", "17342230"},
+		{HashAlgorithmSHA3_512, 1, "6dc22c6858461cc57923c51cf5a9ffbb4b3fb6c215f7f269b9d084597e129f4a" +
+			"83ad3f6ff7289896c89f15e9deecbacc6a93e2dd1e4434c87e389f6e50c8981d", "04625483"},
+		{HashAlgorithmSHA3_512, 2, "8cc6006d231fdf10a9a2be44f8c113d97485477ec4f06fc6752a735fc1327194" +
+			"b823a7458f84f2cf0c8d8038ffc60d13e3bd4d5e2fff0217842ab5ccd0a0800d", "91819892"},
+	} {
+		hashAlgorithm, err := testCase.HashAlgorithm.hash()
+		assert.NoError(t, err)
+		mac := hmac.New(hashAlgorithm, secret)
+		mac.Write(make([]byte, 7))
+		mac.Write([]byte{byte(testCase.MovingFactor)})
+		assert.Equal(t, testCase.HexHMAC, hex.EncodeToString(mac.Sum(nil)))
+
+		hotp, err := NewHOTP(testCase.HashAlgorithm, secret, 8)
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.Expected, hotp.Generate(testCase.MovingFactor))
+		assert.True(t, hotp.Validate(testCase.MovingFactor, testCase.Expected))
+	}
+
+	for _, testCase := range []struct {
+		HashAlgorithm   HashAlgorithm
+		HexSecretString string
+		Epoch           int64
+		Expected        string
+	}{
+		{HashAlgorithmSHA3_256, "3132333435363738393031323334353637383930313233343536373839303132", 59, "03503818"},
+		{HashAlgorithmSHA3_256, "3132333435363738393031323334353637383930313233343536373839303132", 1111111109,
+			"00384900"},
+		{HashAlgorithmSHA3_512, "3132333435363738393031323334353637383930313233343536373839303132" +
+			"3132333435363738393031323334353637383930313233343536373839303132", 59, "59969185"},
+		{HashAlgorithmSHA3_512, "3132333435363738393031323334353637383930313233343536373839303132" +
+			"3132333435363738393031323334353637383930313233343536373839303132", 1111111109, "46254992"},
+	} {
+		secret, _ := hex.DecodeString(testCase.HexSecretString)
+		totp, err := NewTOTP(testCase.HashAlgorithm, secret, 8, 30, 0, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.Expected, totp.Generate(testCase.Epoch))
+		assert.True(t, totp.Validate(testCase.Epoch, testCase.Expected))
 	}
-	if _, err := NewHOTP(HashAlgorithmSHA1, nil, 10); assert.Error(t, err) {
-		assert.Equal(t, "invalid code digit", err.Error())
+}
+
+func TestGenerateTruncatedSHA2(t *testing.T) {
+	// The HMAC results are computed independently with Python's hmac and hashlib modules.
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	for _, testCase := range []struct {
+		HashAlgorithm HashAlgorithm
+		MovingFactor  int64
+		HexHMAC       string
+		Expected      string
+	}{
+		{HashAlgorithmSHA224, 0, "4c41047933676d42ed147022b724dc1be56acf6bc525556945919289", "42893239"},
+		{HashAlgorithmSHA224, 1, "374c9971149caf4ac4697202fa4e71a9449d89ba7b7d35a867ead644", "45812810"},
+		{HashAlgorithmSHA224, 2, "7d17daad91b8c2cfd6181b3891a3ecfe842e23aed87a5d60fa5ae6e5", "52291286"},
+		{HashAlgorithmSHA384, 0, "3b4f1c208e20564b7320eee5026d2a0486018e576b9015a3" +
+			"4c0aebb6488062edca140c4e7609fb89bacb6322aa05438a", "60502125"},
+		{HashAlgorithmSHA384, 1, "2fc8b64eabc478a68268a3ef8731d21f8f06e4f3b1a7c6c5" +
+			"f618c5d306f85e09273bb0d98dd8515fbc7b4ed75a048927", "46080675"},
+		{HashAlgorithmSHA384, 2, "be63fd32c28fffdf63ec46cad8f27f30ca9f0b0947caf7e2" +
+			"5172596417d31d069bbd02842d37258108de363979017d1a", "87698930"},
+	} {
+		hashAlgorithm, err := testCase.HashAlgorithm.hash()
+		assert.NoError(t, err)
+		mac := hmac.New(hashAlgorithm, secret)
+		mac.Write(make([]byte, 7))
+		mac.Write([]byte{byte(testCase.MovingFactor)})
+		assert.Equal(t, testCase.HexHMAC, hex.EncodeToString(mac.Sum(nil)))
+
+		hotp, err := NewHOTP(testCase.HashAlgorithm, secret, 8)
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.Expected, hotp.Generate(testCase.MovingFactor))
+		assert.True(t, hotp.Validate(testCase.MovingFactor, testCase.Expected))
+	}
+
+	for _, testCase := range []struct {
+		HashAlgorithm   HashAlgorithm
+		HexSecretString string
+		Epoch           int64
+		Expected        string
+	}{
+		{HashAlgorithmSHA224, "31323334353637383930313233343536373839303132333435363738", 59, "32201820"},
+		{HashAlgorithmSHA224, "31323334353637383930313233343536373839303132333435363738", 1111111109, "82019503"},
+		{HashAlgorithmSHA384, "3132333435363738393031323334353637383930" +
+			"3132333435363738393031323334353637383930" + "3132333435363738", 59, "12260385"},
+		{HashAlgorithmSHA384, "3132333435363738393031323334353637383930" +
+			"3132333435363738393031323334353637383930" + "3132333435363738", 1111111109, "93607533"},
+	} {
+		secret, _ := hex.DecodeString(testCase.HexSecretString)
+		totp, err := NewTOTP(testCase.HashAlgorithm, secret, 8, 30, 0, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.Expected, totp.Generate(testCase.Epoch))
+		assert.True(t, totp.Validate(testCase.Epoch, testCase.Expected))
+	}
+}
+
+func TestPowersOfTen(t *testing.T) {
+	expected := uint64(1)
+	for n := 0; n <= MaxCodeDigits; n += 1 {
+		assert.Equal(t, expected, powersOfTen[n], n)
+		assert.Equal(t, math.Pow10(n), float64(powersOfTen[n]), n)
+		expected *= 10
+	}
+}
+
+func BenchmarkHOTPGenerate(b *testing.B) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, _ := NewHOTP(HashAlgorithmSHA1, secret, 6)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i += 1 {
+		generator.Generate(int64(i))
+	}
+}
+
+func BenchmarkTOTPValidateWindow(b *testing.B) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	// The window covers 8 time steps, and the code matches none of them so that all are computed.
+	generator, _ := NewTOTP(HashAlgorithmSHA1, secret, 6, 30, 4, 3)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i += 1 {
+		generator.Validate(1234567890, "000000")
+	}
+}
+
+func BenchmarkHOTPSearchCounter(b *testing.B) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, _ := NewHOTP(HashAlgorithmSHA1, secret, 6)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i += 1 {
+		generator.SearchCounter(0, "000000", 7)
+	}
+}
+
+func TestHOTPQueryParams(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewHOTP(HashAlgorithmSHA256, secret, 6)
+	assert.NoError(t, err)
+	params := generator.QueryParams()
+	assert.Equal(t, "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", params.Get("secret"))
+	assert.Equal(t, "SHA256", params.Get("algorithm"))
+	assert.Equal(t, "6", params.Get("digits"))
+	assert.Equal(t, "0", params.Get("counter"))
+	assert.NotContains(t, params, "period")
+}
+
+func TestHOTPQueryParamsAt(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewHOTP(HashAlgorithmSHA1, secret, 6)
+	assert.NoError(t, err)
+	for _, counter := range []int64{0, 1, 9, 1234567890123} {
+		params, err := generator.QueryParamsAt(counter)
+		assert.NoError(t, err)
+		assert.Equal(t, strconv.FormatInt(counter, 10), params.Get("counter"))
+		assert.Equal(t, "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", params.Get("secret"))
+	}
+	if _, err := generator.QueryParamsAt(-1); assert.Error(t, err) {
+		assert.Equal(t, "invalid counter", err.Error())
+	}
+}
+
+func TestHOTPSecretBase32(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewHOTP(HashAlgorithmSHA1, secret, 6)
+	assert.NoError(t, err)
+	assert.Equal(t, "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", generator.SecretBase32())
+	assert.Equal(t, "gezd gnbv gy3t qojq gezd gnbv gy3t qojq", generator.SecretBase32Grouped())
+
+	generator, err = NewHOTP(HashAlgorithmSHA1, []byte("1234567"), 6, AllowShortSecret())
+	assert.NoError(t, err)
+	assert.Equal(t, "GEZDGNBVGY3Q", generator.SecretBase32())
+	assert.Equal(t, "gezd gnbv gy3q", generator.SecretBase32Grouped())
+
+	generator, err = NewHOTP(HashAlgorithmSHA1, []byte("12345"), 6, AllowShortSecret())
+	assert.NoError(t, err)
+	assert.Equal(t, "gezd gnbv", generator.SecretBase32Grouped())
+}
+
+func TestHOTPSecretBase32GroupedRoundTrip(t *testing.T) {
+	for _, algorithm := range []HashAlgorithm{HashAlgorithmSHA1, HashAlgorithmSHA256, HashAlgorithmSHA512} {
+		generator, err := NewHOTP(algorithm, nil, 6)
+		assert.NoError(t, err)
+		grouped := generator.SecretBase32Grouped()
+		for _, group := range strings.Split(grouped, " ") {
+			assert.True(t, len(group) > 0 && len(group) <= 4)
+		}
+		stripped := strings.ToUpper(strings.ReplaceAll(grouped, " ", ""))
+		assert.Equal(t, generator.SecretBase32(), stripped)
+		decoded, err := secretEncoding.DecodeString(stripped)
+		assert.NoError(t, err)
+		assert.Equal(t, generator.(*hotpManager).secret, decoded)
+	}
+}
+
+func TestSecret(t *testing.T) {
+	for _, algorithm := range []HashAlgorithm{HashAlgorithmSHA1, HashAlgorithmSHA256, HashAlgorithmSHA512} {
+		hotp, err := NewHOTP(algorithm, nil, 6)
+		assert.NoError(t, err)
+		secret := hotp.Secret()
+		assert.Equal(t, hotp.(*hotpManager).secret, secret)
+		code := hotp.Generate(0)
+		for i := range secret {
+			secret[i] ^= 0xff
+		}
+		assert.NotEqual(t, hotp.(*hotpManager).secret, secret)
+		assert.Equal(t, code, hotp.Generate(0))
+
+		totp, err := NewTOTP(algorithm, nil, 6, 30, 0, 0)
+		assert.NoError(t, err)
+		secret = totp.Secret()
+		assert.Equal(t, totp.(*totpManager).hotp.secret, secret)
+		secret[0] ^= 0xff
+		assert.NotEqual(t, totp.(*totpManager).hotp.secret, secret)
+		assert.Equal(t, totp.SecretBase32(), EncodeSecret(totp.Secret()))
+	}
+}
+
+func TestAccessors(t *testing.T) {
+	hotp, err := NewHOTP(HashAlgorithmSHA256, nil, 8, WithChecksum(true))
+	assert.NoError(t, err)
+	assert.Equal(t, HashAlgorithmSHA256, hotp.Algorithm())
+	assert.Equal(t, 8, hotp.CodeDigits())
+
+	totp, err := NewTOTP(HashAlgorithmSHA512, nil, 7, 60, 2, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, HashAlgorithmSHA512, totp.Algorithm())
+	assert.Equal(t, 7, totp.CodeDigits())
+	assert.Equal(t, 60, totp.TimeStep())
+	assert.Equal(t, time.Minute, totp.TimeStepDuration())
+	assert.Equal(t, 2, totp.LookBackward())
+	assert.Equal(t, 1, totp.LookForward())
+
+	totp, err = NewTOTPDuration(HashAlgorithmSHA1, nil, 6, 1500*time.Millisecond, 0, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, totp.TimeStep())
+	assert.Equal(t, 1500*time.Millisecond, totp.TimeStepDuration())
+	assert.Equal(t, 0, totp.LookBackward())
+	assert.Equal(t, 3, totp.LookForward())
+
+	totp, err = NewTOTPWithOptions(HashAlgorithmSHA1)
+	assert.NoError(t, err)
+	assert.Equal(t, 6, totp.CodeDigits())
+	assert.Equal(t, 30, totp.TimeStep())
+	assert.Equal(t, 1, totp.LookBackward())
+	assert.Equal(t, 0, totp.LookForward())
+}
+
+func TestType(t *testing.T) {
+	hotp, err := NewHOTP(HashAlgorithmSHA1, nil, 6)
+	assert.NoError(t, err)
+	totp, err := NewTOTP(HashAlgorithmSHA1, nil, 6, 30, 0, 0)
+	assert.NoError(t, err)
+	session, err := totp.SessionOTP([]byte("session"))
+	assert.NoError(t, err)
+	for _, testCase := range []struct {
+		Manager  Manager
+		Expected OTPType
+	}{
+		{hotp, OTPTypeHOTP},
+		{totp, OTPTypeTOTP},
+		{session, OTPTypeTOTP},
+	} {
+		assert.Equal(t, testCase.Expected, testCase.Manager.Type())
+		uri, err := testCase.Manager.ProvisioningURI("", "alice")
+		assert.NoError(t, err)
+		key, err := ParseKeyURI(uri)
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.Expected, key.Manager.Type())
+	}
+
+	assert.Equal(t, "hotp", OTPTypeHOTP.String())
+	assert.Equal(t, "totp", OTPTypeTOTP.String())
+	assert.Equal(t, "unknown", OTPType(-1).String())
+}
+
+func TestClose(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	hotp, err := NewHOTP(HashAlgorithmSHA1, secret, 6)
+	assert.NoError(t, err)
+	internal := hotp.(*hotpManager).secret
+	assert.NoError(t, hotp.Close())
+	assert.Equal(t, make([]byte, len(secret)), internal)
+	assert.Equal(t, "3132333435363738393031323334353637383930", hex.EncodeToString(secret))
+	assert.Equal(t, "", hotp.Generate(0))
+	code, err := hotp.GenerateError(0)
+	assert.EqualError(t, err, "manager closed")
+	assert.Equal(t, "", code)
+	assert.Equal(t, []string{"", ""}, hotp.GenerateRange(0, 2))
+	assert.False(t, hotp.Validate(0, "755224"))
+	assert.False(t, hotp.Validate(0, ""))
+	assert.False(t, hotp.ValidateChallengeResponse(0, "", ""))
+	_, match := hotp.ValidateWindow(0, "", 3)
+	assert.False(t, match)
+	assert.Empty(t, hotp.Secret())
+	_, err = hotp.ProvisioningURI("Example", "alice")
+	assert.EqualError(t, err, "manager closed")
+	_, err = json.Marshal(hotp)
+	assert.Error(t, err)
+	assert.NoError(t, hotp.Close())
+
+	totp, err := NewTOTP(HashAlgorithmSHA1, nil, 8, 30, 1, 1)
+	assert.NoError(t, err)
+	session, err := totp.SessionOTP([]byte("session"))
+	assert.NoError(t, err)
+	internal = totp.(*totpManager).hotp.secret
+	assert.NoError(t, totp.Close())
+	assert.Equal(t, make([]byte, 20), internal)
+	assert.Equal(t, "", totp.Generate(59))
+	assert.False(t, totp.Validate(59, totp.Generate(59)))
+	_, err = totp.ProvisioningURI("Example", "alice")
+	assert.EqualError(t, err, "manager closed")
+	_, err = json.Marshal(totp)
+	assert.Error(t, err)
+	assert.Len(t, session.Generate(59), 8)
+	assert.True(t, session.Validate(59, session.Generate(59)))
+}
+
+func TestNewTOTP(t *testing.T) {
+	for _, algorithm := range []HashAlgorithm{HashAlgorithmSHA1, HashAlgorithmSHA256, HashAlgorithmSHA512} {
+		generator, err := NewTOTP(algorithm, nil, 6, 30, 0, 0)
+		assert.NoError(t, err)
+		assert.IsType(t, &totpManager{}, generator)
+	}
+}
+
+func TestNewTOTPFailure(t *testing.T) {
+	if _, err := NewTOTP(-1, nil, 6, 30, 0, 0); assert.Error(t, err) {
+		assert.Equal(t, "unknown hash algorithm", err.Error())
+	}
+	if _, err := NewTOTP(HashAlgorithmSHA1, nil, 0, 30, 0, 0); assert.Error(t, err) {
+		assert.Equal(t, "invalid code digit 0: must be between 1 and 10", err.Error())
+		assert.True(t, errors.Is(err, ErrInvalidCodeDigit))
+	}
+	if _, err := NewTOTP(HashAlgorithmSHA1, nil, 11, 30, 0, 0); assert.Error(t, err) {
+		assert.Equal(t, "invalid code digit 11: must be between 1 and 10", err.Error())
+		assert.True(t, errors.Is(err, ErrInvalidCodeDigit))
+	}
+	if _, err := NewTOTP(HashAlgorithmSHA1, nil, 6, 0, 0, 0); assert.Error(t, err) {
+		assert.Equal(t, "invalid time step", err.Error())
+	}
+	if _, err := NewTOTP(HashAlgorithmSHA1, nil, 6, 30, -1, 0); assert.Error(t, err) {
+		assert.Equal(t, "invalid look-backward value", err.Error())
+	}
+	if _, err := NewTOTP(HashAlgorithmSHA1, nil, 6, 30, 0, -1); assert.Error(t, err) {
+		assert.Equal(t, "invalid look-forward value", err.Error())
+	}
+}
+
+func TestNewTOTPWithOptions(t *testing.T) {
+	generator, err := NewTOTPWithOptions(HashAlgorithmSHA256)
+	assert.NoError(t, err)
+	totp := generator.(*totpManager)
+	assert.Len(t, totp.hotp.secret, 32)
+	assert.Equal(t, 6, totp.hotp.codeDigits)
+	assert.Equal(t, 30, totp.timeStep)
+	assert.Equal(t, 1, totp.lookBackward)
+	assert.Equal(t, 0, totp.lookForward)
+
+	for _, testCase := range totpTestMatrix {
+		secret, _ := hex.DecodeString(testCase.HexSecretString)
+		generator, err := NewTOTPWithOptions(testCase.HashAlgorithm, WithSecret(secret),
+			WithDigits(testCase.CodeDigits), WithTimeStep(testCase.TimeStep), WithSkewWindow(0, 0))
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.Expected, generator.Generate(testCase.Epoch))
+	}
+
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err = NewTOTPWithOptions(HashAlgorithmSHA1, WithSecret(secret), WithDigits(8), WithSkewWindow(2, 1),
+		WithEpochOffset(100))
+	assert.NoError(t, err)
+	assert.Equal(t, "89005924", generator.Generate(1234567790))
+	assert.True(t, generator.Validate(1234567790+60, "89005924"))
+	assert.False(t, generator.Validate(1234567790+90, "89005924"))
+	assert.True(t, generator.Validate(1234567790-30, "89005924"))
+	assert.False(t, generator.Validate(1234567790-60, "89005924"))
+
+	generator, err = NewTOTPWithOptions(HashAlgorithmSHA1, WithDigits(8), WithDigits(7))
+	assert.NoError(t, err)
+	assert.Len(t, generator.Generate(0), 7)
+
+	for _, testCase := range []struct {
+		Err      error
+		Expected ParamError
+	}{
+		{second(NewTOTPWithOptions(HashAlgorithmSHA1, WithDigits(0))), ParamError{"codeDigit", 0, 1, MaxCodeDigits}},
+		{second(NewTOTPWithOptions(HashAlgorithmSHA1, WithTimeStep(0))), ParamError{"timeStep", 0, 1, math.MaxInt}},
+		{second(NewTOTPWithOptions(HashAlgorithmSHA1, WithSkewWindow(-1, 0))), ParamError{"lookBackward", -1, 0,
+			math.MaxInt}},
+		{second(NewTOTPWithOptions(HashAlgorithmSHA1, WithSkewWindow(0, -1))), ParamError{"lookForward", -1, 0,
+			math.MaxInt}},
+	} {
+		if paramError, ok := testCase.Err.(*ParamError); assert.True(t, ok) {
+			assert.Equal(t, testCase.Expected, *paramError)
+		}
+	}
+	_, err = NewTOTPWithOptions(-1)
+	assert.EqualError(t, err, "unknown hash algorithm")
+}
+
+func TestNewTOTPBound(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	deviceID := []byte("device-1")
+	generator, err := NewTOTPBound(HashAlgorithmSHA1, secret, 8, 30, 0, 0, deviceID)
+	assert.NoError(t, err)
+	deviceID[0] = 'X'
+	assert.Equal(t, []byte("device-1"), generator.(*totpManager).hotp.binding)
+
+	if _, err := NewTOTPBound(HashAlgorithmSHA1, secret, 8, 30, 0, 0, nil); assert.Error(t, err) {
+		assert.Equal(t, "invalid device identifier", err.Error())
+	}
+	if _, err := NewTOTPBound(HashAlgorithmSHA1, secret, 8, 0, 0, 0, deviceID); assert.Error(t, err) {
+		assert.Equal(t, "invalid time step", err.Error())
+	}
+}
+
+func TestTOTPBoundGenerate(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	unbound, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 0, 0)
+	assert.NoError(t, err)
+	device1, err := NewTOTPBound(HashAlgorithmSHA1, secret, 8, 30, 0, 0, []byte("device-1"))
+	assert.NoError(t, err)
+	device1Again, err := NewTOTPBound(HashAlgorithmSHA1, secret, 8, 30, 0, 0, []byte("device-1"))
+	assert.NoError(t, err)
+	device2, err := NewTOTPBound(HashAlgorithmSHA1, secret, 8, 30, 0, 0, []byte("device-2"))
+	assert.NoError(t, err)
+
+	// HMAC-SHA1 of the time step followed by "device-1", computed with a reference implementation
+	code := device1.Generate(1234567890)
+	assert.Equal(t, "46349172", code)
+	assert.NotEqual(t, unbound.Generate(1234567890), code)
+	assert.True(t, device1Again.Validate(1234567890, code))
+	assert.False(t, device2.Validate(1234567890, code))
+	assert.False(t, unbound.Validate(1234567890, code))
+}
+
+func TestNewTOTPDuration(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewTOTPDuration(HashAlgorithmSHA1, secret, 8, 30*time.Second, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 30, generator.(*totpManager).timeStep)
+	assert.Equal(t, time.Duration(0), generator.(*totpManager).stepDuration)
+	assert.Equal(t, "89005924", generator.Generate(1234567890))
+
+	if _, err := NewTOTPDuration(HashAlgorithmSHA1, secret, 8, 0, 0, 0); assert.Error(t, err) {
+		assert.Equal(t, "invalid time step", err.Error())
+	}
+	if _, err := NewTOTPDuration(HashAlgorithmSHA1, secret, 8, -time.Millisecond, 0, 0); assert.Error(t, err) {
+		assert.Equal(t, "invalid time step", err.Error())
+	}
+	if _, err := NewTOTPDuration(HashAlgorithmSHA1, secret, 8, 500*time.Millisecond, -1, 0); assert.Error(t, err) {
+		assert.Equal(t, "invalid look-backward value", err.Error())
+	}
+}
+
+func TestTOTPSubSecondStep(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	hotp, err := NewHOTP(HashAlgorithmSHA1, secret, 8)
+	assert.NoError(t, err)
+	generator, err := NewTOTPDuration(HashAlgorithmSHA1, secret, 8, 250*time.Millisecond, 1, 0)
+	assert.NoError(t, err)
+
+	base := time.Unix(1234567890, 0)
+	assert.Equal(t, hotp.Generate(4938271560), generator.GenerateTime(base))
+	assert.Equal(t, hotp.Generate(4938271560), generator.GenerateTime(base.Add(249*time.Millisecond)))
+	assert.Equal(t, hotp.Generate(4938271561), generator.GenerateTime(base.Add(250*time.Millisecond)))
+	assert.Equal(t, hotp.Generate(4938271563), generator.GenerateTime(base.Add(999*time.Millisecond)))
+	assert.Equal(t, hotp.Generate(4938271560), generator.Generate(1234567890))
+
+	code := generator.GenerateTime(base)
+	assert.True(t, generator.ValidateTime(base.Add(300*time.Millisecond), code))
+	assert.False(t, generator.ValidateTime(base.Add(500*time.Millisecond), code))
+	assert.False(t, generator.ValidateTime(base.Add(-time.Millisecond), code))
+
+	assert.Equal(t, int64(1234567891), generator.NextChangeTime(1234567890))
+	assert.Equal(t, 1, generator.RemainingSeconds(1234567890))
+	assert.Equal(t, "0.25", generator.QueryParams().Get("period"))
+}
+
+func TestTOTPGenerateTime(t *testing.T) {
+	for _, testCase := range totpTestMatrix {
+		secret, _ := hex.DecodeString(testCase.HexSecretString)
+		generator, err := NewTOTP(testCase.HashAlgorithm, secret, testCase.CodeDigits, testCase.TimeStep, 0, 0)
+		assert.NoError(t, err)
+		actual := generator.GenerateTime(time.Unix(testCase.Epoch, 999999999))
+		assert.Equal(t, testCase.Expected, actual)
+		assert.True(t, generator.ValidateTime(time.Unix(testCase.Epoch, 0), testCase.Expected))
+	}
+}
+
+func TestTOTPGenerateRFC(t *testing.T) {
+	for _, testCase := range totpTestMatrix {
+		secret, _ := hex.DecodeString(testCase.HexSecretString)
+		generator, err := NewTOTP(testCase.HashAlgorithm, secret, testCase.CodeDigits, testCase.TimeStep, 0, 0)
+		assert.NoError(t, err)
+		actual := generator.Generate(testCase.Epoch)
+		assert.Equal(t, testCase.Expected, actual)
 	}
 }
 
-func TestHOTPGenerateRFC(t *testing.T) {
-	for _, testCase := range hotpTestMatrix {
+func TestTOTPGenerateRange(t *testing.T) {
+	for _, testCase := range totpTestMatrix {
 		secret, _ := hex.DecodeString(testCase.HexSecretString)
-		generator, err := NewHOTP(testCase.HashAlgorithm, secret, testCase.CodeDigits)
+		generator, err := NewTOTP(testCase.HashAlgorithm, secret, testCase.CodeDigits, testCase.TimeStep, 0, 0)
 		assert.NoError(t, err)
-		actual := generator.Generate(testCase.MovingFactor)
-		assert.Equal(t, testCase.Expected, actual)
+		codes := generator.GenerateRange(testCase.Epoch, 3)
+		if assert.Len(t, codes, 3) {
+			assert.Equal(t, testCase.Expected, codes[0])
+			for i, code := range codes {
+				assert.Equal(t, generator.Generate(testCase.Epoch+int64(i*testCase.TimeStep)), code)
+			}
+		}
 	}
 }
 
-func TestHOTPValidateRFC(t *testing.T) {
-	for _, testCase := range hotpTestMatrix {
+func TestTOTPNearEpochZero(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewTOTP(HashAlgorithmSHA1, secret, 6, 30, 2, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "755224", generator.Generate(0))
+	assert.True(t, generator.Validate(0, "755224"))
+	assert.True(t, generator.Validate(0, "287082"))
+	assert.True(t, generator.Validate(89, "755224"))
+	assert.False(t, generator.Validate(90, "755224"))
+	match, offset := generator.ValidateWithSkew(59, "755224")
+	assert.True(t, match)
+	assert.Equal(t, -1, offset)
+	assert.Equal(t, []int{0}, generator.ValidateAllOffsets(0, "755224"))
+
+	// Looking backward from time step 0 does not wrap around to the codes of counters 2^64-1 and 2^64-2.
+	for _, code := range []string{"094451", "488204", ""} {
+		assert.False(t, generator.Validate(0, code))
+		assert.False(t, generator.ValidateWithClockError(0, code, time.Minute))
+		assert.Nil(t, generator.ValidateAllOffsets(0, code))
+	}
+
+	// Epochs before 0 belong to negative time steps, which have no code.
+	assert.Equal(t, int64(-1), generator.MovingFactor(-1))
+	_, err = generator.GenerateError(-1)
+	assert.EqualError(t, err, "invalid counter")
+	assert.True(t, generator.Validate(-1, "755224"))
+	assert.False(t, generator.Validate(-1, "094451"))
+}
+
+func TestTOTPValidateRFC(t *testing.T) {
+	for _, testCase := range totpTestMatrix {
 		secret, _ := hex.DecodeString(testCase.HexSecretString)
-		generator, err := NewHOTP(testCase.HashAlgorithm, secret, testCase.CodeDigits)
+		generator, err := NewTOTP(testCase.HashAlgorithm, secret, testCase.CodeDigits, testCase.TimeStep, 0, 0)
 		assert.NoError(t, err)
-		match := generator.Validate(testCase.MovingFactor, testCase.Expected)
+		match := generator.Validate(testCase.Epoch, testCase.Expected)
 		assert.True(t, match)
 	}
 }
 
-func TestNewTOTP(t *testing.T) {
-	for _, algorithm := range []HashAlgorithm{HashAlgorithmSHA1, HashAlgorithmSHA256, HashAlgorithmSHA512} {
-		generator, err := NewTOTP(algorithm, nil, 6, 30, 0, 0)
-		assert.NoError(t, err)
-		assert.IsType(t, &totpManager{}, generator)
-	}
+func TestTOTPValidateContext(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 2, 2)
+	assert.NoError(t, err)
+	match, err := generator.ValidateContext(context.Background(), 1234567890, "89005924")
+	assert.NoError(t, err)
+	assert.True(t, match)
+	match, err = generator.ValidateContext(context.Background(), 1234567950, "89005924")
+	assert.NoError(t, err)
+	assert.True(t, match)
+	match, err = generator.ValidateContext(context.Background(), 1234567890, "89005925")
+	assert.NoError(t, err)
+	assert.False(t, match)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	match, err = generator.ValidateContext(ctx, 1234567890, "89005924")
+	assert.Equal(t, context.Canceled, err)
+	assert.False(t, match)
 }
 
-func TestNewTOTPFailure(t *testing.T) {
-	if _, err := NewTOTP(-1, nil, 6, 30, 0, 0); assert.Error(t, err) {
-		assert.Equal(t, "unknown hash algorithm", err.Error())
-	}
-	if _, err := NewTOTP(HashAlgorithmSHA1, nil, 0, 30, 0, 0); assert.Error(t, err) {
-		assert.Equal(t, "invalid code digit", err.Error())
-	}
-	if _, err := NewTOTP(HashAlgorithmSHA1, nil, 10, 30, 0, 0); assert.Error(t, err) {
-		assert.Equal(t, "invalid code digit", err.Error())
-	}
-	if _, err := NewTOTP(HashAlgorithmSHA1, nil, 6, 0, 0, 0); assert.Error(t, err) {
-		assert.Equal(t, "invalid time step", err.Error())
-	}
-	if _, err := NewTOTP(HashAlgorithmSHA1, nil, 6, 30, -1, 0); assert.Error(t, err) {
-		assert.Equal(t, "invalid look-backward value", err.Error())
-	}
-	if _, err := NewTOTP(HashAlgorithmSHA1, nil, 6, 30, 0, -1); assert.Error(t, err) {
-		assert.Equal(t, "invalid look-forward value", err.Error())
+func TestTOTPValidateWithSkew(t *testing.T) {
+	for _, testCase := range totpTestMatrix {
+		secret, _ := hex.DecodeString(testCase.HexSecretString)
+		generator, err := NewTOTP(testCase.HashAlgorithm, secret, testCase.CodeDigits, testCase.TimeStep, 2, 2)
+		assert.NoError(t, err)
+		step := int64(testCase.TimeStep)
+		if testCase.Epoch < 3*step {
+			// Epochs before the Unix epoch are not covered.
+			continue
+		}
+		for _, offset := range []int{-2, -1, 0, 1, 2} {
+			match, skew := generator.ValidateWithSkew(testCase.Epoch-int64(offset)*step, testCase.Expected)
+			assert.True(t, match, "epoch %d offset %d", testCase.Epoch, offset)
+			assert.Equal(t, offset, skew, "epoch %d offset %d", testCase.Epoch, offset)
+		}
+		for _, offset := range []int64{-3, 3} {
+			match, skew := generator.ValidateWithSkew(testCase.Epoch-offset*step, testCase.Expected)
+			assert.False(t, match, "epoch %d offset %d", testCase.Epoch, offset)
+			assert.Equal(t, 0, skew)
+		}
 	}
 }
 
-func TestTOTPGenerateRFC(t *testing.T) {
-	for _, testCase := range totpTestMatrix {
+func TestTOTPGenerate6Digits(t *testing.T) {
+	for _, testCase := range totp6DigitTestMatrix {
 		secret, _ := hex.DecodeString(testCase.HexSecretString)
 		generator, err := NewTOTP(testCase.HashAlgorithm, secret, testCase.CodeDigits, testCase.TimeStep, 0, 0)
 		assert.NoError(t, err)
@@ -90,8 +1115,8 @@ func TestTOTPGenerateRFC(t *testing.T) {
 	}
 }
 
-func TestTOTPValidateRFC(t *testing.T) {
-	for _, testCase := range totpTestMatrix {
+func TestTOTPValidate6Digits(t *testing.T) {
+	for _, testCase := range totp6DigitTestMatrix {
 		secret, _ := hex.DecodeString(testCase.HexSecretString)
 		generator, err := NewTOTP(testCase.HashAlgorithm, secret, testCase.CodeDigits, testCase.TimeStep, 0, 0)
 		assert.NoError(t, err)
@@ -118,6 +1143,533 @@ func TestTOTPValidateBackwardForward(t *testing.T) {
 	assert.False(t, match)
 }
 
+func TestTOTPStepBoundary(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 0, 0)
+	assert.NoError(t, err)
+
+	// 1234567890 is exactly at the start of time step 41152263
+	assert.Equal(t, int64(41152262), generator.MovingFactor(1234567889))
+	assert.Equal(t, int64(41152263), generator.MovingFactor(1234567890))
+	assert.Equal(t, int64(41152263), generator.MovingFactor(1234567919))
+	assert.Equal(t, int64(41152264), generator.MovingFactor(1234567920))
+
+	assert.NotEqual(t, "89005924", generator.Generate(1234567889))
+	assert.Equal(t, "89005924", generator.Generate(1234567890))
+	assert.Equal(t, "89005924", generator.Generate(1234567919))
+	assert.NotEqual(t, "89005924", generator.Generate(1234567920))
+
+	assert.False(t, generator.Validate(1234567889, "89005924"))
+	assert.True(t, generator.Validate(1234567890, "89005924"))
+	assert.True(t, generator.Validate(1234567919, "89005924"))
+	assert.False(t, generator.Validate(1234567920, "89005924"))
+
+	assert.Equal(t, 1, generator.RemainingSeconds(1234567889))
+	assert.Equal(t, 30, generator.RemainingSeconds(1234567890))
+	assert.Equal(t, 1, generator.RemainingSeconds(1234567919))
+	assert.Equal(t, 30, generator.RemainingSeconds(1234567920))
+
+	assert.Equal(t, int64(1234567890), generator.NextChangeTime(1234567889))
+	assert.Equal(t, int64(1234567920), generator.NextChangeTime(1234567890))
+	assert.Equal(t, int64(1234567920), generator.NextChangeTime(1234567919))
+	assert.Equal(t, int64(1234567950), generator.NextChangeTime(1234567920))
+
+	for epoch := int64(1234567880); epoch < 1234567930; epoch += 1 {
+		next := generator.NextChangeTime(epoch)
+		assert.Equal(t, next, epoch+int64(generator.RemainingSeconds(epoch)))
+		assert.Equal(t, generator.MovingFactor(epoch)+1, generator.MovingFactor(next))
+		assert.Equal(t, generator.MovingFactor(epoch), generator.MovingFactor(next-1))
+	}
+}
+
+func TestTOTPNegativeEpoch(t *testing.T) {
+	generator, err := NewTOTP(HashAlgorithmSHA1, nil, 6, 30, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), generator.MovingFactor(0))
+	assert.Equal(t, int64(-1), generator.MovingFactor(-1))
+	assert.Equal(t, int64(-1), generator.MovingFactor(-30))
+	assert.Equal(t, int64(-2), generator.MovingFactor(-31))
+	assert.Equal(t, generator.Generate(-30), generator.GenerateTime(time.Unix(-1, 0)))
+	assert.NotEqual(t, generator.Generate(0), generator.Generate(-1))
+
+	assert.Equal(t, 1, generator.RemainingSeconds(-1))
+	assert.Equal(t, 30, generator.RemainingSeconds(-30))
+	assert.Equal(t, int64(0), generator.NextChangeTime(-1))
+	assert.Equal(t, int64(-30), generator.NextChangeTime(-31))
+	for epoch := int64(-100); epoch < 100; epoch += 1 {
+		next := generator.NextChangeTime(epoch)
+		assert.Equal(t, next, epoch+int64(generator.RemainingSeconds(epoch)))
+		assert.Equal(t, generator.MovingFactor(epoch)+1, generator.MovingFactor(next))
+		assert.Equal(t, generator.MovingFactor(epoch), generator.MovingFactor(next-1))
+	}
+
+	generator, err = NewTOTPDuration(HashAlgorithmSHA1, nil, 6, 1500*time.Millisecond, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-1), generator.MovingFactor(-1))
+	assert.Equal(t, int64(-2), generator.MovingFactor(-2))
+	assert.Equal(t, int64(-1), generator.NextChangeTime(-2))
+	assert.Equal(t, int64(0), generator.NextChangeTime(-1))
+}
+
+func TestTOTPStepsUntil(t *testing.T) {
+	generator, err := NewTOTP(HashAlgorithmSHA1, nil, 6, 30, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), generator.StepsUntil(1234567890, 1234567890))
+	assert.Equal(t, int64(0), generator.StepsUntil(1234567890, 1234567919))
+	assert.Equal(t, int64(1), generator.StepsUntil(1234567890, 1234567920))
+	assert.Equal(t, int64(1), generator.StepsUntil(1234567905, 1234567949))
+	assert.Equal(t, int64(120), generator.StepsUntil(1234567890, 1234567890+3600))
+	assert.Equal(t, int64(0), generator.StepsUntil(1234567920, 1234567890))
+
+	generator, err = NewTOTPDuration(HashAlgorithmSHA1, nil, 6, 250*time.Millisecond, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(40), generator.StepsUntil(1234567890, 1234567900))
+
+	// Spans whose nanoseconds exceed an int64 are counted without overflowing.
+	assert.Equal(t, int64(4*20000000000), generator.StepsUntil(0, 20000000000))
+	assert.Equal(t, int64(math.MaxInt64), generator.StepsUntil(math.MinInt64, math.MaxInt64))
+	generator, err = NewTOTPDuration(HashAlgorithmSHA1, nil, 6, 1500*time.Millisecond, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(6148914691236517204), generator.StepsUntil(0, math.MaxInt64))
+	assert.Equal(t, int64(math.MaxInt64), generator.StepsUntil(math.MinInt64, math.MaxInt64))
+
+	generator, err = NewTOTP(HashAlgorithmSHA1, nil, 6, 1, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(math.MaxInt64), generator.StepsUntil(0, math.MaxInt64))
+	assert.Equal(t, int64(math.MaxInt64), generator.StepsUntil(math.MinInt64, math.MaxInt64))
+	generator, err = NewTOTP(HashAlgorithmSHA1, nil, 6, 30, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(614891469123651720), generator.StepsUntil(math.MinInt64, math.MaxInt64))
+}
+
+func TestTOTPAcceptedUntil(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1234567919), generator.AcceptedUntil(1234567890))
+	assert.Equal(t, int64(1234567919), generator.AcceptedUntil(1234567919))
+
+	generator, err = NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 2, 1)
+	assert.NoError(t, err)
+	for _, epoch := range []int64{1234567890, 1234567905, 1234567919} {
+		until := generator.AcceptedUntil(epoch)
+		assert.Equal(t, int64(1234567979), until)
+		assert.True(t, generator.Validate(until, "89005924"))
+		assert.False(t, generator.Validate(until+1, "89005924"))
+	}
+}
+
+func TestTOTPAcceptedCodes(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 2, 1)
+	assert.NoError(t, err)
+	codes := generator.AcceptedCodes(1111111109)
+	if assert.Len(t, codes, 4) {
+		assert.Equal(t, "07081804", codes[2])
+		for i, code := range codes {
+			assert.Equal(t, generator.Generate(1111111109+int64(i-2)*30), code)
+			assert.True(t, generator.Validate(1111111109, code))
+		}
+	}
+	assert.False(t, generator.Validate(1111111109, generator.Generate(1111111109-90)))
+	assert.False(t, generator.Validate(1111111109, generator.Generate(1111111109+60)))
+
+	generator, err = NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"94287082"}, generator.AcceptedCodes(59))
+
+	// Time steps before 0 have no code.
+	generator, err = NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 2, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"", "84755224", "94287082"}, generator.AcceptedCodes(30))
+}
+
+func TestTOTPValidateWithRefreshHint(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 1, 1)
+	assert.NoError(t, err)
+	for _, test := range []struct {
+		epoch   int64
+		valid   bool
+		refresh int
+	}{
+		{1234567890, true, 30},
+		{1234567905, true, 15},
+		{1234567919, true, 1},
+		{1234567875, true, 45},
+		{1234567925, true, 0},
+		{1234567950, false, 0},
+		{1234567859, false, 0},
+	} {
+		valid, refresh := generator.ValidateWithRefreshHint(test.epoch, "89005924")
+		assert.Equal(t, test.valid, valid, "epoch %d", test.epoch)
+		assert.Equal(t, test.refresh, refresh, "epoch %d", test.epoch)
+	}
+	valid, refresh := generator.ValidateWithRefreshHint(1234567890, "00000000")
+	assert.False(t, valid)
+	assert.Equal(t, 0, refresh)
+}
+
+func TestTOTPValidateWithExpiry(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 1, 1)
+	assert.NoError(t, err)
+	for _, test := range []struct {
+		epoch  int64
+		valid  bool
+		expiry int
+	}{
+		{1234567890, true, 30},
+		{1234567905, true, 15},
+		{1234567919, true, 1},
+		{1234567920, true, 0},
+		{1234567949, true, 0},
+		{1234567875, true, 45},
+		{1234567860, true, 60},
+		{1234567950, false, 0},
+		{1234567859, false, 0},
+	} {
+		valid, expiry := generator.ValidateWithExpiry(test.epoch, "89005924")
+		assert.Equal(t, test.valid, valid, "epoch %d", test.epoch)
+		assert.Equal(t, test.expiry, expiry, "epoch %d", test.epoch)
+	}
+	valid, expiry := generator.ValidateWithExpiry(1234567890, "00000000")
+	assert.False(t, valid)
+	assert.Equal(t, 0, expiry)
+
+	generator, err = NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 0, 0)
+	assert.NoError(t, err)
+	valid, expiry = generator.ValidateWithExpiry(1234567910, "89005924")
+	assert.True(t, valid)
+	assert.Equal(t, 10, expiry)
+}
+
+func TestTOTPValidateWithClockError(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 0, 0)
+	assert.NoError(t, err)
+	assert.True(t, generator.ValidateWithClockError(1234567890, "89005924", 0))
+	assert.False(t, generator.ValidateWithClockError(1234567880, "89005924", 0))
+	assert.False(t, generator.ValidateWithClockError(1234567880, "89005924", 5*time.Second))
+	assert.True(t, generator.ValidateWithClockError(1234567880, "89005924", 10*time.Second))
+	assert.True(t, generator.ValidateWithClockError(1234567880, "89005924", 9500*time.Millisecond))
+	assert.True(t, generator.ValidateWithClockError(1234567929, "89005924", 10*time.Second))
+	assert.False(t, generator.ValidateWithClockError(1234567930, "89005924", 10*time.Second))
+	assert.False(t, generator.ValidateWithClockError(1234567880, "89005924", -time.Minute))
+
+	// Closed managers generate empty codes, which must not make empty codes match.
+	assert.NoError(t, generator.Close())
+	assert.False(t, generator.ValidateWithClockError(59, "", time.Minute))
+}
+
+func TestTOTPValidateWithClockErrorMultipleSteps(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 1, 0)
+	assert.NoError(t, err)
+	assert.False(t, generator.ValidateWithClockError(1234567890+75, "89005924", 15*time.Second))
+	assert.True(t, generator.ValidateWithClockError(1234567890+75, "89005924", 16*time.Second))
+	assert.True(t, generator.ValidateWithClockError(1234567890+105, "89005924", time.Minute))
+	assert.False(t, generator.ValidateWithClockError(1234567890-60, "89005924", 59*time.Second))
+	assert.True(t, generator.ValidateWithClockError(1234567890-60, "89005924", 60*time.Second))
+}
+
+func TestTOTPValidateAllOffsets(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 1, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0}, generator.ValidateAllOffsets(1234567890, "89005924"))
+	assert.Equal(t, []int{-1}, generator.ValidateAllOffsets(1234567920, "89005924"))
+	assert.Equal(t, []int{2}, generator.ValidateAllOffsets(1234567830, "89005924"))
+	assert.Nil(t, generator.ValidateAllOffsets(1234567800, "89005924"))
+
+	// With a single code digit, the codes for time steps 0 to 9 are 4, 2, 2, 9, 4, 6, 2, 3, 1 and 9
+	generator, err = NewTOTP(HashAlgorithmSHA1, secret, 1, 30, 4, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{-3, -2, 2}, generator.ValidateAllOffsets(4*30, "2"))
+	assert.Equal(t, []int{3}, generator.ValidateAllOffsets(4*30, "3"))
+	assert.Nil(t, generator.ValidateAllOffsets(4*30, "5"))
+
+	// Closed managers generate empty codes, which must not make empty codes match.
+	assert.NoError(t, generator.Close())
+	assert.Nil(t, generator.ValidateAllOffsets(59, ""))
+}
+
+func TestTOTPValidateConsecutive(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 1, 1)
+	assert.NoError(t, err)
+	// Codes of the time steps from 2 before to 2 after the one of epoch 1234567890.
+	hotp, err := NewHOTP(HashAlgorithmSHA1, secret, 8)
+	assert.NoError(t, err)
+	codes := hotp.GenerateRange(generator.MovingFactor(1234567890)-2, 5)
+	assert.Equal(t, "89005924", codes[2])
+
+	// The previous and the current codes, and the current and the next ones, straddle the time step of the epoch.
+	assert.True(t, generator.ValidateConsecutive(1234567890, codes[1], codes[2]))
+	assert.True(t, generator.ValidateConsecutive(1234567890, codes[2], codes[3]))
+	assert.True(t, generator.ValidateConsecutive(1234567919, codes[2], codes[3]))
+	// Swapped order
+	assert.False(t, generator.ValidateConsecutive(1234567890, codes[2], codes[1]))
+	assert.False(t, generator.ValidateConsecutive(1234567890, codes[3], codes[2]))
+	// Gap between the codes
+	assert.False(t, generator.ValidateConsecutive(1234567890, codes[1], codes[3]))
+	// Same code twice
+	assert.False(t, generator.ValidateConsecutive(1234567890, codes[2], codes[2]))
+	// Pairs partly outside the tolerant window
+	assert.False(t, generator.ValidateConsecutive(1234567890, codes[0], codes[1]))
+	assert.False(t, generator.ValidateConsecutive(1234567890, codes[3], codes[4]))
+	assert.True(t, generator.ValidateConsecutive(1234567920, codes[3], codes[4]))
+	assert.False(t, generator.ValidateConsecutive(1234567890, codes[1], "00000000"))
+	assert.False(t, generator.ValidateConsecutive(1234567890, "", codes[2]))
+
+	// A window of a single time step never matches a pair.
+	generator, err = NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 0, 0)
+	assert.NoError(t, err)
+	assert.False(t, generator.ValidateConsecutive(1234567890, codes[1], codes[2]))
+	assert.False(t, generator.ValidateConsecutive(1234567890, codes[2], codes[3]))
+	generator, err = NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 1, 0)
+	assert.NoError(t, err)
+	assert.True(t, generator.ValidateConsecutive(1234567890, codes[1], codes[2]))
+	assert.False(t, generator.ValidateConsecutive(1234567890, codes[2], codes[3]))
+}
+
+func TestTOTPValidateInRange(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 0, 0)
+	assert.NoError(t, err)
+	match, epoch, err := generator.ValidateInRange(1234567800, 1234568000, "89005924")
+	assert.NoError(t, err)
+	assert.True(t, match)
+	assert.Equal(t, int64(1234567890), epoch)
+	match, epoch, err = generator.ValidateInRange(1234567900, 1234568000, "89005924")
+	assert.NoError(t, err)
+	assert.True(t, match)
+	assert.Equal(t, int64(1234567900), epoch)
+	match, epoch, err = generator.ValidateInRange(1234567800, 1234567890, "89005924")
+	assert.NoError(t, err)
+	assert.True(t, match)
+	assert.Equal(t, int64(1234567890), epoch)
+	match, _, err = generator.ValidateInRange(1234567800, 1234567889, "89005924")
+	assert.NoError(t, err)
+	assert.False(t, match)
+	match, _, err = generator.ValidateInRange(1234567920, 1234568000, "89005924")
+	assert.NoError(t, err)
+	assert.False(t, match)
+
+	// Closed managers generate empty codes, which must not make empty codes match.
+	assert.NoError(t, generator.Close())
+	match, _, err = generator.ValidateInRange(0, 59, "")
+	assert.NoError(t, err)
+	assert.False(t, match)
+}
+
+func TestTOTPValidateInRangeFailure(t *testing.T) {
+	generator, err := NewTOTP(HashAlgorithmSHA1, nil, 8, 30, 0, 0)
+	assert.NoError(t, err)
+	if _, _, err := generator.ValidateInRange(1234567890, 1234567889, "89005924"); assert.Error(t, err) {
+		assert.Equal(t, "invalid range", err.Error())
+	}
+	if _, _, err := generator.ValidateInRange(0, 30*maxRangeSteps, "89005924"); assert.Error(t, err) {
+		assert.Equal(t, "range too large", err.Error())
+	}
+	_, _, err = generator.ValidateInRange(0, 30*maxRangeSteps-1, "89005924")
+	assert.NoError(t, err)
+}
+
+func TestTOTPValidateAt(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 1, 1)
+	assert.NoError(t, err)
+	epoch, match := generator.ValidateAt([]int64{59, 1234567890, 1234567919}, "89005924")
+	assert.True(t, match)
+	assert.Equal(t, int64(1234567890), epoch)
+	epoch, match = generator.ValidateAt([]int64{59, 2000000000, 1234567910}, "89005924")
+	assert.True(t, match)
+	assert.Equal(t, int64(1234567910), epoch)
+	epoch, match = generator.ValidateAt([]int64{1234567860, 1234567920}, "89005924")
+	assert.False(t, match)
+	assert.Equal(t, int64(0), epoch)
+	epoch, match = generator.ValidateAt(nil, "89005924")
+	assert.False(t, match)
+	assert.Equal(t, int64(0), epoch)
+
+	// Closed managers generate empty codes, which must not make empty codes match.
+	assert.NoError(t, generator.Close())
+	epoch, match = generator.ValidateAt([]int64{59}, "")
+	assert.False(t, match)
+	assert.Equal(t, int64(0), epoch)
+}
+
+func TestTOTPValidateAutoDigits(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewTOTP(HashAlgorithmSHA1, secret, 6, 30, 1, 0)
+	assert.NoError(t, err)
+	assert.True(t, generator.ValidateAutoDigits(1234567890, "005924", []int{6, 8}))
+	assert.True(t, generator.ValidateAutoDigits(1234567890, "89005924", []int{6, 8}))
+	assert.True(t, generator.ValidateAutoDigits(1234567920, "89005924", []int{6, 8}))
+	assert.False(t, generator.ValidateAutoDigits(1234567950, "89005924", []int{6, 8}))
+	assert.False(t, generator.ValidateAutoDigits(1234567890, "89005924", []int{6}))
+	assert.False(t, generator.ValidateAutoDigits(1234567890, "9005924", []int{6, 8}))
+}
+
+func TestTOTPValidateCanonical(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 1, 0)
+	assert.NoError(t, err)
+	for _, code := range []string{"07081804", "7081804", "0708 1804", "708-1804", "\t07081804\n"} {
+		match, canonical := generator.ValidateCanonical(1111111109, code)
+		assert.True(t, match, code)
+		assert.Equal(t, "07081804", canonical)
+	}
+	match, canonical := generator.ValidateCanonical(1111111109+30, "7081804")
+	assert.True(t, match)
+	assert.Equal(t, "07081804", canonical)
+	match, canonical = generator.ValidateCanonical(1111111109+60, "7081804")
+	assert.False(t, match)
+	assert.Equal(t, "", canonical)
+	match, canonical = generator.ValidateCanonical(1111111109, "007081804")
+	assert.False(t, match)
+	assert.Equal(t, "", canonical)
+}
+
+func TestTOTPValidateStrict(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 1, 0)
+	assert.NoError(t, err)
+	match, err := generator.ValidateStrict(1111111109, "07081804")
+	assert.NoError(t, err)
+	assert.True(t, match)
+	match, err = generator.ValidateStrict(1111111109+30, "07081804")
+	assert.NoError(t, err)
+	assert.True(t, match)
+	match, err = generator.ValidateStrict(1111111109+60, "07081804")
+	assert.NoError(t, err)
+	assert.False(t, match)
+	for _, code := range []string{"7081804", "007081804", "0708180x", "0708 1804"} {
+		match, err := generator.ValidateStrict(1111111109, code)
+		assert.Equal(t, ErrMalformedCode, err, code)
+		assert.False(t, match, code)
+	}
+}
+
+func TestTOTPQueryParams(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewTOTP(HashAlgorithmSHA512, secret, 8, 60, 1, 1)
+	assert.NoError(t, err)
+	params := generator.QueryParams()
+	assert.Equal(t, "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", params.Get("secret"))
+	assert.Equal(t, "SHA512", params.Get("algorithm"))
+	assert.Equal(t, "8", params.Get("digits"))
+	assert.Equal(t, "60", params.Get("period"))
+	assert.NotContains(t, params, "counter")
+}
+
+// countingEncoder represents a decimal encoder counting how many codes it renders.
+type countingEncoder struct {
+	count *int
+}
+
+func (encoder countingEncoder) Encode(value uint64, length int) string {
+	*encoder.count += 1
+	return DecimalEncoder.Encode(value, length)
+}
+
+func TestValidateNoShortCircuit(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	hotp, err := NewHOTP(HashAlgorithmSHA1, secret, 6)
+	assert.NoError(t, err)
+	assert.True(t, hotp.Validate(0, "755224"))
+	for _, code := range []string{"755225", "855224", "000000", "287082"} {
+		assert.False(t, hotp.Validate(0, code), code)
+	}
+
+	var count int
+	totp, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 2, 2, WithEncoder(countingEncoder{&count}))
+	assert.NoError(t, err)
+	for _, epoch := range []int64{1234567890 + 60, 1234567890, 1234567890 - 60} {
+		count = 0
+		assert.True(t, totp.Validate(epoch, "89005924"))
+		assert.Equal(t, 5, count, "epoch %d", epoch)
+	}
+	count = 0
+	assert.False(t, totp.Validate(1234567890, "89005925"))
+	assert.Equal(t, 5, count)
+	count = 0
+	assert.True(t, totp.ValidateWithClockError(1234567890, "89005924", 0))
+	assert.Equal(t, 5, count)
+	count = 0
+	assert.True(t, totp.ValidateAutoDigits(1234567890+60, "89005924", []int{8}))
+	assert.Equal(t, 5, count)
+}
+
+// malformedCodes contains codes that cannot match a 6-digit manager.
+var malformedCodes = []string{"", "12345", "1234567", "12345a", "-12345", "１２３４５６", "755224\x00", " "}
+
+func TestHOTPValidateMalformedCode(t *testing.T) {
+	generator, err := NewHOTP(HashAlgorithmSHA1, nil, 6)
+	assert.NoError(t, err)
+	for _, code := range malformedCodes {
+		assert.False(t, generator.Validate(0, code), code)
+		assert.False(t, generator.ValidateAutoDigits(0, code, []int{6}), code)
+		assert.False(t, generator.ValidateChallengeResponse(0, "challenge", code), code)
+		match, _ := generator.ValidateCanonical(0, code)
+		assert.False(t, match, code)
+		_, match, err := generator.SearchCounter(0, code, 10)
+		assert.False(t, match, code)
+		assert.NoError(t, err, code)
+	}
+}
+
+func TestTOTPValidateMalformedCode(t *testing.T) {
+	generator, err := NewTOTP(HashAlgorithmSHA1, nil, 6, 30, 1, 1)
+	assert.NoError(t, err)
+	for _, code := range malformedCodes {
+		assert.False(t, generator.Validate(1234567890, code), code)
+		assert.False(t, generator.ValidateTime(time.Unix(1234567890, 0), code), code)
+		assert.False(t, generator.ValidateAutoDigits(1234567890, code, []int{6}), code)
+		assert.False(t, generator.ValidateChallengeResponse(1234567890, "challenge", code), code)
+		assert.False(t, generator.ValidateWithClockError(1234567890, code, time.Minute), code)
+		assert.Empty(t, generator.ValidateAllOffsets(1234567890, code), code)
+		match, _ := generator.ValidateCanonical(1234567890, code)
+		assert.False(t, match, code)
+		_, match = generator.ValidateAt([]int64{1234567890}, code)
+		assert.False(t, match, code)
+	}
+	_, match := ValidateAnyConstantTime([]OTPManager{generator}, 1234567890, "12345")
+	assert.False(t, match)
+}
+
+// TestConcurrentValidate shares managers between goroutines, and is meant to be run with the race detector.
+func TestConcurrentValidate(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	hotp, err := NewHOTP(HashAlgorithmSHA1, secret, 6)
+	assert.NoError(t, err)
+	totp, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 1, 1, WithSkewHistogram())
+	assert.NoError(t, err)
+	var wg sync.WaitGroup
+	failures := make(chan string, 32)
+	for i := 0; i < 32; i += 1 {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j += 1 {
+				ok := hotp.Validate(0, "755224") && !hotp.Validate(0, "755225") && hotp.Generate(3) == "969429" &&
+					totp.Validate(1234567890+int64(j%60), "89005924") && !totp.Validate(1234567890, "89005925") &&
+					totp.Generate(1234567890) == "89005924"
+				if !ok {
+					failures <- strconv.Itoa(i)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(failures)
+	for failure := range failures {
+		t.Errorf("goroutine %s got a wrong result", failure)
+	}
+}
+
 type hotpTestVector struct {
 	HashAlgorithm   HashAlgorithm
 	HexSecretString string
@@ -186,3 +1738,44 @@ var totpTestMatrix = []totpTestVector{
 		"3132333435363738393031323334353637383930" +
 		"313233343536373839303132333435363738393031323334", 8, 30, 20000000000, "47863826"},
 }
+
+// totp6DigitTestMatrix contains 6-digit codes for the RFC 6238 secrets and epochs, computed independently with a
+// reference HMAC implementation rather than derived from this package.
+var totp6DigitTestMatrix = []totpTestVector{
+	{HashAlgorithmSHA1, "3132333435363738393031323334353637383930", 6, 30, 59, "287082"},
+	{HashAlgorithmSHA256, "3132333435363738393031323334353637383930" +
+		"313233343536373839303132", 6, 30, 59, "119246"},
+	{HashAlgorithmSHA512, "3132333435363738393031323334353637383930" +
+		"3132333435363738393031323334353637383930" +
+		"313233343536373839303132333435363738393031323334", 6, 30, 59, "693936"},
+	{HashAlgorithmSHA1, "3132333435363738393031323334353637383930", 6, 30, 1111111109, "081804"},
+	{HashAlgorithmSHA256, "3132333435363738393031323334353637383930" +
+		"313233343536373839303132", 6, 30, 1111111109, "084774"},
+	{HashAlgorithmSHA512, "3132333435363738393031323334353637383930" +
+		"3132333435363738393031323334353637383930" +
+		"313233343536373839303132333435363738393031323334", 6, 30, 1111111109, "091201"},
+	{HashAlgorithmSHA1, "3132333435363738393031323334353637383930", 6, 30, 1111111111, "050471"},
+	{HashAlgorithmSHA256, "3132333435363738393031323334353637383930" +
+		"313233343536373839303132", 6, 30, 1111111111, "062674"},
+	{HashAlgorithmSHA512, "3132333435363738393031323334353637383930" +
+		"3132333435363738393031323334353637383930" +
+		"313233343536373839303132333435363738393031323334", 6, 30, 1111111111, "943326"},
+	{HashAlgorithmSHA1, "3132333435363738393031323334353637383930", 6, 30, 1234567890, "005924"},
+	{HashAlgorithmSHA256, "3132333435363738393031323334353637383930" +
+		"313233343536373839303132", 6, 30, 1234567890, "819424"},
+	{HashAlgorithmSHA512, "3132333435363738393031323334353637383930" +
+		"3132333435363738393031323334353637383930" +
+		"313233343536373839303132333435363738393031323334", 6, 30, 1234567890, "441116"},
+	{HashAlgorithmSHA1, "3132333435363738393031323334353637383930", 6, 30, 2000000000, "279037"},
+	{HashAlgorithmSHA256, "3132333435363738393031323334353637383930" +
+		"313233343536373839303132", 6, 30, 2000000000, "698825"},
+	{HashAlgorithmSHA512, "3132333435363738393031323334353637383930" +
+		"3132333435363738393031323334353637383930" +
+		"313233343536373839303132333435363738393031323334", 6, 30, 2000000000, "618901"},
+	{HashAlgorithmSHA1, "3132333435363738393031323334353637383930", 6, 30, 20000000000, "353130"},
+	{HashAlgorithmSHA256, "3132333435363738393031323334353637383930" +
+		"313233343536373839303132", 6, 30, 20000000000, "737706"},
+	{HashAlgorithmSHA512, "3132333435363738393031323334353637383930" +
+		"3132333435363738393031323334353637383930" +
+		"313233343536373839303132333435363738393031323334", 6, 30, 20000000000, "863826"},
+}
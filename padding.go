@@ -0,0 +1,69 @@
+package otp
+
+import (
+	"errors"
+	"strings"
+)
+
+// Padding identifies how decimal codes shorter than the code digits are padded.
+type Padding int
+
+const (
+	// PaddingZero pads codes with leading zeros, such as "07081804", as defined by RFC 4226. It is the default.
+	PaddingZero Padding = iota
+
+	// PaddingNone leaves codes without padding, such as "7081804", so codes may be shorter than the code digits.
+	PaddingNone
+
+	// PaddingSpace pads codes with leading spaces, such as " 7081804".
+	PaddingSpace
+)
+
+// WithPadding sets how generated decimal codes are padded to the code digits, for legacy displays that do not show
+// leading zeros. The HMAC computation and truncation are not affected.
+//
+// With padding other than PaddingZero, codes are validated by their numeric value: leading zeros and spaces of both the
+// expected and the submitted codes are ignored, so "7081804", " 7081804" and "07081804" are equivalent, but codes
+// longer than the code digits are rejected. Codes are not compatible with authenticator apps, and padding can only be
+// used with DecimalEncoder.
+func WithPadding(padding Padding) Option {
+	return hotpOption(func(generator *hotpManager) error {
+		if padding < PaddingZero || padding > PaddingSpace {
+			return errors.New("invalid padding")
+		}
+		generator.padding = padding
+		return nil
+	})
+}
+
+// pad replaces the leading zeros of a zero-padded code according to the padding of the manager. A code of only zeros
+// keeps its last zero.
+func (generator *hotpManager) pad(code string) string {
+	if generator.padding == PaddingZero {
+		return code
+	}
+	zeros := len(code) - len(strings.TrimLeft(code, "0"))
+	if zeros == len(code) && zeros > 0 {
+		zeros -= 1
+	}
+	if generator.padding == PaddingSpace {
+		return strings.Repeat(" ", zeros) + code[zeros:]
+	}
+	return code[zeros:]
+}
+
+// unpad removes the leading spaces and zeros of a code padded with padding other than PaddingZero, so that codes can be
+// compared by their numeric value. A code of only zeros gives "0", and a code of only spaces gives an empty string.
+func (generator *hotpManager) unpad(code string) string {
+	if generator.padding == PaddingZero {
+		return code
+	}
+	code = strings.TrimLeft(code, " ")
+	if code == "" {
+		return ""
+	}
+	if code = strings.TrimLeft(code, "0"); code == "" {
+		return "0"
+	}
+	return code
+}
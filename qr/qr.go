@@ -0,0 +1,77 @@
+// Package qr renders key URIs, such as those created by the ProvisioningURI methods of package otp, as QR codes that
+// authenticator apps can scan. It is a separate package so that package otp does not depend on a QR code encoder.
+package qr
+
+import (
+	"errors"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// RecoveryLevel identifies the error correction level of QR codes. Higher levels tolerate more damage to the printed or
+// displayed code, at the cost of denser codes.
+type RecoveryLevel int
+
+const (
+	// RecoveryLow represents level L, which recovers about 7% of the code.
+	RecoveryLow RecoveryLevel = iota
+
+	// RecoveryMedium represents level M, which recovers about 15% of the code.
+	RecoveryMedium
+
+	// RecoveryQuartile represents level Q, which recovers about 25% of the code.
+	RecoveryQuartile
+
+	// RecoveryHigh represents level H, which recovers about 30% of the code.
+	RecoveryHigh
+)
+
+// Option configures how QR codes are rendered.
+type Option func(*options)
+
+// options represents the resolved options for rendering QR codes.
+type options struct {
+	level RecoveryLevel
+}
+
+// WithRecoveryLevel sets the error correction level of the QR code. RecoveryMedium is used by default, which is what
+// most authenticator apps expect for codes shown on a screen.
+func WithRecoveryLevel(level RecoveryLevel) Option {
+	return func(options *options) {
+		options.level = level
+	}
+}
+
+// QRCode encodes the key URI as a QR code, and gets it as a PNG image of size by size pixels, with a white border
+// around the code as required for scanning. The image may be larger than the specified size if the code has more
+// modules than the size in pixels. The size must be positive.
+//
+// The key URI contains the secret key, so the image is as sensitive as the secret key itself: it should only be shown
+// to the user being enrolled, and must never be logged or cached.
+func QRCode(uri string, size int, opts ...Option) ([]byte, error) {
+	options := options{level: RecoveryMedium}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if uri == "" {
+		return nil, errors.New("invalid URI")
+	}
+	if size <= 0 {
+		return nil, errors.New("invalid size")
+	}
+
+	var level qrcode.RecoveryLevel
+	switch options.level {
+	case RecoveryLow:
+		level = qrcode.Low
+	case RecoveryMedium:
+		level = qrcode.Medium
+	case RecoveryQuartile:
+		level = qrcode.High
+	case RecoveryHigh:
+		level = qrcode.Highest
+	default:
+		return nil, errors.New("invalid recovery level")
+	}
+	return qrcode.Encode(uri, level, size)
+}
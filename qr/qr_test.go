@@ -0,0 +1,80 @@
+package qr
+
+import (
+	"bytes"
+	"encoding/hex"
+	"image/png"
+	"testing"
+
+	"github.com/makiuchi-d/gozxing"
+	zxing "github.com/makiuchi-d/gozxing/qrcode"
+	"github.com/stretchr/testify/assert"
+	"github.com/zesik/otp"
+)
+
+// decode decodes the QR code in the PNG image, and gets its content and error correction level.
+func decode(t *testing.T, image []byte) (string, string) {
+	decoded, err := png.Decode(bytes.NewReader(image))
+	if !assert.NoError(t, err) {
+		return "", ""
+	}
+	bitmap, err := gozxing.NewBinaryBitmapFromImage(decoded)
+	if !assert.NoError(t, err) {
+		return "", ""
+	}
+	result, err := zxing.NewQRCodeReader().Decode(bitmap, nil)
+	if !assert.NoError(t, err) {
+		return "", ""
+	}
+	level, _ := result.GetResultMetadata()[gozxing.ResultMetadataType_ERROR_CORRECTION_LEVEL].(string)
+	return result.GetText(), level
+}
+
+func TestQRCode(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	totp, err := otp.NewTOTP(otp.HashAlgorithmSHA1, secret, 6, 30, 1, 0)
+	assert.NoError(t, err)
+	uri, err := totp.ProvisioningURI("Big Corp", "alice@example.com")
+	assert.NoError(t, err)
+
+	image, err := QRCode(uri, 256)
+	assert.NoError(t, err)
+	config, err := png.DecodeConfig(bytes.NewReader(image))
+	assert.NoError(t, err)
+	assert.Equal(t, 256, config.Width)
+	assert.Equal(t, 256, config.Height)
+	content, level := decode(t, image)
+	assert.Equal(t, uri, content)
+	assert.Equal(t, "M", level)
+
+	for expected, recoveryLevel := range map[string]RecoveryLevel{
+		"L": RecoveryLow,
+		"M": RecoveryMedium,
+		"Q": RecoveryQuartile,
+		"H": RecoveryHigh,
+	} {
+		image, err := QRCode(uri, 300, WithRecoveryLevel(recoveryLevel))
+		assert.NoError(t, err)
+		content, level := decode(t, image)
+		assert.Equal(t, uri, content)
+		assert.Equal(t, expected, level)
+	}
+
+	// Images too small for the code are enlarged.
+	image, err = QRCode(uri, 1)
+	assert.NoError(t, err)
+	config, err = png.DecodeConfig(bytes.NewReader(image))
+	assert.NoError(t, err)
+	assert.True(t, config.Width > 1)
+}
+
+func TestQRCodeFailure(t *testing.T) {
+	_, err := QRCode("", 256)
+	assert.EqualError(t, err, "invalid URI")
+	_, err = QRCode("otpauth://totp/alice?secret=GEZDGNBVGY3TQOJQ", 0)
+	assert.EqualError(t, err, "invalid size")
+	_, err = QRCode("otpauth://totp/alice?secret=GEZDGNBVGY3TQOJQ", 256, WithRecoveryLevel(RecoveryLevel(4)))
+	assert.EqualError(t, err, "invalid recovery level")
+	_, err = QRCode(string(bytes.Repeat([]byte("a"), 8000)), 256)
+	assert.Error(t, err)
+}
@@ -0,0 +1,185 @@
+package otp
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// ErrTooManyAttempts is returned when a validation is rejected without checking the code, because too many attempts
+// for the key have failed recently.
+var ErrTooManyAttempts = errors.New("too many failed attempts")
+
+// AttemptStore records failed validation attempts per key for a RateLimitedManager.
+//
+// The default store keeps records in memory, which only protects a single process. Distributed deployments can
+// implement the interface on top of shared storage, such as a Redis counter with INCR and EXPIREAT. Implementations
+// must be safe for concurrent use.
+type AttemptStore interface {
+	// Failures gets the number of failures recorded for the key, or 0 if there is no record or it expired before the
+	// current epoch.
+	Failures(key string, epoch int64) (int, error)
+
+	// AddFailure atomically increments the number of failures of the key, and gets the new number. A record that does
+	// not exist or expired before the current epoch starts over from 0. The record must be kept at least until the
+	// expiry epoch inclusive, and a later expiry replaces an earlier one.
+	AddFailure(key string, epoch, expiry int64) (int, error)
+
+	// Reset removes the record of the key.
+	Reset(key string) error
+}
+
+// memoryStorePruneSize represents the minimum number of records of the in-memory stores before expired records are
+// pruned.
+const memoryStorePruneSize = 64
+
+// attemptRecord represents the failures recorded for a key.
+type attemptRecord struct {
+	failures int
+	expiry   int64
+}
+
+// memoryAttemptStore represents an attempt store keeping records in memory. It is safe for concurrent use. Expired
+// records are pruned once the number of records has doubled since they were last pruned, so that the cost of pruning
+// is amortized over the failures added.
+type memoryAttemptStore struct {
+	mutex     sync.Mutex
+	records   map[string]attemptRecord
+	pruneSize int
+}
+
+func (store *memoryAttemptStore) Failures(key string, epoch int64) (int, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	record, ok := store.records[key]
+	if !ok || record.expiry < epoch {
+		return 0, nil
+	}
+	return record.failures, nil
+}
+
+func (store *memoryAttemptStore) AddFailure(key string, epoch, expiry int64) (int, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if store.records == nil {
+		store.records = make(map[string]attemptRecord)
+	}
+	if len(store.records) >= store.pruneSize {
+		for recordKey, record := range store.records {
+			if record.expiry < epoch {
+				delete(store.records, recordKey)
+			}
+		}
+		store.pruneSize = max(2*len(store.records), memoryStorePruneSize)
+	}
+	record := store.records[key]
+	if record.expiry < epoch {
+		record = attemptRecord{}
+	}
+	record.failures += 1
+	record.expiry = max(record.expiry, expiry)
+	store.records[key] = record
+	return record.failures, nil
+}
+
+func (store *memoryAttemptStore) Reset(key string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	delete(store.records, key)
+	return nil
+}
+
+// RateLimitedManager wraps a manager to limit failed validation attempts per key, such as a user name, which mitigates
+// brute-forcing codes. Once the maximum number of attempts has failed within the window, further attempts for the key
+// are rejected with ErrTooManyAttempts until the cooldown has passed. A successful validation resets the failures.
+//
+// RateLimitedManager is safe for concurrent use by multiple goroutines. Every attempt is counted as a failure before
+// the code is checked, and the failure is removed if the code matches, so concurrent attempts cannot exceed the limit.
+type RateLimitedManager struct {
+	manager     OTPManager
+	store       AttemptStore
+	maxFailures int
+	window      int64
+	cooldown    int64
+	clock       func() time.Time
+}
+
+// NewRateLimitedManager wraps the manager to allow at most maxFailures failed attempts per key within the window. Each
+// failure keeps the failures of the key for the window, so the failures are forgotten once no attempt has failed for
+// the window. The attempt reaching the limit keeps them for the cooldown instead, if it is longer. Both durations are
+// rounded down to whole seconds, and cannot be shorter than a second. Failures are recorded in memory if store is nil.
+//
+// Out-of-range parameters are reported with a *ParamError.
+func NewRateLimitedManager(manager OTPManager, store AttemptStore, maxFailures int,
+	window, cooldown time.Duration) (*RateLimitedManager, error) {
+	if manager == nil {
+		return nil, errors.New("invalid manager")
+	}
+	if maxFailures <= 0 {
+		return nil, &ParamError{Param: "maxFailures", Value: maxFailures, Min: 1, Max: math.MaxInt}
+	}
+	if window < time.Second {
+		return nil, &ParamError{Param: "window", Value: int(window), Min: int(time.Second), Max: math.MaxInt}
+	}
+	if cooldown < time.Second {
+		return nil, &ParamError{Param: "cooldown", Value: int(cooldown), Min: int(time.Second), Max: math.MaxInt}
+	}
+	if store == nil {
+		store = &memoryAttemptStore{}
+	}
+	return &RateLimitedManager{
+		manager:     manager,
+		store:       store,
+		maxFailures: maxFailures,
+		window:      int64(window / time.Second),
+		cooldown:    int64(cooldown / time.Second),
+		clock:       time.Now,
+	}, nil
+}
+
+// Validate validates whether the one-time password matches for the key like the Validate method of the wrapped
+// manager. ErrTooManyAttempts is returned without checking the code when too many attempts have failed, and errors of
+// the attempt store are returned as they are. The code is rejected whenever an error is returned, except when the
+// failures could not be reset after a successful validation.
+func (limiter *RateLimitedManager) Validate(key string, movingFactor int64, code string) (bool, error) {
+	now := limiter.clock().Unix()
+	failures, err := limiter.store.Failures(key, now)
+	if err != nil {
+		return false, err
+	}
+	if failures >= limiter.maxFailures {
+		return false, ErrTooManyAttempts
+	}
+
+	expiry := now + limiter.window
+	if failures+1 >= limiter.maxFailures {
+		expiry = now + max(limiter.window, limiter.cooldown)
+	}
+	failures, err = limiter.store.AddFailure(key, now, expiry)
+	if err != nil {
+		return false, err
+	}
+	if failures > limiter.maxFailures {
+		return false, ErrTooManyAttempts
+	}
+
+	if !limiter.manager.Validate(movingFactor, code) {
+		return false, nil
+	}
+	return true, limiter.store.Reset(key)
+}
+
+// RemainingAttempts gets the number of attempts for the key that may still fail before further attempts are rejected.
+func (limiter *RateLimitedManager) RemainingAttempts(key string) (int, error) {
+	failures, err := limiter.store.Failures(key, limiter.clock().Unix())
+	if err != nil {
+		return 0, err
+	}
+	return max(limiter.maxFailures-failures, 0), nil
+}
+
+// Reset forgets the failed attempts of the key, such as after the user has been verified in another way.
+func (limiter *RateLimitedManager) Reset(key string) error {
+	return limiter.store.Reset(key)
+}
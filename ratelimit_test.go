@@ -0,0 +1,185 @@
+package otp
+
+import (
+	"encoding/hex"
+	"errors"
+	"math"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestRateLimitedManager creates a rate-limited HOTP manager with the RFC 4226 secret key and a clock that can be
+// advanced.
+func newTestRateLimitedManager(t *testing.T, store AttemptStore, maxFailures int,
+	window, cooldown time.Duration) (*RateLimitedManager, *time.Time) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	hotp, err := NewHOTP(HashAlgorithmSHA1, secret, 6)
+	assert.NoError(t, err)
+	limiter, err := NewRateLimitedManager(hotp, store, maxFailures, window, cooldown)
+	assert.NoError(t, err)
+	now := time.Unix(1234567890, 0)
+	limiter.clock = func() time.Time { return now }
+	return limiter, &now
+}
+
+func TestRateLimitedManager(t *testing.T) {
+	limiter, now := newTestRateLimitedManager(t, nil, 3, time.Minute, 10*time.Minute)
+	match, err := limiter.Validate("alice", 0, "755224")
+	assert.NoError(t, err)
+	assert.True(t, match)
+
+	for remaining := 2; remaining >= 0; remaining -= 1 {
+		match, err = limiter.Validate("alice", 0, "000000")
+		assert.NoError(t, err)
+		assert.False(t, match)
+		attempts, err := limiter.RemainingAttempts("alice")
+		assert.NoError(t, err)
+		assert.Equal(t, remaining, attempts)
+	}
+
+	// Further attempts are rejected without checking the code, even the right one, and do not affect other keys.
+	match, err = limiter.Validate("alice", 0, "755224")
+	assert.Equal(t, ErrTooManyAttempts, err)
+	assert.False(t, match)
+	match, err = limiter.Validate("bob", 0, "755224")
+	assert.NoError(t, err)
+	assert.True(t, match)
+
+	// The attempt reaching the limit keeps the failures for the cooldown.
+	*now = now.Add(9 * time.Minute)
+	_, err = limiter.Validate("alice", 0, "755224")
+	assert.Equal(t, ErrTooManyAttempts, err)
+	*now = now.Add(time.Minute + time.Second)
+	attempts, err := limiter.RemainingAttempts("alice")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+	match, err = limiter.Validate("alice", 0, "755224")
+	assert.NoError(t, err)
+	assert.True(t, match)
+}
+
+func TestRateLimitedManagerResets(t *testing.T) {
+	limiter, now := newTestRateLimitedManager(t, nil, 3, time.Minute, 10*time.Minute)
+	for i := 0; i < 2; i += 1 {
+		match, err := limiter.Validate("alice", 0, "000000")
+		assert.NoError(t, err)
+		assert.False(t, match)
+	}
+	match, err := limiter.Validate("alice", 0, "755224")
+	assert.NoError(t, err)
+	assert.True(t, match)
+	attempts, err := limiter.RemainingAttempts("alice")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+
+	// Failures are forgotten once no attempt has failed for the window.
+	for i := 0; i < 2; i += 1 {
+		_, err = limiter.Validate("alice", 0, "000000")
+		assert.NoError(t, err)
+		*now = now.Add(30 * time.Second)
+	}
+	attempts, err = limiter.RemainingAttempts("alice")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, attempts)
+	*now = now.Add(31 * time.Second)
+	attempts, err = limiter.RemainingAttempts("alice")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+
+	for i := 0; i < 3; i += 1 {
+		_, err = limiter.Validate("alice", 0, "000000")
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, limiter.Reset("alice"))
+	match, err = limiter.Validate("alice", 0, "755224")
+	assert.NoError(t, err)
+	assert.True(t, match)
+}
+
+func TestRateLimitedManagerConcurrent(t *testing.T) {
+	limiter, _ := newTestRateLimitedManager(t, nil, 5, time.Minute, time.Minute)
+	var wait sync.WaitGroup
+	var mutex sync.Mutex
+	checked, rejected := 0, 0
+	for i := 0; i < 50; i += 1 {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			_, err := limiter.Validate("alice", 0, "000000")
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err == ErrTooManyAttempts {
+				rejected += 1
+			} else {
+				checked += 1
+			}
+		}()
+	}
+	wait.Wait()
+	assert.Equal(t, 5, checked)
+	assert.Equal(t, 45, rejected)
+}
+
+func TestRateLimitedManagerPrunes(t *testing.T) {
+	store := &memoryAttemptStore{}
+	limiter, now := newTestRateLimitedManager(t, store, 3, time.Minute, time.Minute)
+	for i := 0; i < 1000; i += 1 {
+		_, err := limiter.Validate(strconv.Itoa(i), 0, "000000")
+		assert.NoError(t, err)
+		*now = now.Add(10 * time.Second)
+	}
+	assert.True(t, len(store.records) <= memoryStorePruneSize)
+
+	// A record that expired but has not been pruned yet starts over.
+	store = &memoryAttemptStore{}
+	failures, err := store.AddFailure("alice", 0, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, failures)
+	failures, err = store.AddFailure("alice", 11, 20)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, failures)
+	assert.Len(t, store.records, 1)
+}
+
+// failingAttemptStore represents an attempt store that always fails.
+type failingAttemptStore struct{}
+
+func (failingAttemptStore) Failures(string, int64) (int, error) {
+	return 0, errors.New("store unavailable")
+}
+
+func (failingAttemptStore) AddFailure(string, int64, int64) (int, error) {
+	return 0, errors.New("store unavailable")
+}
+
+func (failingAttemptStore) Reset(string) error {
+	return errors.New("store unavailable")
+}
+
+func TestRateLimitedManagerStoreFailure(t *testing.T) {
+	limiter, _ := newTestRateLimitedManager(t, failingAttemptStore{}, 3, time.Minute, time.Minute)
+	match, err := limiter.Validate("alice", 0, "755224")
+	assert.EqualError(t, err, "store unavailable")
+	assert.False(t, match)
+	_, err = limiter.RemainingAttempts("alice")
+	assert.EqualError(t, err, "store unavailable")
+	assert.EqualError(t, limiter.Reset("alice"), "store unavailable")
+}
+
+func TestNewRateLimitedManagerFailure(t *testing.T) {
+	hotp, err := NewHOTP(HashAlgorithmSHA1, nil, 6)
+	assert.NoError(t, err)
+	_, err = NewRateLimitedManager(nil, nil, 3, time.Minute, time.Minute)
+	assert.EqualError(t, err, "invalid manager")
+	_, err = NewRateLimitedManager(hotp, nil, 0, time.Minute, time.Minute)
+	assert.Equal(t, &ParamError{Param: "maxFailures", Value: 0, Min: 1, Max: math.MaxInt}, err)
+	_, err = NewRateLimitedManager(hotp, nil, 3, time.Millisecond, time.Minute)
+	assert.Equal(t, &ParamError{Param: "window", Value: int(time.Millisecond), Min: int(time.Second), Max: math.MaxInt},
+		err)
+	_, err = NewRateLimitedManager(hotp, nil, 3, time.Minute, 0)
+	assert.Equal(t, &ParamError{Param: "cooldown", Value: 0, Min: int(time.Second), Max: math.MaxInt}, err)
+}
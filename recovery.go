@@ -0,0 +1,35 @@
+package otp
+
+import "errors"
+
+// maxRecoverySheetSize represents the maximum number of codes on a recovery sheet.
+const maxRecoverySheetSize = 1000
+
+// IndexedCode represents an HOTP code paired with the counter it was generated for.
+type IndexedCode struct {
+	// Index is the counter the code was generated for.
+	Index int64
+
+	// Code is the generated one-time password.
+	Code string
+}
+
+// RecoverySheet generates count consecutive HOTP codes starting at the start counter, each paired with its counter, so
+// that a printed sheet of one-time codes can be numbered and the server knows which counter each line corresponds to.
+// A submitted pair of index and code can be checked with Validate(index, code).
+//
+// Count cannot be greater than 1000.
+func (generator *hotpManager) RecoverySheet(start, count int64) ([]IndexedCode, error) {
+	if start < 0 {
+		return nil, errors.New("invalid counter")
+	}
+	if count < 0 || count > maxRecoverySheetSize {
+		return nil, errors.New("invalid count")
+	}
+	sheet := make([]IndexedCode, count)
+	for i := range sheet {
+		index := start + int64(i)
+		sheet[i] = IndexedCode{Index: index, Code: generator.Generate(index)}
+	}
+	return sheet, nil
+}
@@ -0,0 +1,42 @@
+package otp
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHOTPRecoverySheet(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewHOTP(HashAlgorithmSHA1, secret, 6)
+	assert.NoError(t, err)
+	sheet, err := generator.RecoverySheet(1, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, []IndexedCode{{1, "287082"}, {2, "359152"}, {3, "969429"}}, sheet)
+	for _, line := range sheet {
+		assert.True(t, generator.Validate(line.Index, line.Code))
+	}
+	assert.False(t, generator.Validate(sheet[0].Index, sheet[1].Code))
+
+	sheet, err = generator.RecoverySheet(0, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, sheet)
+	sheet, err = generator.RecoverySheet(0, maxRecoverySheetSize)
+	assert.NoError(t, err)
+	assert.Len(t, sheet, maxRecoverySheetSize)
+}
+
+func TestHOTPRecoverySheetFailure(t *testing.T) {
+	generator, err := NewHOTP(HashAlgorithmSHA1, nil, 6)
+	assert.NoError(t, err)
+	if _, err := generator.RecoverySheet(-1, 10); assert.Error(t, err) {
+		assert.Equal(t, "invalid counter", err.Error())
+	}
+	if _, err := generator.RecoverySheet(0, -1); assert.Error(t, err) {
+		assert.Equal(t, "invalid count", err.Error())
+	}
+	if _, err := generator.RecoverySheet(0, maxRecoverySheetSize+1); assert.Error(t, err) {
+		assert.Equal(t, "invalid count", err.Error())
+	}
+}
@@ -0,0 +1,89 @@
+package otp
+
+import (
+	"errors"
+	"sync"
+)
+
+// UsedCodeStore records the codes accepted by ValidateOnce along with their time steps, so that a code cannot be
+// replayed while it is still within the tolerant window.
+//
+// The default store keeps records in memory, which only protects a single process. Distributed deployments can
+// implement the interface on top of shared storage, such as a Redis SET with the NX and EXAT arguments. A store shared
+// by the managers of several users should scope its records by user, or a user may occasionally be rejected when the
+// code of another user for the same time step happens to be the same. Implementations must be safe for concurrent use.
+type UsedCodeStore interface {
+	// MarkUsed atomically records the code of the specified time step, and reports whether it had not been recorded
+	// before. The record must be kept at least until the expiry epoch inclusive, after which the code is rejected
+	// anyway. The current epoch is given for pruning expired records.
+	MarkUsed(movingFactor int64, code string, epoch, expiry int64) (bool, error)
+}
+
+// usedCode represents a code accepted for a time step.
+type usedCode struct {
+	movingFactor int64
+	code         string
+}
+
+// memoryUsedCodeStore represents a used code store keeping records in memory. It is safe for concurrent use. Expired
+// records are pruned like those of memoryAttemptStore.
+type memoryUsedCodeStore struct {
+	mutex     sync.Mutex
+	expiries  map[usedCode]int64
+	pruneSize int
+}
+
+func (store *memoryUsedCodeStore) MarkUsed(movingFactor int64, code string, epoch, expiry int64) (bool, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if store.expiries == nil {
+		store.expiries = make(map[usedCode]int64)
+	}
+	if len(store.expiries) >= store.pruneSize {
+		for used, usedExpiry := range store.expiries {
+			if usedExpiry < epoch {
+				delete(store.expiries, used)
+			}
+		}
+		store.pruneSize = max(2*len(store.expiries), memoryStorePruneSize)
+	}
+	key := usedCode{movingFactor, code}
+	if usedExpiry, ok := store.expiries[key]; ok && usedExpiry >= epoch {
+		return false, nil
+	}
+	store.expiries[key] = expiry
+	return true, nil
+}
+
+// WithUsedCodeStore makes ValidateOnce record accepted codes in the specified store instead of in memory.
+func WithUsedCodeStore(store UsedCodeStore) TOTPOption {
+	return totpOption(func(generator *totpManager) error {
+		if store == nil {
+			return errors.New("invalid used code store")
+		}
+		generator.used = store
+		return nil
+	})
+}
+
+// ValidateOnce validates whether the one-time password matches within the tolerant time steps like Validate, but
+// rejects a code that has already been accepted by ValidateOnce, which prevents an observed code from being replayed
+// while it is still valid. Records are pruned once the code would no longer be accepted anyway.
+//
+// The code is rejected when the used code store fails, since accepting a possibly replayed code is worse than asking
+// the user to try again.
+func (generator *totpManager) ValidateOnce(epoch int64, code string) bool {
+	code, ok := generator.hotp.prepareInput(code)
+	if !ok {
+		return false
+	}
+	movingFactor := generator.MovingFactor(epoch)
+	offset, ok := generator.matchOffset(movingFactor, code)
+	if !ok {
+		return false
+	}
+	matched := movingFactor + int64(offset)
+	expiry := generator.stepStart(matched+int64(generator.lookBackward)+1) - 1
+	fresh, err := generator.used.MarkUsed(matched, code, epoch, expiry)
+	return err == nil && fresh
+}
@@ -0,0 +1,124 @@
+package otp
+
+import (
+	"encoding/hex"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateOnce(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 1, 0)
+	assert.NoError(t, err)
+	assert.False(t, generator.ValidateOnce(1234567890, "00000000"))
+	assert.True(t, generator.ValidateOnce(1234567890, "89005924"))
+	assert.False(t, generator.ValidateOnce(1234567890, "89005924"))
+	assert.False(t, generator.ValidateOnce(1234567919, "89005924"))
+	assert.False(t, generator.ValidateOnce(1234567920, "89005924"))
+	assert.False(t, generator.ValidateOnce(1234567949, "89005924"))
+	assert.False(t, generator.ValidateOnce(1234567950, "89005924"))
+	assert.True(t, generator.Validate(1234567890, "89005924"))
+
+	next := generator.Generate(1234567920)
+	assert.True(t, generator.ValidateOnce(1234567920, next))
+	assert.False(t, generator.ValidateOnce(1234567921, next))
+}
+
+func TestValidateOncePrunes(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 1, 0)
+	assert.NoError(t, err)
+	store := generator.(*totpManager).used.(*memoryUsedCodeStore)
+	for epoch := int64(1234567890); epoch < 1234567890+30*1000; epoch += 30 {
+		assert.True(t, generator.ValidateOnce(epoch, generator.Generate(epoch)))
+	}
+	assert.True(t, len(store.expiries) <= memoryStorePruneSize)
+
+	// A record that expired but has not been pruned yet no longer rejects the code.
+	store = &memoryUsedCodeStore{}
+	fresh, err := store.MarkUsed(1, "123456", 0, 10)
+	assert.NoError(t, err)
+	assert.True(t, fresh)
+	fresh, err = store.MarkUsed(1, "123456", 11, 20)
+	assert.NoError(t, err)
+	assert.True(t, fresh)
+	fresh, err = store.MarkUsed(1, "123456", 12, 20)
+	assert.NoError(t, err)
+	assert.False(t, fresh)
+	assert.Len(t, store.expiries, 1)
+}
+
+// failingUsedCodeStore represents a used code store that always fails.
+type failingUsedCodeStore struct{}
+
+func (failingUsedCodeStore) MarkUsed(int64, string, int64, int64) (bool, error) {
+	return false, errors.New("store unavailable")
+}
+
+// recordingUsedCodeStore represents a used code store that records its calls.
+type recordingUsedCodeStore struct {
+	memoryUsedCodeStore
+	calls [][4]int64
+}
+
+func (store *recordingUsedCodeStore) MarkUsed(movingFactor int64, code string, epoch, expiry int64) (bool, error) {
+	store.calls = append(store.calls, [4]int64{movingFactor, int64(len(code)), epoch, expiry})
+	return store.memoryUsedCodeStore.MarkUsed(movingFactor, code, epoch, expiry)
+}
+
+func TestWithUsedCodeStore(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	store := &recordingUsedCodeStore{}
+	generator, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 2, 1, WithUsedCodeStore(store))
+	assert.NoError(t, err)
+	assert.True(t, generator.ValidateOnce(1234567890, "89005924"))
+	assert.False(t, generator.ValidateOnce(1234567890, "89005924"))
+	// The code of time step 41152263 is accepted until the end of time step 41152265.
+	assert.Equal(t, [][4]int64{{41152263, 8, 1234567890, 1234567979}, {41152263, 8, 1234567890, 1234567979}},
+		store.calls)
+	assert.Equal(t, int64(1234567979), generator.AcceptedUntil(1234567890))
+
+	generator, err = NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 1, 0, WithUsedCodeStore(failingUsedCodeStore{}))
+	assert.NoError(t, err)
+	assert.False(t, generator.ValidateOnce(1234567890, "89005924"))
+
+	_, err = NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 1, 0, WithUsedCodeStore(nil))
+	assert.EqualError(t, err, "invalid used code store")
+}
+
+func TestValidateOnceSession(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 1, 0)
+	assert.NoError(t, err)
+	session, err := generator.SessionOTP([]byte("session"))
+	assert.NoError(t, err)
+	assert.True(t, session.ValidateOnce(1234567890, session.Generate(1234567890)))
+	assert.True(t, generator.ValidateOnce(1234567890, "89005924"))
+}
+
+func TestValidateOnceConcurrent(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 1, 0)
+	assert.NoError(t, err)
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	accepted := 0
+	for i := 0; i < 16; i += 1 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j += 1 {
+				if generator.ValidateOnce(1234567890+int64(j%2), "89005924") {
+					mutex.Lock()
+					accepted += 1
+					mutex.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, accepted)
+}
@@ -0,0 +1,88 @@
+package otp
+
+import (
+	"crypto/hkdf"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// deriveSecretLabel represents the HKDF info prefix used for deriving secret keys, which separates them from other
+// keys derived from the same master key.
+const deriveSecretLabel = "github.com/zesik/otp secret\x00"
+
+// EncodeSecret encodes a secret key in base32 as defined by RFC 4648, in uppercase and without padding, which is the
+// form authenticator apps and key URIs expect.
+func EncodeSecret(secret []byte) string {
+	return secretEncoding.EncodeToString(secret)
+}
+
+// DeriveSecret derives a secret key of the default key size of the algorithm from a master key and a label, such as the
+// account name, with HKDF as defined by RFC 5869 over the hash algorithm. The same inputs always derive the same secret
+// key, so only the master key needs to be stored, and the secret key of an account can be derived again whenever it is
+// needed. The info of HKDF is a fixed prefix followed by the label, and no salt is used.
+//
+// Anyone who obtains the master key can derive the secret keys of all accounts, and a single account cannot be rotated
+// without changing its label. The master key must be at least 16 bytes long.
+//
+// An error is returned for unknown algorithms and master keys that are too short. Returning a nil secret key instead
+// would not be safe, since managers created with a nil secret key silently generate a random one.
+func DeriveSecret(masterKey []byte, label string, algorithm HashAlgorithm) ([]byte, error) {
+	hash, err := algorithm.hash()
+	if err != nil {
+		return nil, err
+	}
+	if len(masterKey) < minSecretLength {
+		return nil, errors.New("master key too short")
+	}
+	keyByteSize, _ := algorithm.DefaultKeyByteSize()
+	return hkdf.Key(hash, masterKey, nil, deriveSecretLabel+label, keyByteSize)
+}
+
+// SecretFromString decodes a secret key from a string with an optional scheme prefix, such as one stored in an
+// environment variable: "base32:GEZDGNBV...", "hex:3132..." or "base64:MTIz...". Strings without a prefix are decoded
+// as base32, which is how authenticator apps and key URIs represent secret keys. Schemes are matched
+// case-insensitively, base32 follows DecodeSecret, and base64 is accepted with or without padding.
+//
+// An error is returned for unknown schemes, and for invalid or empty secret keys.
+func SecretFromString(s string) ([]byte, error) {
+	scheme, encoded, found := strings.Cut(s, ":")
+	if !found {
+		return DecodeSecret(s)
+	}
+	var secret []byte
+	var err error
+	switch strings.ToLower(scheme) {
+	case "base32":
+		return DecodeSecret(encoded)
+	case "hex":
+		secret, err = hex.DecodeString(encoded)
+	case "base64":
+		secret, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(encoded, "="))
+	default:
+		return nil, errors.New("unknown secret scheme")
+	}
+	if err != nil || len(secret) == 0 {
+		return nil, errors.New("invalid secret")
+	}
+	return secret, nil
+}
+
+// DecodeSecret decodes a secret key encoded in base32, following the conventions of authenticator apps: whitespace is
+// ignored, letters are accepted in either case and padding is optional. This accepts secret keys as typed by users,
+// such as the grouped form of SecretBase32Grouped. An error is returned for invalid or empty secret keys.
+func DecodeSecret(s string) ([]byte, error) {
+	encoded := strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return unicode.ToUpper(r)
+	}, s)
+	secret, err := secretEncoding.DecodeString(strings.TrimRight(encoded, "="))
+	if err != nil || len(secret) == 0 {
+		return nil, errors.New("invalid secret")
+	}
+	return secret, nil
+}
@@ -0,0 +1,126 @@
+package otp
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeSecret(t *testing.T) {
+	for _, testCase := range []struct {
+		Secret   string
+		Expected string
+	}{
+		{"1", "GE"},
+		{"12", "GEZA"},
+		{"123", "GEZDG"},
+		{"1234", "GEZDGNA"},
+		{"12345", "GEZDGNBV"},
+		{"123456", "GEZDGNBVGY"},
+		{"12345678901234567890", "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"},
+	} {
+		assert.Equal(t, testCase.Expected, EncodeSecret([]byte(testCase.Secret)))
+	}
+}
+
+func TestDecodeSecret(t *testing.T) {
+	for _, testCase := range []struct {
+		Encoded  string
+		Expected string
+	}{
+		{"GE", "1"},
+		{"GE======", "1"},
+		{"GEZA", "12"},
+		{"geza====", "12"},
+		{"GEZDG", "123"},
+		{"GEZDG===", "123"},
+		{"GEZDGNA", "1234"},
+		{"gezd gna=", "1234"},
+		{"GEZDGNBV", "12345"},
+		{"GEZDGNBVGY", "123456"},
+		{"gezd gnbv gy3t qojq gezd gnbv gy3t qojq", "12345678901234567890"},
+		{" GEZDGNBV\tGY3TQOJQ\nGEZDGNBVGY3TQOJQ ", "12345678901234567890"},
+	} {
+		secret, err := DecodeSecret(testCase.Encoded)
+		assert.NoError(t, err, testCase.Encoded)
+		assert.Equal(t, []byte(testCase.Expected), secret, testCase.Encoded)
+	}
+
+	for _, encoded := range []string{"", " ", "====", "GEZDGNBVGY3TQOJ1", "GEZ", "GE-ZA"} {
+		_, err := DecodeSecret(encoded)
+		assert.EqualError(t, err, "invalid secret", encoded)
+	}
+}
+
+func TestDecodeSecretRoundTrip(t *testing.T) {
+	for length := 1; length <= 64; length += 1 {
+		secret := make([]byte, length)
+		for i := range secret {
+			secret[i] = byte(i * 37)
+		}
+		decoded, err := DecodeSecret(EncodeSecret(secret))
+		assert.NoError(t, err)
+		assert.Equal(t, secret, decoded)
+	}
+}
+
+func TestSecretFromString(t *testing.T) {
+	for _, s := range []string{
+		"GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+		"gezd gnbv gy3t qojq gezd gnbv gy3t qojq",
+		"base32:GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+		"BASE32:gezdgnbvgy3tqojqgezdgnbvgy3tqojq",
+		"hex:3132333435363738393031323334353637383930",
+		"Hex:3132333435363738393031323334353637383930",
+		"base64:MTIzNDU2Nzg5MDEyMzQ1Njc4OTA=",
+		"base64:MTIzNDU2Nzg5MDEyMzQ1Njc4OTA",
+	} {
+		secret, err := SecretFromString(s)
+		assert.NoError(t, err, s)
+		assert.Equal(t, []byte("12345678901234567890"), secret, s)
+	}
+
+	for _, s := range []string{"", "base32:", "hex:", "base64:", "GEZDGNBVGY3TQOJ1", "base32:GEZ", "hex:313", "hex:zz",
+		"base64:MTIz*", "base64:MTIzN"} {
+		_, err := SecretFromString(s)
+		assert.EqualError(t, err, "invalid secret", s)
+	}
+	for _, s := range []string{"base58:2bJ9", ":GEZDGNBV", "env:OTP_SECRET"} {
+		_, err := SecretFromString(s)
+		assert.EqualError(t, err, "unknown secret scheme", s)
+	}
+}
+
+func TestDeriveSecret(t *testing.T) {
+	// Expected values computed with a reference implementation of HKDF
+	masterKey := []byte("12345678901234567890")
+	secret, err := DeriveSecret(masterKey, "alice@example.com", HashAlgorithmSHA1)
+	assert.NoError(t, err)
+	assert.Equal(t, "8a64477cd28fca10c647d3deef90a0153d1e0271", hex.EncodeToString(secret))
+	secret, err = DeriveSecret(masterKey, "alice@example.com", HashAlgorithmSHA256)
+	assert.NoError(t, err)
+	assert.Equal(t, "84a32effda1b5bc5af89af1ef9352bdf3aae0a3250c9982c7a669500e443accd", hex.EncodeToString(secret))
+
+	for _, algorithm := range []HashAlgorithm{HashAlgorithmSHA1, HashAlgorithmSHA256, HashAlgorithmSHA512,
+		HashAlgorithmSHA3_256, HashAlgorithmSHA3_512, HashAlgorithmSHA224, HashAlgorithmSHA384} {
+		secret, err := DeriveSecret(masterKey, "alice", algorithm)
+		assert.NoError(t, err)
+		size, _ := algorithm.DefaultKeyByteSize()
+		assert.Len(t, secret, size)
+		again, err := DeriveSecret(masterKey, "alice", algorithm)
+		assert.NoError(t, err)
+		assert.Equal(t, secret, again)
+		other, err := DeriveSecret(masterKey, "bob", algorithm)
+		assert.NoError(t, err)
+		assert.NotEqual(t, secret, other)
+		other, err = DeriveSecret([]byte("09876543210987654321"), "alice", algorithm)
+		assert.NoError(t, err)
+		assert.NotEqual(t, secret, other)
+	}
+
+	_, err = DeriveSecret(masterKey[:15], "alice", HashAlgorithmSHA1)
+	assert.EqualError(t, err, "master key too short")
+	_, err = DeriveSecret(masterKey, "alice", HashAlgorithm(-1))
+	assert.EqualError(t, err, "unknown hash algorithm")
+}
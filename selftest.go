@@ -0,0 +1,88 @@
+package otp
+
+import "fmt"
+
+const (
+	// selfTestSecret20 represents the 20-byte secret key of the test vectors of RFC 4226 and RFC 6238.
+	selfTestSecret20 = "12345678901234567890"
+
+	// selfTestSecret32 represents the 32-byte secret key of the SHA-256 test vectors of RFC 6238.
+	selfTestSecret32 = "12345678901234567890123456789012"
+
+	// selfTestSecret64 represents the 64-byte secret key of the SHA-512 test vectors of RFC 6238.
+	selfTestSecret64 = "1234567890123456789012345678901234567890123456789012345678901234"
+)
+
+// selfTestVector represents a known code checked by SelfTest. The moving factor is the counter of HOTP vectors and the
+// epoch of TOTP vectors, which use 30-second time steps. The code digits are the length of the expected code.
+type selfTestVector struct {
+	kind         OTPType
+	algorithm    HashAlgorithm
+	secret       string
+	movingFactor int64
+	expected     string
+}
+
+// selfTestVectors contains the test vectors of appendix D of RFC 4226 and appendix B of RFC 6238, along with codes of
+// the hash algorithms not covered by the RFCs computed independently with Python's hmac and hashlib modules.
+var selfTestVectors = []selfTestVector{
+	{OTPTypeHOTP, HashAlgorithmSHA1, selfTestSecret20, 0, "755224"},
+	{OTPTypeHOTP, HashAlgorithmSHA1, selfTestSecret20, 1, "287082"},
+	{OTPTypeHOTP, HashAlgorithmSHA1, selfTestSecret20, 2, "359152"},
+	{OTPTypeHOTP, HashAlgorithmSHA1, selfTestSecret20, 3, "969429"},
+	{OTPTypeHOTP, HashAlgorithmSHA1, selfTestSecret20, 4, "338314"},
+	{OTPTypeHOTP, HashAlgorithmSHA1, selfTestSecret20, 5, "254676"},
+	{OTPTypeHOTP, HashAlgorithmSHA1, selfTestSecret20, 6, "287922"},
+	{OTPTypeHOTP, HashAlgorithmSHA1, selfTestSecret20, 7, "162583"},
+	{OTPTypeHOTP, HashAlgorithmSHA1, selfTestSecret20, 8, "399871"},
+	{OTPTypeHOTP, HashAlgorithmSHA1, selfTestSecret20, 9, "520489"},
+	{OTPTypeTOTP, HashAlgorithmSHA1, selfTestSecret20, 59, "94287082"},
+	{OTPTypeTOTP, HashAlgorithmSHA256, selfTestSecret32, 59, "46119246"},
+	{OTPTypeTOTP, HashAlgorithmSHA512, selfTestSecret64, 59, "90693936"},
+	{OTPTypeTOTP, HashAlgorithmSHA1, selfTestSecret20, 1111111109, "07081804"},
+	{OTPTypeTOTP, HashAlgorithmSHA256, selfTestSecret32, 1111111109, "68084774"},
+	{OTPTypeTOTP, HashAlgorithmSHA512, selfTestSecret64, 1111111109, "25091201"},
+	{OTPTypeTOTP, HashAlgorithmSHA1, selfTestSecret20, 1234567890, "89005924"},
+	{OTPTypeTOTP, HashAlgorithmSHA256, selfTestSecret32, 1234567890, "91819424"},
+	{OTPTypeTOTP, HashAlgorithmSHA512, selfTestSecret64, 1234567890, "93441116"},
+	{OTPTypeTOTP, HashAlgorithmSHA1, selfTestSecret20, 20000000000, "65353130"},
+	{OTPTypeTOTP, HashAlgorithmSHA256, selfTestSecret32, 20000000000, "77737706"},
+	{OTPTypeTOTP, HashAlgorithmSHA512, selfTestSecret64, 20000000000, "47863826"},
+	{OTPTypeHOTP, HashAlgorithmSHA3_256, selfTestSecret20, 1, "09902588"},
+	{OTPTypeHOTP, HashAlgorithmSHA3_512, selfTestSecret20, 1, "04625483"},
+	{OTPTypeHOTP, HashAlgorithmSHA224, selfTestSecret20, 1, "45812810"},
+	{OTPTypeHOTP, HashAlgorithmSHA384, selfTestSecret20, 1, "46080675"},
+}
+
+// SelfTest generates and validates the codes of the test vectors of RFC 4226 and RFC 6238, and of known codes of every
+// other supported hash algorithm, and returns an error describing the first mismatch if any. It is a cheap check that
+// the library and the hash functions it relies on behave correctly, such as at startup of services with integrity
+// requirements.
+func SelfTest() error {
+	return runSelfTest(selfTestVectors)
+}
+
+// runSelfTest checks the codes of the test vectors like SelfTest.
+func runSelfTest(vectors []selfTestVector) error {
+	for _, vector := range vectors {
+		var manager Manager
+		var err error
+		if vector.kind == OTPTypeTOTP {
+			manager, err = NewTOTP(vector.algorithm, []byte(vector.secret), len(vector.expected), 30, 0, 0)
+		} else {
+			manager, err = NewHOTP(vector.algorithm, []byte(vector.secret), len(vector.expected))
+		}
+		if err != nil {
+			return fmt.Errorf("self-test failed: %w", err)
+		}
+		code := manager.Generate(vector.movingFactor)
+		valid := manager.Validate(vector.movingFactor, vector.expected)
+		manager.Close()
+		if code != vector.expected || !valid {
+			name, _ := vector.algorithm.name()
+			return fmt.Errorf("self-test failed: %s %s code of moving factor %d is %q instead of %q", vector.kind, name,
+				vector.movingFactor, code, vector.expected)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package otp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelfTest(t *testing.T) {
+	assert.NoError(t, SelfTest())
+
+	// Every supported hash algorithm is covered.
+	covered := map[HashAlgorithm]bool{}
+	for _, vector := range selfTestVectors {
+		covered[vector.algorithm] = true
+	}
+	for _, algorithm := range []HashAlgorithm{HashAlgorithmSHA1, HashAlgorithmSHA256, HashAlgorithmSHA512,
+		HashAlgorithmSHA3_256, HashAlgorithmSHA3_512, HashAlgorithmSHA224, HashAlgorithmSHA384} {
+		assert.True(t, covered[algorithm], "%v", algorithm)
+	}
+
+	err := runSelfTest([]selfTestVector{
+		{OTPTypeHOTP, HashAlgorithmSHA1, selfTestSecret20, 0, "755224"},
+		{OTPTypeTOTP, HashAlgorithmSHA256, selfTestSecret32, 59, "46119247"},
+	})
+	assert.EqualError(t, err, `self-test failed: totp SHA256 code of moving factor 59 is "46119246" instead of "46119247"`)
+	err = runSelfTest([]selfTestVector{{OTPTypeHOTP, HashAlgorithm(-1), selfTestSecret20, 0, "755224"}})
+	assert.EqualError(t, err, "self-test failed: unknown hash algorithm")
+}
@@ -0,0 +1,28 @@
+package otp
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// SessionOTP derives a manager whose codes are only valid within the session with the specified identifier, for
+// step-up authentication within an existing session. The HMAC message of the derived manager is the time step,
+// followed by the device identifier if the manager is bound to one, followed by the session identifier and its length
+// as a 4-byte big-endian integer, so a code harvested outside the session is not accepted inside it. The trailing
+// length keeps the boundary between device and session identifiers unambiguous, so that device "device-" with session
+// "1" does not get the codes of device "device-1" with session "". All other settings, including the secret key, are
+// the same as those of the original manager. The derived manager holds its own copy of the secret key and of all
+// other mutable state like the copies of Clone, so it must be closed separately, and validating with it never affects
+// the original manager.
+//
+// The session identifier must stay the same for the whole lifetime of the session, or codes shown to the user will
+// stop validating.
+func (generator *totpManager) SessionOTP(sessionID []byte) (TOTPManager, error) {
+	if len(sessionID) == 0 {
+		return nil, errors.New("invalid session identifier")
+	}
+	session := generator.copy()
+	session.hotp.binding = append(session.hotp.binding, sessionID...)
+	session.hotp.binding = binary.BigEndian.AppendUint32(session.hotp.binding, uint32(len(sessionID)))
+	return session, nil
+}
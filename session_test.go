@@ -0,0 +1,68 @@
+package otp
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTOTPSessionOTP(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 1, 0)
+	assert.NoError(t, err)
+	sessionID := []byte("session-1")
+	session, err := generator.SessionOTP(sessionID)
+	assert.NoError(t, err)
+	sessionID[0] = 'X'
+
+	// HMAC-SHA1 of the time step followed by "session-1" and its length, computed with a reference implementation
+	code := session.Generate(1234567890)
+	assert.Equal(t, "12837016", code)
+	assert.True(t, session.Validate(1234567920, code))
+	assert.False(t, generator.Validate(1234567890, code))
+	assert.False(t, session.Validate(1234567890, generator.Generate(1234567890)))
+	assert.Equal(t, "89005924", generator.Generate(1234567890))
+
+	other, err := generator.SessionOTP([]byte("session-2"))
+	assert.NoError(t, err)
+	assert.False(t, other.Validate(1234567890, code))
+
+	if _, err := generator.SessionOTP(nil); assert.Error(t, err) {
+		assert.Equal(t, "invalid session identifier", err.Error())
+	}
+}
+
+func TestTOTPSessionOTPBound(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	device, err := NewTOTPBound(HashAlgorithmSHA1, secret, 8, 30, 0, 0, []byte("device-"))
+	assert.NoError(t, err)
+	session, err := device.SessionOTP([]byte("1"))
+	assert.NoError(t, err)
+	other, err := NewTOTPBound(HashAlgorithmSHA1, secret, 8, 30, 0, 0, []byte("device-1"))
+	assert.NoError(t, err)
+	otherSession, err := other.SessionOTP([]byte{0})
+	assert.NoError(t, err)
+
+	// Moving bytes between the device and session identifiers changes the codes.
+	assert.NotEqual(t, other.Generate(1234567890), session.Generate(1234567890))
+	assert.NotEqual(t, otherSession.Generate(1234567890), session.Generate(1234567890))
+	assert.Equal(t, []byte("device-"), device.(*totpManager).hotp.binding)
+}
+
+func TestTOTPSessionOTPState(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	generator, err := NewTOTP(HashAlgorithmSHA1, secret, 8, 30, 1, 1, WithSkewHistogram())
+	assert.NoError(t, err)
+	session, err := generator.SessionOTP([]byte("session-1"))
+	assert.NoError(t, err)
+
+	// Validating within the session is neither counted by the original manager, nor recorded as a used code.
+	code := session.Generate(1234567890)
+	assert.True(t, session.Validate(1234567920, code))
+	assert.True(t, session.ValidateOnce(1234567890, code))
+	assert.Equal(t, map[int]int64{-1: 1, 0: 1}, session.SkewHistogram())
+	assert.Equal(t, map[int]int64{}, generator.SkewHistogram())
+	assert.True(t, generator.(*totpManager).used != session.(*totpManager).used)
+	assert.True(t, generator.(*totpManager).hotp.counter != session.(*totpManager).hotp.counter)
+}
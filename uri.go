@@ -0,0 +1,253 @@
+package otp
+
+import (
+	"errors"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// KeyURI represents a parsed key URI.
+type KeyURI struct {
+	// Issuer is the provider of the account, taken from the issuer parameter or else from the label. It may be empty.
+	Issuer string
+
+	// AccountName is the name of the account, taken from the label.
+	AccountName string
+
+	// Counter is the initial counter of HOTP key URIs, which is 0 when absent. It is always 0 for TOTP key URIs.
+	Counter int64
+
+	// Image is the URL of the icon of the account taken from the image parameter, which some apps display. It may be
+	// empty.
+	Image string
+
+	// Params are the parameters not understood by this package, such as ones specific to an app, which can be emitted
+	// again with WithExtraParams. It is nil when there are none.
+	Params url.Values
+
+	// Manager is the manager configured from the key URI, which is an HOTPManager or a TOTPManager.
+	Manager Manager
+}
+
+// URIOption configures how the parameters of a manager are emitted in URIs.
+type URIOption func(*uriOptions)
+
+// uriOptions represents the resolved options for emitting URIs.
+type uriOptions struct {
+	lowercaseAlgorithm bool
+	image              string
+	extraParams        url.Values
+}
+
+// keyURIParams represents the parameters of key URIs understood by this package, which cannot be overridden by
+// WithExtraParams.
+var keyURIParams = map[string]bool{
+	"secret":    true,
+	"issuer":    true,
+	"algorithm": true,
+	"digits":    true,
+	"counter":   true,
+	"period":    true,
+	"image":     true,
+}
+
+// newURIOptions resolves the specified options.
+func newURIOptions(opts []URIOption) uriOptions {
+	var options uriOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// WithLowercaseAlgorithm emits the algorithm parameter in lowercase, such as "sha1", for apps that reject the uppercase
+// names. Uppercase names are emitted by default, which is what Google Authenticator uses. Both are accepted on parsing.
+func WithLowercaseAlgorithm(lowercase bool) URIOption {
+	return func(options *uriOptions) {
+		options.lowercaseAlgorithm = lowercase
+	}
+}
+
+// WithImage emits the image parameter with the URL of an icon for the account, which apps such as FreeOTP display next
+// to the codes.
+func WithImage(image string) URIOption {
+	return func(options *uriOptions) {
+		options.image = image
+	}
+}
+
+// WithExtraParams emits additional parameters, such as the ones specific to an app preserved in KeyURI.Params.
+// Parameters understood by this package, such as digits and secret, are always emitted from the manager and cannot be
+// overridden.
+func WithExtraParams(params url.Values) URIOption {
+	return func(options *uriOptions) {
+		options.extraParams = params
+	}
+}
+
+// ProvisioningURI gets the key URI of the manager, such as "otpauth://hotp/Example:alice?counter=0&...", which
+// authenticator apps can import, usually from a QR code. The counter is 0. Refers to the TOTP counterpart for details
+// of the label.
+//
+// The algorithm, digits and counter parameters are always emitted, even with their default values, since some apps
+// assume defaults that differ from the specification.
+func (generator *hotpManager) ProvisioningURI(issuer, accountName string, opts ...URIOption) (string, error) {
+	if generator.closed() {
+		return "", errors.New("manager closed")
+	}
+	if !generator.algorithm.standard() {
+		return "", errors.New("unsupported hash algorithm")
+	}
+	return provisioningURI(OTPTypeHOTP, issuer, accountName, generator.QueryParams(opts...))
+}
+
+// ProvisioningURI gets the key URI of the manager, such as "otpauth://totp/Example:alice?period=30&...", which
+// authenticator apps can import, usually from a QR code.
+//
+// The label is "issuer:accountName" with both parts URL escaped, or just the account name when the issuer is empty;
+// the issuer is also emitted as a query parameter, as recommended for apps that ignore the label. Colons of the issuer
+// are escaped as %3A, so that the only literal colon separates the issuer from the account name. The account name is
+// required and cannot contain a colon, since the label would become ambiguous. Managers using algorithms not defined by
+// RFC 6238, such as SHA3-256, are rejected, since no authenticator app could import the key URI. The algorithm, digits
+// and period parameters are always emitted, even with their default values, since some apps assume defaults that
+// differ from the specification.
+func (generator *totpManager) ProvisioningURI(issuer, accountName string, opts ...URIOption) (string, error) {
+	if generator.hotp.closed() {
+		return "", errors.New("manager closed")
+	}
+	if !generator.hotp.algorithm.standard() {
+		return "", errors.New("unsupported hash algorithm")
+	}
+	return provisioningURI(OTPTypeTOTP, issuer, accountName, generator.QueryParams(opts...))
+}
+
+// provisioningURI builds a key URI of the specified type from the label parts and the query parameters.
+func provisioningURI(kind OTPType, issuer, accountName string, params url.Values) (string, error) {
+	if accountName == "" || strings.Contains(accountName, ":") {
+		return "", errors.New("invalid account name")
+	}
+	label := url.PathEscape(accountName)
+	if issuer != "" {
+		// PathEscape keeps colons, which would make apps split the label at a colon of the issuer.
+		label = strings.ReplaceAll(url.PathEscape(issuer), ":", "%3A") + ":" + label
+		params.Set("issuer", issuer)
+	}
+	// Spaces are escaped as %20 rather than +, which some apps display literally. Literal plus signs are already
+	// escaped as %2B, so the replacement is unambiguous.
+	query := strings.ReplaceAll(params.Encode(), "+", "%20")
+	return "otpauth://" + kind.String() + "/" + label + "?" + query, nil
+}
+
+// ParseURI parses a key URI, such as one created by ProvisioningURI, and gets a manager configured from it. Refers to
+// ParseKeyURI for details, and for getting the issuer, account name and counter as well.
+func ParseURI(uri string) (Manager, error) {
+	key, err := ParseKeyURI(uri)
+	if err != nil {
+		return nil, err
+	}
+	return key.Manager, nil
+}
+
+// ParseKeyURI parses a key URI, such as one created by ProvisioningURI.
+//
+// The secret parameter is required, and is accepted in either case with or without padding. Absent parameters take the
+// defaults of authenticator apps: SHA1, 6 digits, a period of 30 seconds and a counter of 0. HOTP managers hold the
+// counter of the key URI, so that Next resumes at the provisioned counter. Periods that are not a whole number of
+// seconds are accepted as created by NewTOTPDuration. TOTP managers allow one time step backward for network delay, as
+// recommended by RFC 6238, and none forward. Secret keys shorter than 16 bytes are accepted, since they have already
+// been issued and cannot be strengthened by rejecting them. Unknown parameters are ignored, and kept in the Params
+// field.
+func ParseKeyURI(uri string) (*KeyURI, error) {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	if parsed.Scheme != "otpauth" {
+		return nil, errors.New("invalid scheme")
+	}
+	params := parsed.Query()
+
+	var key KeyURI
+	key.Issuer, key.AccountName = ParseLabel(strings.TrimPrefix(parsed.EscapedPath(), "/"))
+	if issuer := params.Get("issuer"); issuer != "" {
+		key.Issuer = issuer
+	}
+	key.Image = params.Get("image")
+	for name, values := range params {
+		if !keyURIParams[name] {
+			if key.Params == nil {
+				key.Params = url.Values{}
+			}
+			key.Params[name] = values
+		}
+	}
+
+	secret, err := DecodeSecret(params.Get("secret"))
+	if err != nil {
+		return nil, err
+	}
+
+	algorithm := HashAlgorithmSHA1
+	if name := params.Get("algorithm"); name != "" {
+		if algorithm, err = parseAlgorithmName(name); err != nil {
+			return nil, err
+		}
+	}
+
+	codeDigit := 6
+	if digits := params.Get("digits"); digits != "" {
+		if codeDigit, err = strconv.Atoi(digits); err != nil {
+			return nil, ErrInvalidCodeDigit
+		}
+	}
+
+	switch parsed.Host {
+	case "hotp":
+		if counter := params.Get("counter"); counter != "" {
+			if key.Counter, err = strconv.ParseInt(counter, 10, 64); err != nil || key.Counter < 0 {
+				return nil, errors.New("invalid counter")
+			}
+		}
+		key.Manager, err = NewHOTP(algorithm, secret, codeDigit, AllowShortSecret(), WithInitialCounter(key.Counter))
+	case "totp":
+		timeStep := 30 * time.Second
+		if period := params.Get("period"); period != "" {
+			seconds, err := strconv.ParseFloat(period, 64)
+			if err != nil || seconds <= 0 || seconds > float64(math.MaxInt32) {
+				return nil, ErrInvalidTimeStep
+			}
+			timeStep = time.Duration(seconds * float64(time.Second))
+		}
+		key.Manager, err = NewTOTPDuration(algorithm, secret, codeDigit, timeStep, 1, 0, AllowShortSecret())
+	default:
+		return nil, errors.New("invalid type")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// ParseLabel splits the label of a key URI into issuer and account name.
+//
+// Labels are accepted in the forms "issuer:account", "issuer: account" and "account", with the colon optionally URL
+// encoded. When the label contains more than one colon, the last one separates issuer from account name, since issuers
+// may contain colons themselves. Surrounding whitespace is trimmed from both parts.
+func ParseLabel(label string) (issuer, account string) {
+	if unescaped, err := url.PathUnescape(label); err == nil {
+		label = unescaped
+	}
+	return splitLabel(label)
+}
+
+// splitLabel splits an unescaped label into issuer and account name. Refers to ParseLabel for details.
+func splitLabel(label string) (issuer, account string) {
+	index := strings.LastIndex(label, ":")
+	if index < 0 {
+		return "", strings.TrimSpace(label)
+	}
+	return strings.TrimSpace(label[:index]), strings.TrimSpace(label[index+1:])
+}
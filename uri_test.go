@@ -0,0 +1,307 @@
+package otp
+
+import (
+	"encoding/hex"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLabel(t *testing.T) {
+	for _, testCase := range []struct {
+		Label   string
+		Issuer  string
+		Account string
+	}{
+		{"Example:alice@example.com", "Example", "alice@example.com"},
+		{"Example: alice@example.com", "Example", "alice@example.com"},
+		{"Example%3Aalice@example.com", "Example", "alice@example.com"},
+		{"Example%3A%20alice%40example.com", "Example", "alice@example.com"},
+		{"alice@example.com", "", "alice@example.com"},
+		{"  alice@example.com  ", "", "alice@example.com"},
+		{":alice@example.com", "", "alice@example.com"},
+		{"Big Corp: Asia:alice", "Big Corp: Asia", "alice"},
+		{"Example:", "Example", ""},
+		{"100%:alice", "100%", "alice"},
+		{"", "", ""},
+	} {
+		issuer, account := ParseLabel(testCase.Label)
+		assert.Equal(t, testCase.Issuer, issuer, testCase.Label)
+		assert.Equal(t, testCase.Account, account, testCase.Label)
+	}
+}
+
+func TestWithLowercaseAlgorithm(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	hotp, err := NewHOTP(HashAlgorithmSHA256, secret, 6)
+	assert.NoError(t, err)
+	assert.Equal(t, "SHA256", hotp.QueryParams().Get("algorithm"))
+	assert.Equal(t, "SHA256", hotp.QueryParams(WithLowercaseAlgorithm(false)).Get("algorithm"))
+	assert.Equal(t, "sha256", hotp.QueryParams(WithLowercaseAlgorithm(true)).Get("algorithm"))
+	params, err := hotp.QueryParamsAt(5, WithLowercaseAlgorithm(true))
+	assert.NoError(t, err)
+	assert.Equal(t, "sha256", params.Get("algorithm"))
+
+	totp, err := NewTOTP(HashAlgorithmSHA1, secret, 6, 30, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "SHA1", totp.QueryParams().Get("algorithm"))
+	assert.Equal(t, "sha1", totp.QueryParams(WithLowercaseAlgorithm(true)).Get("algorithm"))
+}
+
+func TestParseAlgorithmName(t *testing.T) {
+	for _, testCase := range []struct {
+		Name     string
+		Expected HashAlgorithm
+	}{
+		{"SHA1", HashAlgorithmSHA1},
+		{"sha1", HashAlgorithmSHA1},
+		{"Sha256", HashAlgorithmSHA256},
+		{"sha512", HashAlgorithmSHA512},
+	} {
+		algorithm, err := parseAlgorithmName(testCase.Name)
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.Expected, algorithm)
+	}
+	for _, name := range []string{"", "SHA-1", "MD5", "sha1 "} {
+		if _, err := parseAlgorithmName(name); assert.Error(t, err) {
+			assert.Equal(t, "unknown hash algorithm", err.Error())
+		}
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	totp, err := NewTOTP(HashAlgorithmSHA1, secret, 6, 30, 0, 0)
+	assert.NoError(t, err)
+	uri, err := totp.ProvisioningURI("Example", "alice@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "otpauth://totp/Example:alice@example.com?algorithm=SHA1&digits=6&issuer=Example&period=30&"+
+		"secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", uri)
+
+	uri, err = totp.ProvisioningURI("Big Corp", "alice smith", WithLowercaseAlgorithm(true))
+	assert.NoError(t, err)
+	assert.Equal(t, "otpauth://totp/Big%20Corp:alice%20smith?algorithm=sha1&digits=6&issuer=Big%20Corp&period=30&"+
+		"secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", uri)
+
+	uri, err = totp.ProvisioningURI("", "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, "otpauth://totp/alice?algorithm=SHA1&digits=6&period=30&secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", uri)
+
+	uri, err = totp.ProvisioningURI("A+B: Asia/Pacific", "a&b?")
+	assert.NoError(t, err)
+	assert.Equal(t, "otpauth://totp/A+B%3A%20Asia%2FPacific:a&b%3F?algorithm=SHA1&digits=6&"+
+		"issuer=A%2BB%3A%20Asia%2FPacific&period=30&secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", uri)
+	issuer, account := ParseLabel("A+B%3A%20Asia%2FPacific:a&b%3F")
+	assert.Equal(t, "A+B: Asia/Pacific", issuer)
+	assert.Equal(t, "a&b?", account)
+
+	// The colon of the issuer is escaped, so the label has a single literal colon.
+	uri, err = totp.ProvisioningURI("Acme:Corp", "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, "otpauth://totp/Acme%3ACorp:alice?algorithm=SHA1&digits=6&issuer=Acme%3ACorp&period=30&"+
+		"secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", uri)
+	key, err := ParseKeyURI(uri)
+	assert.NoError(t, err)
+	assert.Equal(t, "Acme:Corp", key.Issuer)
+	assert.Equal(t, "alice", key.AccountName)
+
+	_, err = totp.ProvisioningURI("Example", "alice:smith")
+	assert.EqualError(t, err, "invalid account name")
+	_, err = totp.ProvisioningURI("Example", "")
+	assert.EqualError(t, err, "invalid account name")
+
+	hotp, err := NewHOTP(HashAlgorithmSHA512, secret, 8)
+	assert.NoError(t, err)
+	uri, err = hotp.ProvisioningURI("Example", "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, "otpauth://hotp/Example:alice?algorithm=SHA512&counter=0&digits=8&issuer=Example&"+
+		"secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", uri)
+
+	for _, algorithm := range []HashAlgorithm{HashAlgorithmSHA3_256, HashAlgorithmSHA3_512, HashAlgorithmSHA224,
+		HashAlgorithmSHA384} {
+		hotp, err := NewHOTP(algorithm, secret, 6)
+		assert.NoError(t, err)
+		_, err = hotp.ProvisioningURI("Example", "alice")
+		assert.EqualError(t, err, "unsupported hash algorithm")
+		totp, err := NewTOTP(algorithm, secret, 6, 30, 0, 0)
+		assert.NoError(t, err)
+		_, err = totp.ProvisioningURI("Example", "alice")
+		assert.EqualError(t, err, "unsupported hash algorithm")
+	}
+}
+
+func TestProvisioningURIExtras(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	totp, err := NewTOTP(HashAlgorithmSHA1, secret, 6, 30, 0, 0)
+	assert.NoError(t, err)
+	uri, err := totp.ProvisioningURI("Example", "alice", WithImage("https://example.com/icon.png"),
+		WithExtraParams(url.Values{"color": {"blue"}, "digits": {"8"}, "secret": {"AAAA"}}))
+	assert.NoError(t, err)
+	assert.Equal(t, "otpauth://totp/Example:alice?algorithm=SHA1&color=blue&digits=6&"+
+		"image=https%3A%2F%2Fexample.com%2Ficon.png&issuer=Example&period=30&secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", uri)
+
+	key, err := ParseKeyURI(uri)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/icon.png", key.Image)
+	assert.Equal(t, url.Values{"color": {"blue"}}, key.Params)
+	assert.Equal(t, 6, key.Manager.CodeDigits())
+	again, err := key.Manager.ProvisioningURI(key.Issuer, key.AccountName, WithImage(key.Image),
+		WithExtraParams(key.Params))
+	assert.NoError(t, err)
+	assert.Equal(t, uri, again)
+
+	key, err = ParseKeyURI("otpauth://hotp/alice?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&digits=8")
+	assert.NoError(t, err)
+	assert.Equal(t, "", key.Image)
+	assert.Nil(t, key.Params)
+	uri, err = key.Manager.ProvisioningURI("", key.AccountName)
+	assert.NoError(t, err)
+	assert.Equal(t, "otpauth://hotp/alice?algorithm=SHA1&counter=0&digits=8&secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", uri)
+}
+
+func TestParseKeyURI(t *testing.T) {
+	secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	totp, err := NewTOTP(HashAlgorithmSHA256, secret, 8, 60, 0, 0)
+	assert.NoError(t, err)
+	uri, err := totp.ProvisioningURI("Big Corp: Asia", "alice smith")
+	assert.NoError(t, err)
+	key, err := ParseKeyURI(uri)
+	assert.NoError(t, err)
+	assert.Equal(t, "Big Corp: Asia", key.Issuer)
+	assert.Equal(t, "alice smith", key.AccountName)
+	assert.Equal(t, int64(0), key.Counter)
+	if parsed, ok := key.Manager.(TOTPManager); assert.True(t, ok) {
+		assert.Equal(t, totp.QueryParams(), parsed.QueryParams())
+		assert.Equal(t, totp.Generate(1234567890), parsed.Generate(1234567890))
+		assert.True(t, parsed.Validate(1234567890+60, totp.Generate(1234567890)))
+		assert.False(t, parsed.Validate(1234567890-60, totp.Generate(1234567890)))
+	}
+
+	key, err = ParseKeyURI("otpauth://hotp/Example:alice?secret=gezdgnbvgy3tqojqgezdgnbvgy3tqojq&counter=3")
+	assert.NoError(t, err)
+	assert.Equal(t, "Example", key.Issuer)
+	assert.Equal(t, "alice", key.AccountName)
+	assert.Equal(t, int64(3), key.Counter)
+	if parsed, ok := key.Manager.(HOTPManager); assert.True(t, ok) {
+		assert.Equal(t, "SHA1", parsed.QueryParams().Get("algorithm"))
+		assert.Equal(t, "969429", parsed.Generate(3))
+		assert.Equal(t, int64(3), parsed.Counter())
+		assert.Equal(t, "969429", parsed.Next())
+		assert.Equal(t, int64(4), parsed.Counter())
+	}
+
+	key, err = ParseKeyURI("otpauth://hotp/alice?issuer=Example&secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ")
+	assert.NoError(t, err)
+	assert.Equal(t, "Example", key.Issuer)
+	assert.Equal(t, int64(0), key.Counter)
+
+	manager, err := ParseURI("otpauth://totp/alice?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ")
+	assert.NoError(t, err)
+	assert.Equal(t, "30", manager.QueryParams().Get("period"))
+	assert.Equal(t, "287082", manager.Generate(59))
+
+	manager, err = ParseURI("otpauth://totp/alice?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&period=0.5")
+	assert.NoError(t, err)
+	assert.Equal(t, "0.5", manager.QueryParams().Get("period"))
+
+	for _, testCase := range []struct {
+		URI   string
+		Error string
+	}{
+		{"https://totp/alice?secret=GEZDGNBVGY3TQOJQ", "invalid scheme"},
+		{"otpauth://motp/alice?secret=GEZDGNBVGY3TQOJQ", "invalid type"},
+		{"otpauth://totp/alice", "invalid secret"},
+		{"otpauth://totp/alice?secret=GEZDGNBVGY3TQOJ1", "invalid secret"},
+		{"otpauth://totp/alice?secret=GEZDGNBVGY3TQOJQ&algorithm=MD5", "unknown hash algorithm"},
+		{"otpauth://totp/alice?secret=GEZDGNBVGY3TQOJQ&digits=six", "invalid code digit"},
+		{"otpauth://totp/alice?secret=GEZDGNBVGY3TQOJQ&digits=11", "invalid code digit 11: must be between 1 and 10"},
+		{"otpauth://totp/alice?secret=GEZDGNBVGY3TQOJQ&period=0", "invalid time step"},
+		{"otpauth://totp/alice?secret=GEZDGNBVGY3TQOJQ&period=thirty", "invalid time step"},
+		{"otpauth://hotp/alice?secret=GEZDGNBVGY3TQOJQ&counter=-1", "invalid counter"},
+	} {
+		_, err := ParseURI(testCase.URI)
+		assert.EqualError(t, err, testCase.Error, testCase.URI)
+	}
+}
+
+func FuzzParseURI(f *testing.F) {
+	for _, uri := range []string{
+		"otpauth://totp/Example:alice@example.com?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&issuer=Example",
+		"otpauth://totp/%F0%9F%94%91%20Vault:alice?secret=GEZDGNBVGY3TQOJQ&issuer=%F0%9F%94%91%20Vault",
+		"otpauth://totp/🦊:alice?secret=GEZDGNBVGY3TQOJQ",
+		"otpauth://totp/Example:?secret=GEZDGNBVGY3TQOJQ&issuer=Example",
+		"otpauth://totp/?secret=GEZDGNBVGY3TQOJQ",
+		"otpauth://hotp/alice?secret=GEZDGNBVGY3TQOJQ&digits=1&counter=7",
+		"otpauth://totp/alice?secret=GEZDGNBVGY3TQOJQ&digits=7&period=0.5&algorithm=sha512",
+		"otpauth://hotp/alice?secret=GEZDGNBVGY3TQOJQ&digits=10&algorithm=SHA256",
+		"otpauth://totp/A+B:%20Asia%2FPacific:a&b%3F?secret=gezd%20gnbv&issuer=A%2BB",
+		"otpauth://totp/alice?secret=GEZDGNBVGY3TQOJQ&image=https%3A%2F%2Fexample.com%2Ficon.png&color=blue&color=red",
+		"otpauth://totp/%zz?secret=GEZDGNBVGY3TQOJQ",
+	} {
+		f.Add(uri)
+	}
+	f.Fuzz(func(t *testing.T, uri string) {
+		key, err := ParseKeyURI(uri)
+		if err != nil {
+			return
+		}
+		emitted, err := key.Manager.ProvisioningURI(key.Issuer, key.AccountName, WithImage(key.Image),
+			WithExtraParams(key.Params))
+		if err != nil {
+			// Parsed key URIs may lack an account name, which cannot be emitted.
+			return
+		}
+		again, err := ParseKeyURI(emitted)
+		if !assert.NoError(t, err, emitted) {
+			return
+		}
+		assert.Equal(t, key.Issuer, again.Issuer, emitted)
+		assert.Equal(t, key.AccountName, again.AccountName, emitted)
+		assert.Equal(t, key.Image, again.Image, emitted)
+		assert.Equal(t, key.Params, again.Params, emitted)
+		assert.Equal(t, key.Manager.QueryParams(), again.Manager.QueryParams(), emitted)
+		assert.Equal(t, key.Manager.Generate(1234567890), again.Manager.Generate(1234567890), emitted)
+		reemitted, err := again.Manager.ProvisioningURI(again.Issuer, again.AccountName, WithImage(again.Image),
+			WithExtraParams(again.Params))
+		assert.NoError(t, err, emitted)
+		assert.Equal(t, emitted, reemitted)
+	})
+}
+
+func FuzzProvisioningURI(f *testing.F) {
+	f.Add("Example", "alice@example.com", 6, true)
+	f.Add("🔑 Vault", "ålice 🦊", 8, false)
+	f.Add("", "alice", 1, true)
+	f.Add("A+B: Asia/Pacific", "a&b?#%", 10, false)
+	f.Add("  ", " alice ", 7, true)
+	f.Fuzz(func(t *testing.T, issuer, accountName string, codeDigit int, totp bool) {
+		secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+		var manager Manager
+		var err error
+		if totp {
+			manager, err = NewTOTP(HashAlgorithmSHA256, secret, codeDigit, 30, 1, 0)
+		} else {
+			manager, err = NewHOTP(HashAlgorithmSHA256, secret, codeDigit)
+		}
+		if err != nil {
+			return
+		}
+		uri, err := manager.ProvisioningURI(issuer, accountName)
+		if err != nil {
+			return
+		}
+		key, err := ParseKeyURI(uri)
+		if !assert.NoError(t, err, uri) {
+			return
+		}
+		// Surrounding whitespace is trimmed from the label, except from the issuer taken from the issuer parameter.
+		assert.Equal(t, strings.TrimSpace(accountName), key.AccountName, uri)
+		if strings.TrimSpace(issuer) != "" {
+			assert.Equal(t, issuer, key.Issuer, uri)
+		}
+		assert.Equal(t, manager.QueryParams(), key.Manager.QueryParams(), uri)
+		assert.Equal(t, manager.Generate(1234567890), key.Manager.Generate(1234567890), uri)
+	})
+}